@@ -1,17 +1,55 @@
 package main
 
 import (
+	"context"
+	"github.com/yael-castro/products-api/internal/logging"
 	"github.com/yael-castro/products-api/internal/model"
 	"github.com/yael-castro/products-api/internal/repository"
-	"log"
+	"gopkg.in/yaml.v3"
+	"log/slog"
 	"os"
 )
 
 func main() {
+	logger := logging.NewLogger(os.Stdout, slog.LevelInfo)
+
+	schemes, err := skuSchemes()
+	if err != nil {
+		logger.Error("invalid sku schemes", "file", os.Getenv("SKU_SCHEMES_FILE"), "error", err)
+		os.Exit(1)
+	}
+
 	db, err := repository.NewGormDB(os.Getenv("GORM_DSN"))
 	if err != nil {
-		log.Fatal(err)
+		logger.Error("database connection failed", "error", err)
+		os.Exit(1)
+	}
+
+	if err = repository.Migrate(context.Background(), db, schemes); err != nil {
+		logger.Error("migration failed", "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("migrations applied", "version", repository.SchemaVersion)
+}
+
+// skuSchemes loads the model.SKUSchemes from the file indicated by the environment variable SKU_SCHEMES_FILE (same as the server),
+// the sequences of those schemes are created by the migrations. If the environment variable is missing, the model.DefaultSKUSchemes is used
+func skuSchemes() (model.SKUSchemes, error) {
+	path := os.Getenv("SKU_SCHEMES_FILE")
+	if path == "" {
+		return model.DefaultSKUSchemes, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return model.SKUSchemes{}, err
+	}
+
+	schemes := model.SKUSchemes{}
+	if err = yaml.Unmarshal(data, &schemes); err != nil {
+		return model.SKUSchemes{}, err
 	}
 
-	db.Migrator().CreateTable(&model.Product{})
+	return schemes, schemes.Check()
 }
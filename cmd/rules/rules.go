@@ -0,0 +1,97 @@
+// Command rules checks the existing products against a proposed rule set before activating it
+//
+// Usage:
+//
+//	go run ./cmd/rules -file rules.yaml [-format text|json]
+//
+// The products are read from the storage indicated by the environment variable GORM_DSN.
+// The exit code is 1 if any product breaks the rules and 2 if the rule set is invalid or the products could not be read
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/business"
+	"github.com/yael-castro/products-api/internal/logging"
+	"github.com/yael-castro/products-api/internal/repository"
+	"gopkg.in/yaml.v3"
+	"io"
+	"log/slog"
+	"os"
+)
+
+func main() {
+	file := flag.String("file", "", "path of the YAML (or JSON) file with the proposed rule set")
+	format := flag.String("format", "text", "format of the report: text or json")
+	flag.Parse()
+
+	logger := logging.NewLogger(os.Stderr, slog.LevelInfo)
+
+	if *file == "" || (*format != "text" && *format != "json") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		logger.Error("rule set could not be read", "error", err)
+		os.Exit(2)
+	}
+
+	set := business.RuleSet{}
+	if err = yaml.Unmarshal(data, &set); err != nil {
+		logger.Error("invalid rule set", "file", *file, "error", err)
+		os.Exit(2)
+	}
+
+	engine, err := business.NewRuleEngine(set)
+	if err != nil {
+		logger.Error("invalid rule set", "file", *file, "error", err)
+		os.Exit(2)
+	}
+
+	db, err := repository.NewGormDB(os.Getenv("GORM_DSN"))
+	if err != nil {
+		logger.Error("database connection failed", "error", err)
+		os.Exit(2)
+	}
+
+	products, err := repository.ProductStore{DB: db}.List(context.Background())
+	if err != nil {
+		logger.Error("products could not be listed", "error", err)
+		os.Exit(2)
+	}
+
+	report := engine.Check(products)
+
+	if *format == "json" {
+		err = json.NewEncoder(os.Stdout).Encode(report)
+	} else {
+		err = writeReport(os.Stdout, report)
+	}
+
+	if err != nil {
+		logger.Error("report could not be written", "error", err)
+		os.Exit(2)
+	}
+
+	if len(report.Invalid) > 0 {
+		os.Exit(1)
+	}
+}
+
+// writeReport writes the business.RuleReport as plain text, one line per broken rule
+func writeReport(w io.Writer, report business.RuleReport) error {
+	for _, product := range report.Invalid {
+		for _, violation := range product.Violations {
+			if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", product.SKU, violation.Pointer, violation.Rule, violation.Reason); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d of %d products break the rules\n", len(report.Invalid), report.Checked)
+	return err
+}
@@ -1,15 +1,35 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/yael-castro/products-api/internal/dependency"
 )
 
-const defaultPort = "8080"
+const (
+	defaultPort     = "8080"
+	defaultGRPCPort = "9090"
+)
+
+// Default values of the server timeouts
+const (
+	defaultReadHeaderTimeout   = 5 * time.Second
+	defaultReadTimeout         = 15 * time.Second
+	defaultWriteTimeout        = 30 * time.Second
+	defaultIdleTimeout         = 120 * time.Second
+	defaultShutdownDrainDelay  = 5 * time.Second
+	defaultShutdownGracePeriod = 25 * time.Second
+)
 
 func main() {
 	port := os.Getenv("PORT")
@@ -17,19 +37,164 @@ func main() {
 		port = defaultPort
 	}
 
-	log.SetFlags(log.Flags() | log.Lshortfile)
+	grpcPort := os.Getenv("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = defaultGRPCPort
+	}
 
 	if os.Getenv("GIN_MODE") == "" {
 		gin.SetMode(gin.TestMode)
 	}
 
-	var h http.Handler
+	timeouts, err := loadTimeouts()
+	if err != nil {
+		slog.Error("invalid server configuration", "error", err)
+		os.Exit(1)
+	}
+
+	app := &dependency.Application{}
 
-	err := dependency.NewInjector(dependency.Default).Inject(&h)
+	err = dependency.NewInjector(dependency.Default).Inject(app)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("dependency injection failed", "error", err)
+		closeApplication(app, timeouts.shutdownGracePeriod)
+		os.Exit(1)
+	}
+
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           app.Handler,
+		ReadHeaderTimeout: timeouts.readHeader,
+		ReadTimeout:       timeouts.read,
+		WriteTimeout:      timeouts.write,
+		IdleTimeout:       timeouts.idle,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	listener, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		slog.Error("grpc listener failed", "error", err)
+		closeApplication(app, timeouts.shutdownGracePeriod)
+		os.Exit(1)
+	}
+
+	serverErr := make(chan error, 2)
+
+	go func() {
+		slog.Info("http server is running 🤘", "port", port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	go func() {
+		slog.Info("grpc server is running 🤘", "port", grpcPort)
+		serverErr <- app.GRPCServer.Serve(listener)
+	}()
+
+	select {
+	case err = <-serverErr:
+		slog.Error("server stopped", "error", err)
+		app.GRPCServer.Stop()
+		_ = server.Close()
+		closeApplication(app, timeouts.shutdownGracePeriod)
+		os.Exit(1)
+	case <-ctx.Done():
+		// A second signal kills the process immediately
+		stop()
+	}
+
+	slog.Info("shutdown started, draining", "drain_delay", timeouts.shutdownDrainDelay, "grace_period", timeouts.shutdownGracePeriod)
+
+	// The readiness probe fails from now on, the server keeps serving requests until the load balancer stops routing traffic
+	app.Drain()
+	time.Sleep(timeouts.shutdownDrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeouts.shutdownGracePeriod)
+	defer cancel()
+
+	code := 0
+
+	grpcStopped := make(chan struct{})
+
+	go func() {
+		app.GRPCServer.GracefulStop()
+		close(grpcStopped)
+	}()
+
+	if err = server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("the grace period expired before the requests in flight were completed", "error", err)
+		_ = server.Close()
+		code = 1
+	}
+
+	select {
+	case <-grpcStopped:
+	case <-shutdownCtx.Done():
+		slog.Error("the grace period expired before the grpc calls in flight were completed")
+		app.GRPCServer.Stop()
+		code = 1
+	}
+
+	if err = app.Close(shutdownCtx); err != nil {
+		slog.Error("releasing resources failed", "error", err)
+		code = 1
+	}
+
+	slog.Info("servers stopped")
+	os.Exit(code)
+}
+
+// closeApplication releases the resources of the application within the grace period
+func closeApplication(app *dependency.Application, gracePeriod time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	if err := app.Close(ctx); err != nil {
+		slog.Error("releasing resources failed", "error", err)
+	}
+}
+
+// timeouts server timeouts and shutdown periods
+type timeouts struct {
+	readHeader, read, write, idle           time.Duration
+	shutdownDrainDelay, shutdownGracePeriod time.Duration
+}
+
+// loadTimeouts reads the timeouts from the environment variables, the missing ones take its default value
+func loadTimeouts() (t timeouts, err error) {
+	durations := []struct {
+		env          string
+		value        *time.Duration
+		defaultValue time.Duration
+	}{
+		{env: "HTTP_READ_HEADER_TIMEOUT", value: &t.readHeader, defaultValue: defaultReadHeaderTimeout},
+		{env: "HTTP_READ_TIMEOUT", value: &t.read, defaultValue: defaultReadTimeout},
+		{env: "HTTP_WRITE_TIMEOUT", value: &t.write, defaultValue: defaultWriteTimeout},
+		{env: "HTTP_IDLE_TIMEOUT", value: &t.idle, defaultValue: defaultIdleTimeout},
+		{env: "SHUTDOWN_DRAIN_DELAY", value: &t.shutdownDrainDelay, defaultValue: defaultShutdownDrainDelay},
+		{env: "SHUTDOWN_GRACE_PERIOD", value: &t.shutdownGracePeriod, defaultValue: defaultShutdownGracePeriod},
+	}
+
+	errs := make([]error, 0)
+
+	for _, d := range durations {
+		*d.value = d.defaultValue
+
+		value := os.Getenv(d.env)
+		if value == "" {
+			continue
+		}
+
+		parsed, parseErr := time.ParseDuration(value)
+		if parseErr != nil || parsed < 0 {
+			errs = append(errs, fmt.Errorf("invalid environment variable %s: '%s' is not a valid duration", d.env, value))
+			continue
+		}
+
+		*d.value = parsed
 	}
 
-	log.Printf(`http server is running on port "%v" %v`, port, "🤘\n")
-	log.Fatal(http.ListenAndServe(":"+port, h))
+	return t, errors.Join(errs...)
 }
@@ -0,0 +1,158 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"math"
+	"strings"
+	"time"
+)
+
+// PriceApproval thresholds of the price changes that must be approved before being applied, the zero value does not
+// require approvals
+type PriceApproval struct {
+	// Percentage maximum change of the price applied without approval as percentage of the current price, zero disables the threshold
+	Percentage float64
+	// Amount maximum change of the price applied without approval, zero disables the threshold
+	Amount float64
+}
+
+// Validate checks that the thresholds are not negative
+func (p PriceApproval) Validate() error {
+	if p.Percentage < 0 || p.Amount < 0 {
+		return errors.New("the price approval thresholds must not be negative")
+	}
+
+	return nil
+}
+
+// requires indicates if the change from the current price to the price exceeds any threshold
+func (p PriceApproval) requires(current, price float64) bool {
+	diff := math.Abs(price - current)
+	if diff == 0 {
+		return false
+	}
+
+	if p.Amount > 0 && diff > p.Amount {
+		return true
+	}
+
+	return p.Percentage > 0 && (current == 0 || diff/math.Abs(current)*100 > p.Percentage)
+}
+
+// holdPrice checks if the price of the model.Product requires approval, in that case the current price is restored
+// into the product and the pending model.PriceChange is returned. Otherwise, returns nil
+func (s ProductStore) holdPrice(ctx context.Context, product *model.Product) (*model.PriceChange, error) {
+	if s.Approvals == nil || s.PriceApproval == (PriceApproval{}) {
+		return nil, nil
+	}
+
+	current, err := s.Obtain(ctx, product.SKU)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.PriceApproval.requires(current.Price, product.Price) {
+		return nil, nil
+	}
+
+	change := &model.PriceChange{
+		SKU:          product.SKU,
+		CurrentPrice: current.Price,
+		Price:        product.Price,
+		Status:       model.ChangePending,
+		RequestedAt:  time.Now().UTC(),
+	}
+
+	if caller, ok := CallerFrom(ctx); ok {
+		change.RequestedBy = caller.Subject
+	}
+
+	product.Price = current.Price
+	return change, nil
+}
+
+// ListPriceChanges returns the changes of the product prices that match the model.PriceChangeFilter,
+// if the filter does not contain statuses only the pending changes are listed
+func (s ProductStore) ListPriceChanges(ctx context.Context, filter model.PriceChangeFilter) ([]model.PriceChange, error) {
+	if filter.SKU != "" {
+		if scheme, err := s.schemes().Match(filter.SKU); err != nil {
+			return nil, s.invalid("sku", RuleFormat, err.Error(), skuParams(scheme))
+		}
+	}
+
+	if s.Approvals == nil {
+		return nil, error2.NotImplemented("price approvals are not enabled")
+	}
+
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = []model.ChangeStatus{model.ChangePending}
+	}
+
+	return s.Approvals.PriceChanges(ctx, filter)
+}
+
+// ObtainPriceChange returns the change of a product price identified by the ID
+func (s ProductStore) ObtainPriceChange(ctx context.Context, id uint) (model.PriceChange, error) {
+	if s.Approvals == nil {
+		return model.PriceChange{}, error2.NotImplemented("price approvals are not enabled")
+	}
+
+	return s.Approvals.PriceChange(ctx, id)
+}
+
+// ReviewPriceChange approves or rejects a pending model.PriceChange, the approved changes are applied in the same operation.
+// Returns the reviewed model.PriceChange
+//
+// The caller must be identified since the reviewer is recorded. A change could not be approved by its requester,
+// but the requester is able to reject it (e.g. to withdraw a mistyped price). A comment is required to reject a change
+func (s ProductStore) ReviewPriceChange(ctx context.Context, review model.PriceReview) (model.PriceChange, error) {
+	if review.Status != model.ChangeApproved && review.Status != model.ChangeRejected {
+		return model.PriceChange{}, s.invalid("status", RuleEnum, fmt.Sprintf("the price change could not be reviewed as '%s'", review.Status), error2.RuleParams{
+			"values": []string{string(model.ChangeApproved), string(model.ChangeRejected)},
+		})
+	}
+
+	if review.Status == model.ChangeRejected && strings.TrimSpace(review.Comment) == "" {
+		return model.PriceChange{}, s.invalid("comment", RuleNotBlank, "a comment is required to reject a price change", nil)
+	}
+
+	if s.Approvals == nil {
+		return model.PriceChange{}, error2.NotImplemented("price approvals are not enabled")
+	}
+
+	caller, ok := CallerFrom(ctx)
+	if !ok {
+		return model.PriceChange{}, error2.Unauthorized("credentials are required to review price changes")
+	}
+
+	change, err := s.Approvals.PriceChange(ctx, review.ID)
+	if err != nil {
+		return model.PriceChange{}, err
+	}
+
+	if change.Status != model.ChangePending {
+		return model.PriceChange{}, error2.Conflict(fmt.Sprintf("the price change '%d' is no longer pending, it was %s", change.ID, change.Status))
+	}
+
+	if review.Status == model.ChangeApproved && change.RequestedBy == caller.Subject {
+		return model.PriceChange{}, error2.Forbidden("the price change must be approved by a client other than its requester")
+	}
+
+	reviewedAt := time.Now().UTC()
+
+	change.Status = review.Status
+	change.Comment = review.Comment
+	change.ReviewedBy = caller.Subject
+	change.ReviewedAt = &reviewedAt
+
+	if err = s.Approvals.ReviewChange(ctx, change); err != nil {
+		return model.PriceChange{}, err
+	}
+
+	s.logger().InfoContext(ctx, "price change reviewed", "id", change.ID, "sku", change.SKU, "status", change.Status, "by", change.ReviewedBy)
+	return change, nil
+}
@@ -0,0 +1,217 @@
+package business
+
+import (
+	"context"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"github.com/yael-castro/products-api/internal/repository"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPriceApproval_requires(t *testing.T) {
+	tdt := []struct {
+		approval       PriceApproval
+		current, price float64
+		expected       bool
+	}{
+		{
+			current: 10.5,
+			price:   1050,
+		},
+		{
+			approval: PriceApproval{Percentage: 50},
+			current:  10.5,
+			price:    1050,
+			expected: true,
+		},
+		{
+			approval: PriceApproval{Percentage: 50},
+			current:  10.5,
+			price:    6,
+		},
+		{
+			approval: PriceApproval{Percentage: 50},
+			current:  0,
+			price:    1,
+			expected: true,
+		},
+		{
+			approval: PriceApproval{Amount: 100},
+			current:  500,
+			price:    650,
+			expected: true,
+		},
+		{
+			approval: PriceApproval{Percentage: 50, Amount: 100},
+			current:  500,
+			price:    550,
+		},
+		{
+			approval: PriceApproval{Percentage: 50, Amount: 100},
+			current:  500,
+			price:    500,
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if requires := v.approval.requires(v.current, v.price); requires != v.expected {
+				t.Fatalf("expected '%v' unexpected '%v'", v.expected, requires)
+			}
+		})
+	}
+}
+
+func TestProductStore_ReviewPriceChange(t *testing.T) {
+	principalImage := mustURL(t, "https://example.com/a.png")
+
+	storage := &repository.MockStorage[model.SKU, model.Product]{
+		"FAL-1000001": {SKU: "FAL-1000001", Name: "Shoes", Brand: "Nike", Price: 10.5, PrincipalImage: &principalImage},
+	}
+
+	store := ProductStore{
+		StorageManager: storage,
+		Approvals:      &repository.MockApprovals{Storage: storage},
+		PriceApproval:  PriceApproval{Percentage: 50},
+	}
+
+	editor := WithCaller(context.Background(), model.Caller{Subject: "editor"})
+
+	// The small price changes are applied without approval
+	product := model.Product{SKU: "FAL-1000001", Name: "Running shoes", Brand: "Nike", Price: 12, PrincipalImage: &principalImage}
+
+	change, err := store.UpdateProduct(editor, product)
+	if err != nil || change != nil {
+		t.Fatalf("unexpected change '%+v' unexpected error '%v'", change, err)
+	}
+
+	// The big price changes are held, but the rest of the product is updated
+	product.Name, product.Price = "Air shoes", 1200
+
+	change, err = store.UpdateProduct(editor, product)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedChange := model.PriceChange{ID: 1, SKU: "FAL-1000001", CurrentPrice: 12, Price: 1200, Status: model.ChangePending, RequestedBy: "editor"}
+	if change == nil || change.RequestedAt.IsZero() {
+		t.Fatalf("expected change '%+v' unexpected change '%+v'", expectedChange, change)
+	}
+
+	if change.RequestedAt = (time.Time{}); *change != expectedChange {
+		t.Fatalf("expected change '%+v' unexpected change '%+v'", expectedChange, *change)
+	}
+
+	if stored, _ := storage.Obtain(editor, "FAL-1000001"); stored.Price != 12 || stored.Name != "Air shoes" {
+		t.Fatalf("unexpected product '%+v'", stored)
+	}
+
+	// A second change is held while the first one is pending
+	product.Price = 1300
+
+	if change, err = store.UpdateProduct(editor, product); err != nil || change == nil || change.ID != 2 {
+		t.Fatalf("unexpected change '%+v' unexpected error '%v'", change, err)
+	}
+
+	if obtained, err := store.ObtainPriceChange(editor, 2); err != nil || obtained.Price != 1300 {
+		t.Fatalf("unexpected change '%+v' unexpected error '%v'", obtained, err)
+	}
+
+	tdt := []struct {
+		caller         *model.Caller
+		review         model.PriceReview
+		expectedStatus model.ChangeStatus
+		expectedPrice  float64
+		expectedErr    error
+	}{
+		{
+			review:      model.PriceReview{ID: 1, Status: model.ChangeApproved},
+			expectedErr: error2.Unauthorized("credentials are required to review price changes"),
+		},
+		{
+			caller:      &model.Caller{Subject: "editor"},
+			review:      model.PriceReview{ID: 1, Status: model.ChangeApproved},
+			expectedErr: error2.Forbidden("the price change must be approved by a client other than its requester"),
+		},
+		{
+			caller:      &model.Caller{Subject: "manager"},
+			review:      model.PriceReview{ID: 1, Status: model.ChangeRejected, Comment: " "},
+			expectedErr: error2.Violations{{Field: "comment", Reason: "a comment is required to reject a price change"}},
+		},
+		{
+			caller:      &model.Caller{Subject: "manager"},
+			review:      model.PriceReview{ID: 3, Status: model.ChangeApproved},
+			expectedErr: error2.NotFound("price change identified by id '3' does not exist"),
+		},
+		{
+			caller:         &model.Caller{Subject: "manager"},
+			review:         model.PriceReview{ID: 1, Status: model.ChangeApproved, Comment: "New collection"},
+			expectedStatus: model.ChangeApproved,
+			expectedPrice:  1200,
+		},
+		{
+			caller:      &model.Caller{Subject: "manager"},
+			review:      model.PriceReview{ID: 1, Status: model.ChangeRejected, Comment: "Too late"},
+			expectedErr: error2.Conflict("the price change '1' is no longer pending, it was approved"),
+		},
+		// The price of the product changed since the second change was requested
+		{
+			caller:      &model.Caller{Subject: "manager"},
+			review:      model.PriceReview{ID: 2, Status: model.ChangeApproved},
+			expectedErr: error2.Conflict("the price of the product 'FAL-1000001' is no longer 12"),
+		},
+		// The requester is able to reject its own change
+		{
+			caller:         &model.Caller{Subject: "editor"},
+			review:         model.PriceReview{ID: 2, Status: model.ChangeRejected, Comment: "Mistyped price"},
+			expectedStatus: model.ChangeRejected,
+			expectedPrice:  1200,
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := context.Background()
+			if v.caller != nil {
+				ctx = WithCaller(ctx, *v.caller)
+			}
+
+			change, err := store.ReviewPriceChange(ctx, v.review)
+			if !matchError(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			if err != nil {
+				t.Skip(err)
+			}
+
+			if change.Status != v.expectedStatus || change.ReviewedBy != v.caller.Subject || change.ReviewedAt == nil {
+				t.Fatalf("unexpected price change '%+v'", change)
+			}
+
+			if stored, _ := storage.Obtain(ctx, "FAL-1000001"); stored.Price != v.expectedPrice {
+				t.Fatalf("expected price '%v' unexpected price '%v'", v.expectedPrice, stored.Price)
+			}
+		})
+	}
+
+	changes, err := store.ListPriceChanges(context.Background(), model.PriceChangeFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes) != 0 {
+		t.Fatalf("unexpected pending changes '%+v'", changes)
+	}
+
+	changes, err = store.ListPriceChanges(context.Background(), model.PriceChangeFilter{Statuses: model.ChangeStatuses, SKU: "FAL-1000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes) != 2 || changes[0].RequestedBy != "editor" || changes[0].CurrentPrice != 12 {
+		t.Fatalf("unexpected changes '%+v'", changes)
+	}
+}
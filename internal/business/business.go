@@ -2,19 +2,48 @@
 package business
 
 import (
+	"context"
 	"github.com/yael-castro/products-api/internal/model"
+	"github.com/yael-castro/products-api/internal/repository"
 )
 
 // ProductManager defines the common actions for product manager
 type ProductManager interface {
 	// CreateProduct saves a new model.Product into the storage
-	CreateProduct(product *model.Product) error
+	CreateProduct(ctx context.Context, product *model.Product) error
 	// ObtainProduct returns the record identified by model.SKU from the store
-	ObtainProduct(sku model.SKU) (model.Product, error)
-	// UpdateProduct updates the record for model.Product identified by model.SKU
-	UpdateProduct(product model.Product) error
+	ObtainProduct(ctx context.Context, sku model.SKU) (model.Product, error)
+	// ObtainProductBatch returns the records identified by the model.SKUs in the same order, the missing SKUs are reported apart
+	ObtainProductBatch(ctx context.Context, skus []model.SKU) (model.ProductBatch, error)
+	// UpdateProduct updates the record for model.Product identified by model.SKU, returns the pending model.PriceChange
+	// if the price change is held until it is approved, otherwise returns nil
+	UpdateProduct(ctx context.Context, product model.Product) (*model.PriceChange, error)
 	// DeleteProduct removes a record of model.Product identified by model.SKU from the store
-	DeleteProduct(sku model.SKU) error
-	// ListProducts returns all records of model.Product into the store
-	ListProducts() (model.Products, error)
+	DeleteProduct(ctx context.Context, sku model.SKU) error
+	// ListProducts returns the page of the records of model.Product into the store that match the model.ProductFilter ordered by SKU
+	ListProducts(ctx context.Context, filter model.ProductFilter) (model.ProductPage, error)
+	// ReserveSKUs reserves a block of free model.SKU for offline use
+	ReserveSKUs(ctx context.Context, reservation model.SKUReservation) (model.SKUBlock, error)
+	// UploadImage stores the model.ImageUpload and adds it to the images of the model.Product identified by model.SKU
+	UploadImage(ctx context.Context, sku model.SKU, upload model.ImageUpload) (model.Product, error)
+	// TransitionProduct changes the model.Status of the model.Product identified by model.SKU
+	TransitionProduct(ctx context.Context, sku model.SKU, status model.Status) (model.Product, error)
+	// ObtainTransitions returns the history of the model.Status of the model.Product identified by model.SKU
+	ObtainTransitions(ctx context.Context, sku model.SKU) ([]model.StatusTransition, error)
+	// ListPriceChanges returns the changes of the product prices held until they are approved that match the model.PriceChangeFilter
+	ListPriceChanges(ctx context.Context, filter model.PriceChangeFilter) ([]model.PriceChange, error)
+	// ObtainPriceChange returns the model.PriceChange identified by the ID
+	ObtainPriceChange(ctx context.Context, id uint) (model.PriceChange, error)
+	// ReviewPriceChange approves or rejects a pending model.PriceChange as indicated by the model.PriceReview
+	ReviewPriceChange(ctx context.Context, review model.PriceReview) (model.PriceChange, error)
+	// StartDuplicateReport starts the generation in background of the model.DuplicateReport of the catalog
+	StartDuplicateReport(ctx context.Context) (model.DuplicateReport, error)
+	// ObtainDuplicateReport returns the last model.DuplicateReport of the catalog
+	ObtainDuplicateReport(ctx context.Context) (model.DuplicateReport, error)
+}
+
+// MediaManager defines the actions to obtain the media served by the API (e.g. the product images and their thumbnails)
+type MediaManager interface {
+	// ObtainMedia returns the repository.Blob identified by the key, the key is the path after MediaPath
+	ObtainMedia(ctx context.Context, key string) (repository.Blob, error)
 }
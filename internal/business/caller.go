@@ -0,0 +1,28 @@
+package business
+
+import (
+	"context"
+	"github.com/yael-castro/products-api/internal/model"
+	"github.com/yael-castro/products-api/internal/repository"
+)
+
+// callerKey context key for model.Caller
+type callerKey struct{}
+
+// WithCaller returns a copy of ctx that carries the model.Caller, it is used by the presentation layer to identify
+// the client that triggers each business operation
+func WithCaller(ctx context.Context, caller model.Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns the model.Caller carried by ctx, the bool value is false for anonymous clients
+func CallerFrom(ctx context.Context) (model.Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(model.Caller)
+	return caller, ok
+}
+
+// WithFields returns a copy of ctx that carries the sparse fieldset requested by the client, the projection is pushed
+// down to the storage to read only the requested fields
+func WithFields(ctx context.Context, fields model.Fields) context.Context {
+	return repository.WithFields(ctx, fields)
+}
@@ -0,0 +1,341 @@
+package business
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"github.com/yael-castro/products-api/internal/repository"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDuplicateThreshold minimum similarity between the names of two products of the same brand to consider them duplicates
+const DefaultDuplicateThreshold = 0.8
+
+// maxCandidates maximum number of candidates reported when a new product is likely a duplicate
+const maxCandidates = 10
+
+// duplicateFields fields read to compare the products, they are pushed down to the storage
+var duplicateFields = model.Fields{"sku", "name", "brand"}
+
+// duplicateStatuses statuses of the products compared, the archived products are not sold anymore so they are ignored
+var duplicateStatuses = []model.Status{model.StatusDraft, model.StatusInReview, model.StatusPublished, model.StatusDiscontinued}
+
+// allowDuplicatesKey context key to skip the detection of duplicates
+type allowDuplicatesKey struct{}
+
+// AllowDuplicates returns a copy of ctx that skips the detection of duplicates when a product is created
+// (e.g. the client confirmed that the product is not a duplicate)
+func AllowDuplicates(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allowDuplicatesKey{}, true)
+}
+
+// duplicatesAllowed indicates if ctx skips the detection of duplicates
+func duplicatesAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(allowDuplicatesKey{}).(bool)
+	return allowed
+}
+
+// trigrams returns the set of trigrams of the words of the normalized text, each word is padded with two spaces
+// at the beginning and one at the end (same as pg_trgm)
+func trigrams(normalized string) map[string]struct{} {
+	set := make(map[string]struct{})
+
+	for _, word := range strings.Fields(normalized) {
+		runes := []rune("  " + word + " ")
+
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = struct{}{}
+		}
+	}
+
+	return set
+}
+
+// similarity returns the ratio of shared trigrams between two sets of trigrams, from 0 (different) to 1 (same)
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for trigram := range a {
+		if _, ok := b[trigram]; ok {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// DuplicateDetector finds products of the same brand with similar names, it checks the new products and generates in
+// background the report of the suspected duplicates of the whole catalog
+type DuplicateDetector struct {
+	threshold float64
+	storage   repository.StorageManager[model.SKU, model.Product]
+	logger    *slog.Logger
+
+	wg     sync.WaitGroup
+	mutex  sync.Mutex
+	report *model.DuplicateReport
+}
+
+// NewDuplicateDetector builds a *DuplicateDetector that considers duplicates the products whose similarity is at least
+// the threshold, if the threshold is zero DefaultDuplicateThreshold is used and if the *slog.Logger is nil slog.Default is used
+func NewDuplicateDetector(threshold float64, storage repository.StorageManager[model.SKU, model.Product], logger *slog.Logger) (*DuplicateDetector, error) {
+	if threshold == 0 {
+		threshold = DefaultDuplicateThreshold
+	}
+
+	if threshold < 0 || threshold > 1 {
+		return nil, fmt.Errorf("the duplicate threshold must be between 0 and 1, it is %v", threshold)
+	}
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &DuplicateDetector{threshold: threshold, storage: storage, logger: logger}, nil
+}
+
+// products reads the compared products
+func (d *DuplicateDetector) products(ctx context.Context) (model.Products, error) {
+	ctx = repository.WithStatuses(repository.WithFields(ctx, duplicateFields), duplicateStatuses)
+
+	products, err := d.storage.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The storages could ignore the statuses carried by the context
+	return slices.DeleteFunc(products, func(product model.Product) bool {
+		return !slices.Contains(duplicateStatuses, product.Status)
+	}), nil
+}
+
+// check returns error2.Duplicates if there are products of the same brand whose names are similar to the name of the product
+func (d *DuplicateDetector) check(ctx context.Context, product model.Product) error {
+	brand, name := model.Normalize(product.Brand), trigrams(model.Normalize(product.Name))
+
+	// Only the products of the brand are read, the storages could ignore the brand carried by the context
+	products, err := d.products(repository.WithBrand(ctx, product.Brand))
+	if err != nil {
+		return err
+	}
+
+	candidates := make([]error2.Candidate, 0)
+
+	for _, existing := range products {
+		if existing.SKU == product.SKU || model.Normalize(existing.Brand) != brand {
+			continue
+		}
+
+		score := similarity(name, trigrams(model.Normalize(existing.Name)))
+		if score < d.threshold {
+			continue
+		}
+
+		candidates = append(candidates, error2.Candidate{
+			SKU:   string(existing.SKU),
+			Name:  existing.Name,
+			Brand: existing.Brand,
+			Score: score,
+		})
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	slices.SortFunc(candidates, func(a, b error2.Candidate) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.SKU, b.SKU))
+	})
+
+	return error2.Duplicates{
+		Reason:     fmt.Sprintf("the product '%s' of the brand '%s' is likely a duplicate of %d existing products", product.Name, product.Brand, len(candidates)),
+		Candidates: candidates[:min(len(candidates), maxCandidates)],
+	}
+}
+
+// Start starts the generation of the report in background and returns the running report,
+// if a report is already running it is returned instead of starting another one
+func (d *DuplicateDetector) Start(ctx context.Context) model.DuplicateReport {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.report != nil && d.report.Status == model.ReportRunning {
+		return *d.report
+	}
+
+	d.report = &model.DuplicateReport{Status: model.ReportRunning, StartedAt: time.Now().UTC(), Clusters: []model.DuplicateCluster{}}
+	report := *d.report
+
+	d.wg.Add(1)
+
+	// The report outlives the request that started it
+	go d.generate(context.WithoutCancel(ctx), report)
+
+	return report
+}
+
+// Report returns the last report, if no report was started returns error2.NotFound
+func (d *DuplicateDetector) Report() (model.DuplicateReport, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.report == nil {
+		return model.DuplicateReport{}, error2.NotFound("the duplicate report was not started")
+	}
+
+	return *d.report, nil
+}
+
+// Close waits until the running report is generated or ctx is done
+func (d *DuplicateDetector) Close(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// generate groups the products in clusters and stores the completed report
+func (d *DuplicateDetector) generate(ctx context.Context, report model.DuplicateReport) {
+	defer d.wg.Done()
+
+	products, err := d.products(ctx)
+	if err == nil {
+		report.Products = len(products)
+		report.Clusters = d.clusters(products)
+		report.Status = model.ReportCompleted
+	} else {
+		d.logger.ErrorContext(ctx, "duplicate report failed", "error", err)
+		report.Status = model.ReportFailed
+	}
+
+	completedAt := time.Now().UTC()
+	report.CompletedAt = &completedAt
+
+	d.mutex.Lock()
+	d.report = &report
+	d.mutex.Unlock()
+
+	d.logger.InfoContext(ctx, "duplicate report completed", "status", report.Status, "products", report.Products, "clusters", len(report.Clusters))
+}
+
+// clusters groups the products of the same brand whose names are similar, two products are in the same cluster if they are
+// similar or both are similar to a third product of the cluster. The clusters of a single product are discarded
+func (d *DuplicateDetector) clusters(products model.Products) []model.DuplicateCluster {
+	brands := make(map[string][]model.Product)
+	for _, product := range products {
+		brand := model.Normalize(product.Brand)
+		brands[brand] = append(brands[brand], product)
+	}
+
+	clusters := make([]model.DuplicateCluster, 0)
+
+	for brand, products := range brands {
+		names := make([]map[string]struct{}, len(products))
+		for i, product := range products {
+			names[i] = trigrams(model.Normalize(product.Name))
+		}
+
+		// parents union-find of the indexes of the products
+		parents := make([]int, len(products))
+		for i := range parents {
+			parents[i] = i
+		}
+
+		var root func(i int) int
+		root = func(i int) int {
+			if parents[i] != i {
+				parents[i] = root(parents[i])
+			}
+
+			return parents[i]
+		}
+
+		for i := range products {
+			for j := i + 1; j < len(products); j++ {
+				if similarity(names[i], names[j]) >= d.threshold {
+					parents[root(j)] = root(i)
+				}
+			}
+		}
+
+		groups := make(map[int][]model.DuplicateProduct)
+		for i, product := range products {
+			groups[root(i)] = append(groups[root(i)], model.DuplicateProduct{SKU: product.SKU, Name: product.Name, Brand: product.Brand})
+		}
+
+		for _, group := range groups {
+			if len(group) < 2 {
+				continue
+			}
+
+			slices.SortFunc(group, func(a, b model.DuplicateProduct) int { return cmp.Compare(a.SKU, b.SKU) })
+			clusters = append(clusters, model.DuplicateCluster{Brand: brand, Products: group})
+		}
+	}
+
+	slices.SortFunc(clusters, func(a, b model.DuplicateCluster) int {
+		return cmp.Or(cmp.Compare(a.Brand, b.Brand), cmp.Compare(a.Products[0].SKU, b.Products[0].SKU))
+	})
+
+	return clusters
+}
+
+// StartDuplicateReport starts the generation in background of the report of the suspected duplicate products,
+// the report is obtained using ObtainDuplicateReport
+func (s ProductStore) StartDuplicateReport(ctx context.Context) (model.DuplicateReport, error) {
+	if s.Duplicates == nil {
+		return model.DuplicateReport{}, error2.NotImplemented("duplicate detection is not enabled")
+	}
+
+	report := s.Duplicates.Start(ctx)
+
+	s.logger().InfoContext(ctx, "duplicate report started", "started_at", report.StartedAt)
+	return report, nil
+}
+
+// ObtainDuplicateReport returns the last report of the suspected duplicate products
+func (s ProductStore) ObtainDuplicateReport(_ context.Context) (model.DuplicateReport, error) {
+	if s.Duplicates == nil {
+		return model.DuplicateReport{}, error2.NotImplemented("duplicate detection is not enabled")
+	}
+
+	return s.Duplicates.Report()
+}
+
+// checkDuplicates returns error2.Duplicates if the new model.Product is likely a duplicate of existing products,
+// the check is skipped if the *DuplicateDetector is nil or ctx allows the duplicates (see AllowDuplicates)
+func (s ProductStore) checkDuplicates(ctx context.Context, product model.Product) error {
+	if s.Duplicates == nil || duplicatesAllowed(ctx) {
+		return nil
+	}
+
+	ctx, span := startSpan(ctx, "check duplicates")
+	err := s.Duplicates.check(ctx, product)
+	endSpan(span, err)
+
+	var duplicates error2.Duplicates
+	if errors.As(err, &duplicates) {
+		s.logger().InfoContext(ctx, "duplicate product rejected", "name", product.Name, "brand", product.Brand, "candidates", len(duplicates.Candidates))
+	}
+
+	return err
+}
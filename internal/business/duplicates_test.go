@@ -0,0 +1,212 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"github.com/yael-castro/products-api/internal/repository"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSimilarity(t *testing.T) {
+	tdt := []struct {
+		a, b     string
+		expected float64
+	}{
+		{
+			a:        "Nike Air Max 90",
+			b:        "NIKE air-max 90",
+			expected: 1,
+		},
+		{
+			a:        "Nike Air Max 90",
+			b:        "Nike Air Max 95",
+			expected: 14.0 / 18.0,
+		},
+		{
+			a: "Nike Air Max 90",
+			b: "Pegasus",
+		},
+		{
+			a: "",
+			b: "Pegasus",
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			score := similarity(trigrams(model.Normalize(v.a)), trigrams(model.Normalize(v.b)))
+			if score != v.expected {
+				t.Fatalf("expected similarity '%v' unexpected similarity '%v'", v.expected, score)
+			}
+		})
+	}
+}
+
+// brandStorage simulates a database that lists only the products of the brand carried by the context
+type brandStorage struct {
+	*repository.MockStorage[model.SKU, model.Product]
+}
+
+// List returns the products of the brand carried by ctx, it fails if there is no brand
+func (b brandStorage) List(ctx context.Context) ([]model.Product, error) {
+	brand, ok := repository.BrandFrom(ctx)
+	if !ok {
+		return nil, errors.New("the brand was not pushed down to the storage")
+	}
+
+	products, err := b.MockStorage.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(products, func(product model.Product) bool {
+		return model.Normalize(product.Brand) != model.Normalize(brand)
+	}), nil
+}
+
+func TestProductStore_CreateProduct_Duplicates(t *testing.T) {
+	principalImage := mustURL(t, "https://example.com/a.png")
+
+	tdt := []struct {
+		product            model.Product
+		allow              bool
+		expectedCandidates []string
+	}{
+		{
+			product:            model.Product{SKU: "FAL-1000010", Name: "NIKE air-max 90", Brand: "NIKE"},
+			expectedCandidates: []string{"FAL-1000001"},
+		},
+		{
+			product: model.Product{SKU: "FAL-1000010", Name: "NIKE air-max 90", Brand: "NIKE"},
+			allow:   true,
+		},
+		// The products of other brands are not compared
+		{
+			product: model.Product{SKU: "FAL-1000011", Name: "Air Max 90", Brand: "Adidas"},
+		},
+		{
+			product: model.Product{SKU: "FAL-1000012", Name: "Nike Air Max 95", Brand: "Nike"},
+		},
+		// The archived products are ignored
+		{
+			product: model.Product{SKU: "FAL-1000013", Name: "Nike Pegasus", Brand: "Nike"},
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			storage := &repository.MockStorage[model.SKU, model.Product]{
+				"FAL-1000001": {SKU: "FAL-1000001", Name: "Nike Air Max 90", Brand: "Nike", Status: model.StatusPublished},
+				"FAL-1000002": {SKU: "FAL-1000002", Name: "Nike Pegasus", Brand: "Nike", Status: model.StatusArchived},
+			}
+
+			detector, err := NewDuplicateDetector(0, brandStorage{MockStorage: storage}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			store := ProductStore{StorageManager: storage, Duplicates: detector}
+
+			ctx := context.Background()
+			if v.allow {
+				ctx = AllowDuplicates(ctx)
+			}
+
+			product := v.product
+			product.Price, product.PrincipalImage = 10, &principalImage
+
+			err = store.CreateProduct(ctx, &product)
+
+			candidates := make([]string, 0)
+
+			var duplicates error2.Duplicates
+			if errors.As(err, &duplicates) {
+				for _, candidate := range duplicates.Candidates {
+					candidates = append(candidates, candidate.SKU)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(candidates) != len(v.expectedCandidates) || (len(candidates) > 0 && !reflect.DeepEqual(candidates, v.expectedCandidates)) {
+				t.Fatalf("expected candidates '%v' unexpected candidates '%v'", v.expectedCandidates, candidates)
+			}
+		})
+	}
+}
+
+func TestDuplicateDetector_Start(t *testing.T) {
+	storage := &repository.MockStorage[model.SKU, model.Product]{
+		"FAL-1000001": {SKU: "FAL-1000001", Name: "Nike Air Max 90", Brand: "Nike", Status: model.StatusPublished},
+		"FAL-1000002": {SKU: "FAL-1000002", Name: "NIKE air-max 90", Brand: "NIKE", Status: model.StatusDraft},
+		"FAL-1000003": {SKU: "FAL-1000003", Name: "Nike Air Max 90 ", Brand: "nike", Status: model.StatusDiscontinued},
+		"FAL-1000004": {SKU: "FAL-1000004", Name: "Nike Pegasus", Brand: "Nike", Status: model.StatusPublished},
+		"FAL-1000005": {SKU: "FAL-1000005", Name: "Nike pegasus", Brand: "Nike", Status: model.StatusArchived},
+		"FAL-1000006": {SKU: "FAL-1000006", Name: "Super Star", Brand: "Adidas", Status: model.StatusPublished},
+		"FAL-1000007": {SKU: "FAL-1000007", Name: "SUPER-STAR", Brand: "adidas", Status: model.StatusInReview},
+	}
+
+	detector, err := NewDuplicateDetector(0, storage, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := ProductStore{StorageManager: storage, Duplicates: detector}
+
+	if _, err = store.ObtainDuplicateReport(context.Background()); !errors.Is(err, error2.NotFound("the duplicate report was not started")) {
+		t.Fatalf("unexpected error '%v'", err)
+	}
+
+	report, err := store.StartDuplicateReport(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Status != model.ReportRunning {
+		t.Fatalf("unexpected report '%+v'", report)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err = detector.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = store.ObtainDuplicateReport(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []model.DuplicateCluster{
+		{
+			Brand: "adidas",
+			Products: []model.DuplicateProduct{
+				{SKU: "FAL-1000006", Name: "Super Star", Brand: "Adidas"},
+				{SKU: "FAL-1000007", Name: "SUPER-STAR", Brand: "adidas"},
+			},
+		},
+		{
+			Brand: "nike",
+			Products: []model.DuplicateProduct{
+				{SKU: "FAL-1000001", Name: "Nike Air Max 90", Brand: "Nike"},
+				{SKU: "FAL-1000002", Name: "NIKE air-max 90", Brand: "NIKE"},
+				{SKU: "FAL-1000003", Name: "Nike Air Max 90 ", Brand: "nike"},
+			},
+		},
+	}
+
+	if report.Status != model.ReportCompleted || report.Products != 6 || report.CompletedAt == nil {
+		t.Fatalf("unexpected report '%+v'", report)
+	}
+
+	if !reflect.DeepEqual(expected, report.Clusters) {
+		t.Fatalf("expected clusters '%+v' unexpected clusters '%+v'", expected, report.Clusters)
+	}
+}
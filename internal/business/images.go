@@ -0,0 +1,426 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Default values of the ImagePolicy
+const (
+	// DefaultMaxURLLength default maximum number of characters of the image URLs
+	DefaultMaxURLLength = 2048
+	// DefaultMaxImages default maximum number of other images of a product
+	DefaultMaxImages = 20
+	// DefaultImageTimeout default time limit to verify each image
+	DefaultImageTimeout = 5 * time.Second
+)
+
+// defaultImageSchemes schemes allowed when the ImagePolicy does not list any scheme
+var defaultImageSchemes = []string{"http", "https"}
+
+// ImagePolicy restrictions of the image URLs of the products, it is loaded from YAML or JSON files (see images.example.yaml)
+type ImagePolicy struct {
+	// Schemes allowed URL schemes, default value [http, https]
+	Schemes []string `yaml:"schemes" json:"schemes"`
+	// Hosts allowed hosts, "*.example.com" allows every subdomain of example.com. If it is empty any host is allowed
+	Hosts []string `yaml:"hosts" json:"hosts"`
+	// MaxURLLength maximum number of characters of each URL, if it is zero DefaultMaxURLLength is used
+	MaxURLLength int `yaml:"maxURLLength" json:"maxURLLength"`
+	// MaxImages maximum number of other images, if it is zero DefaultMaxImages is used
+	MaxImages int `yaml:"maxImages" json:"maxImages"`
+	// Verification requests made to the image URLs before accepting the products
+	Verification ImageVerification `yaml:"verification" json:"verification"`
+}
+
+// ImageVerification settings of the requests made to verify that the images are reachable
+type ImageVerification struct {
+	// Enabled indicates if the images are verified, by default they are not verified
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Timeout time limit to verify each image, if it is zero DefaultImageTimeout is used
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// MaxSize maximum number of bytes of each image, if it is zero the size is not checked
+	MaxSize int64 `yaml:"maxSize" json:"maxSize"`
+}
+
+// Validate checks that the ImagePolicy only contains valid values
+func (p ImagePolicy) Validate() error {
+	for _, scheme := range p.Schemes {
+		if scheme == "" || strings.ToLower(scheme) != scheme {
+			return fmt.Errorf(`scheme '%s' must be lowercase and not blank`, scheme)
+		}
+	}
+
+	for _, host := range p.Hosts {
+		if strings.TrimPrefix(host, "*.") == "" || strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+			return fmt.Errorf(`host '%s' is not supported`, host)
+		}
+	}
+
+	switch {
+	case p.MaxURLLength < 0:
+		return errors.New("maxURLLength must not be negative")
+	case p.MaxImages < 0:
+		return errors.New("maxImages must not be negative")
+	case p.Verification.Timeout < 0:
+		return errors.New("verification timeout must not be negative")
+	case p.Verification.MaxSize < 0:
+		return errors.New("verification maxSize must not be negative")
+	}
+
+	return nil
+}
+
+// schemes returns the allowed URL schemes
+func (p ImagePolicy) schemes() []string {
+	if len(p.Schemes) == 0 {
+		return defaultImageSchemes
+	}
+
+	return p.Schemes
+}
+
+// maxURLLength returns the maximum number of characters of the URLs
+func (p ImagePolicy) maxURLLength() int {
+	if p.MaxURLLength <= 0 {
+		return DefaultMaxURLLength
+	}
+
+	return p.MaxURLLength
+}
+
+// maxImages returns the maximum number of other images
+func (p ImagePolicy) maxImages() int {
+	if p.MaxImages <= 0 {
+		return DefaultMaxImages
+	}
+
+	return p.MaxImages
+}
+
+// allowsHost indicates if the host (without port) is allowed
+func (p ImagePolicy) allowsHost(host string) bool {
+	if len(p.Hosts) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(host)
+
+	for _, allowed := range p.Hosts {
+		allowed = strings.ToLower(allowed)
+
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+
+			continue
+		}
+
+		if host == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// productImage URL of the product data located by the JSON pointer
+type productImage struct {
+	pointer string
+	label   string
+	url     model.URL
+}
+
+// productImages returns the non-nil image URLs of the model.Product
+func productImages(product model.Product) []productImage {
+	images := make([]productImage, 0, len(product.OtherImages)+1)
+
+	if product.PrincipalImage != nil && product.PrincipalImage.URL != nil {
+		images = append(images, productImage{pointer: "/principalImage", label: "principal image", url: *product.PrincipalImage})
+	}
+
+	for i, u := range product.OtherImages {
+		if u.URL == nil {
+			continue
+		}
+
+		images = append(images, productImage{pointer: fmt.Sprintf("/otherImages/%d", i), label: fmt.Sprintf("image %d", i), url: u})
+	}
+
+	return images
+}
+
+// imageKey identifies the images that are the same, the scheme and the host are case-insensitive
+func imageKey(u model.URL) string {
+	normalized := *u.URL
+	normalized.Scheme = strings.ToLower(normalized.Scheme)
+	normalized.Host = strings.ToLower(normalized.Host)
+
+	return normalized.String()
+}
+
+// dedupe returns the other images without the duplicates and without the principal image, the order is kept
+func (p ImagePolicy) dedupe(principal *model.URL, others model.URLs) model.URLs {
+	if len(others) == 0 {
+		return others
+	}
+
+	seen := make(map[string]bool, len(others)+1)
+	if principal != nil && principal.URL != nil {
+		seen[imageKey(*principal)] = true
+	}
+
+	unique := make(model.URLs, 0, len(others))
+
+	for _, u := range others {
+		if u.URL != nil {
+			if seen[imageKey(u)] {
+				continue
+			}
+
+			seen[imageKey(u)] = true
+		}
+
+		unique = append(unique, u)
+	}
+
+	return unique
+}
+
+// check adds the broken rules of the ImagePolicy to the violations, only the first broken rule of each image is reported
+// and the images that already broke other rules or are owned (e.g. the media URLs served by the API) are skipped
+func (p ImagePolicy) check(product model.Product, v *violations, owned func(model.URL) bool) {
+	if maxImages := p.maxImages(); len(product.OtherImages) > maxImages {
+		v.add("/otherImages", RuleMaxItems, fmt.Sprintf("the product has %d other images, the maximum is %d", len(product.OtherImages), maxImages), error2.RuleParams{"max": maxImages})
+	}
+
+	broken := make(map[string]bool, len(v.Violations))
+	for _, violation := range v.Violations {
+		broken[violation.Pointer] = true
+	}
+
+	for _, image := range productImages(product) {
+		if broken[image.pointer] || owned(image.url) {
+			continue
+		}
+
+		raw := image.url.String()
+
+		switch {
+		case len(raw) > p.maxURLLength():
+			v.add(image.pointer, RuleMaxLength, fmt.Sprintf("%s URL is too large", image.label), error2.RuleParams{"max": p.maxURLLength()})
+		case !slices.Contains(p.schemes(), strings.ToLower(image.url.Scheme)) || image.url.Host == "":
+			v.add(image.pointer, RuleURL, fmt.Sprintf("%s must be an absolute URL with scheme [%s]", image.label, strings.Join(p.schemes(), ", ")), error2.RuleParams{"schemes": p.schemes()})
+		case !p.allowsHost(image.url.Hostname()):
+			v.add(image.pointer, RuleHost, fmt.Sprintf("%s host '%s' is not allowed", image.label, image.url.Hostname()), error2.RuleParams{"hosts": p.Hosts})
+		}
+	}
+}
+
+// reachableParams parameters of the rule RuleReachable
+func (p ImagePolicy) reachableParams() error2.RuleParams {
+	params := error2.RuleParams{"contentType": "image/*"}
+
+	if p.Verification.MaxSize > 0 {
+		params["maxSize"] = p.Verification.MaxSize
+	}
+
+	return params
+}
+
+// verify requests every image of the model.Product that is not owned at the same time and adds the images that are
+// not reachable, are not images or are too large to the violations
+func (p ImagePolicy) verify(ctx context.Context, client *http.Client, product model.Product, v *violations, owned func(model.URL) bool) {
+	images := slices.DeleteFunc(productImages(product), func(image productImage) bool { return owned(image.url) })
+	reasons := make([]string, len(images))
+
+	wg := sync.WaitGroup{}
+
+	for i, image := range images {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := p.verifyImage(ctx, client, image.url); err != nil {
+				reasons[i] = fmt.Sprintf("%s could not be verified: %s", image.label, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for i, reason := range reasons {
+		if reason != "" {
+			v.add(images[i].pointer, RuleReachable, reason, p.reachableParams())
+		}
+	}
+}
+
+// verifyImage requests the image using the method HEAD, if the server does not support it the method GET is used.
+// The response must be successful, its Content-Type must be image/* and its size must not exceed the maximum
+func (p ImagePolicy) verifyImage(ctx context.Context, client *http.Client, u model.URL) error {
+	timeout := p.Verification.Timeout
+	if timeout <= 0 {
+		timeout = DefaultImageTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	response, err := p.request(ctx, client, http.MethodHead, u)
+	if err == nil && (response.StatusCode == http.StatusMethodNotAllowed || response.StatusCode == http.StatusNotImplemented) {
+		_ = response.Body.Close()
+		response, err = p.request(ctx, client, http.MethodGet, u)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "image/") {
+		return fmt.Errorf("unexpected content type '%s'", response.Header.Get("Content-Type"))
+	}
+
+	maxSize := p.Verification.MaxSize
+	if maxSize <= 0 {
+		return nil
+	}
+
+	size := response.ContentLength
+
+	// The size of the responses without Content-Length is measured reading at most one byte more than the maximum
+	if size < 0 && response.Request.Method == http.MethodGet {
+		size, err = io.Copy(io.Discard, io.LimitReader(response.Body, maxSize+1))
+		if err != nil {
+			return err
+		}
+	}
+
+	if size > maxSize {
+		return fmt.Errorf("the image has %d bytes, the maximum is %d", size, maxSize)
+	}
+
+	return nil
+}
+
+// request sends a request to the image URL
+func (p ImagePolicy) request(ctx context.Context, client *http.Client, method string, u model.URL) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Accept", "image/*")
+
+	return client.Do(request)
+}
+
+// maxImageRedirects maximum number of redirects followed by the Client of the ImagePolicy
+const maxImageRedirects = 5
+
+// Client builds the *http.Client used to request the image URLs. Every redirect must meet the ImagePolicy (scheme and host)
+// and the connections to loopback, private, link-local, multicast and unspecified addresses are refused. The addresses are
+// checked after the name resolution, so the hosts that resolve to internal addresses are refused too.
+//
+// The proxy of the environment is not used since the addresses behind a proxy could not be checked
+func (p ImagePolicy) Client() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   refuseInternal,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= maxImageRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxImageRedirects)
+			}
+
+			return p.allows(request.URL)
+		},
+	}
+}
+
+// allows returns an error if the URL does not meet the scheme and the host allowed by the ImagePolicy
+func (p ImagePolicy) allows(u *url.URL) error {
+	if !slices.Contains(p.schemes(), strings.ToLower(u.Scheme)) || u.Host == "" {
+		return fmt.Errorf("redirect to '%s' is not allowed, it must be an absolute URL with scheme [%s]", u, strings.Join(p.schemes(), ", "))
+	}
+
+	if !p.allowsHost(u.Hostname()) {
+		return fmt.Errorf("redirect to host '%s' is not allowed", u.Hostname())
+	}
+
+	return nil
+}
+
+// refuseInternal control function of the net.Dialer that refuses the connections to addresses that are not public
+func refuseInternal(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+
+	switch {
+	case ip == nil:
+		return fmt.Errorf("invalid address '%s'", host)
+	case ip.IsLoopback(), ip.IsPrivate(), ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast(), ip.IsMulticast(), ip.IsUnspecified():
+		return fmt.Errorf("address '%s' is not public", ip)
+	}
+
+	return nil
+}
+
+// httpClient returns the *http.Client used to verify the images
+func (s ProductStore) httpClient() *http.Client {
+	if s.ImageClient == nil {
+		return s.Images.Client()
+	}
+
+	return s.ImageClient
+}
+
+// verifyImages verifies the images of the model.Product if the ImagePolicy enables the verification
+func (s ProductStore) verifyImages(ctx context.Context, product model.Product) error {
+	if !s.Images.Verification.Enabled {
+		return nil
+	}
+
+	ctx, span := startSpan(ctx, "verify images")
+
+	v := &violations{metrics: s.Metrics}
+	s.Images.verify(ctx, s.httpClient(), product, v, s.Media.owns)
+
+	err := v.err()
+	endSpan(span, err)
+
+	return err
+}
@@ -0,0 +1,262 @@
+package business
+
+import (
+	"context"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"github.com/yael-castro/products-api/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mustURL parses the raw URL, the test fails if it is not valid
+func mustURL(t *testing.T, raw string) model.URL {
+	t.Helper()
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return model.URL{URL: u}
+}
+
+func TestImagePolicy_check(t *testing.T) {
+	tdt := []struct {
+		policy             ImagePolicy
+		principalImage     string
+		otherImages        []string
+		expectedViolations error2.Violations
+	}{
+		{
+			principalImage: "https://example.com/a.png",
+			otherImages:    []string{"http://example.com/b.png"},
+		},
+		{
+			policy:         ImagePolicy{Schemes: []string{"https"}},
+			principalImage: "javascript:alert(1)",
+			otherImages:    []string{"http://example.com/b.png"},
+			expectedViolations: error2.Violations{
+				{Field: "principalImage", Pointer: "/principalImage", Rule: RuleURL, Params: error2.RuleParams{"schemes": []string{"https"}}, Reason: "principal image must be an absolute URL with scheme [https]"},
+				{Field: "otherImages", Pointer: "/otherImages/0", Rule: RuleURL, Params: error2.RuleParams{"schemes": []string{"https"}}, Reason: "image 0 must be an absolute URL with scheme [https]"},
+			},
+		},
+		{
+			policy:         ImagePolicy{Hosts: []string{"cdn.example.com", "*.images.example.com"}},
+			principalImage: "https://CDN.example.com/a.png",
+			otherImages:    []string{"https://a.images.example.com/b.png", "https://example.com/c.png"},
+			expectedViolations: error2.Violations{
+				{Field: "otherImages", Pointer: "/otherImages/1", Rule: RuleHost, Params: error2.RuleParams{"hosts": []string{"cdn.example.com", "*.images.example.com"}}, Reason: "image 1 host 'example.com' is not allowed"},
+			},
+		},
+		{
+			policy:         ImagePolicy{MaxURLLength: 30, MaxImages: 1},
+			principalImage: "https://example.com/a.png",
+			otherImages:    []string{"https://example.com/" + strings.Repeat("b", 10) + ".png", "https://example.com/c.png"},
+			expectedViolations: error2.Violations{
+				{Field: "otherImages", Pointer: "/otherImages", Rule: RuleMaxItems, Params: error2.RuleParams{"max": 1}, Reason: "the product has 2 other images, the maximum is 1"},
+				{Field: "otherImages", Pointer: "/otherImages/0", Rule: RuleMaxLength, Params: error2.RuleParams{"max": 30}, Reason: "image 0 URL is too large"},
+			},
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			principalImage := mustURL(t, v.principalImage)
+			product := model.Product{PrincipalImage: &principalImage}
+
+			for _, raw := range v.otherImages {
+				product.OtherImages = append(product.OtherImages, mustURL(t, raw))
+			}
+
+			violations := &violations{}
+			v.policy.check(product, violations, (*ImageIngester)(nil).owns)
+
+			if len(v.expectedViolations) == 0 && len(violations.Violations) == 0 {
+				t.Skip("SUCCESS")
+			}
+
+			if !reflect.DeepEqual(v.expectedViolations, violations.Violations) {
+				t.Fatalf("expected violations '%+v' unexpected violations '%+v'", v.expectedViolations, violations.Violations)
+			}
+		})
+	}
+}
+
+func TestImagePolicy_dedupe(t *testing.T) {
+	principalImage := model.URL{URL: &url.URL{Scheme: "https", Host: "example.com", Path: "/a.png"}}
+
+	others := model.URLs{
+		{URL: &url.URL{Scheme: "https", Host: "EXAMPLE.com", Path: "/a.png"}},
+		{URL: &url.URL{Scheme: "https", Host: "example.com", Path: "/b.png"}},
+		{URL: &url.URL{Scheme: "HTTPS", Host: "example.com", Path: "/b.png"}},
+		{URL: &url.URL{Scheme: "https", Host: "example.com", Path: "/B.png"}},
+	}
+
+	unique := ImagePolicy{}.dedupe(&principalImage, others)
+
+	if expected := "https://example.com/b.png https://example.com/B.png"; unique.String() != expected {
+		t.Fatalf("expected images '%s' unexpected images '%s'", expected, unique.String())
+	}
+}
+
+func TestProductStore_CreateProduct_images(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("Content-Length", "10")
+		case "/large.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("Content-Length", "2048")
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		case "/get.jpg":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			w.Header().Set("Content-Type", "image/jpeg")
+			_, _ = w.Write(make([]byte, 100))
+		case "/slow.png":
+			time.Sleep(200 * time.Millisecond)
+			w.Header().Set("Content-Type", "image/png")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tdt := []struct {
+		sku         model.SKU
+		images      []string
+		expectedErr error
+	}{
+		{
+			sku:    "FAL-1000001",
+			images: []string{"/image.png", "/get.jpg"},
+		},
+		{
+			sku:         "FAL-1000002",
+			images:      []string{"/image.png", "/missing.png"},
+			expectedErr: error2.Violations{{Field: "otherImages", Reason: "image 0 could not be verified: unexpected status code 404"}},
+		},
+		{
+			sku:         "FAL-1000003",
+			images:      []string{"/page.html"},
+			expectedErr: error2.Violations{{Field: "principalImage", Reason: "principal image could not be verified: unexpected content type 'text/html; charset=utf-8'"}},
+		},
+		{
+			sku:         "FAL-1000004",
+			images:      []string{"/large.png"},
+			expectedErr: error2.Violations{{Field: "principalImage", Reason: "principal image could not be verified: the image has 2048 bytes, the maximum is 1024"}},
+		},
+		{
+			sku:         "FAL-1000005",
+			images:      []string{"/slow.png"},
+			expectedErr: error2.Violations{{Field: "principalImage", Reason: "principal image could not be verified: Head \"" + server.URL + "/slow.png\": context deadline exceeded"}},
+		},
+	}
+
+	store := ProductStore{
+		StorageManager: &repository.MockStorage[model.SKU, model.Product]{},
+		Images: ImagePolicy{
+			Hosts: []string{"127.0.0.1"},
+			Verification: ImageVerification{
+				Enabled: true,
+				Timeout: 100 * time.Millisecond,
+				MaxSize: 1024,
+			},
+		},
+		ImageClient: server.Client(),
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			principalImage := mustURL(t, server.URL+v.images[0])
+			product := model.Product{SKU: v.sku, Name: "Shoes", Brand: "Nike", Price: 10, PrincipalImage: &principalImage}
+
+			for _, path := range v.images[1:] {
+				product.OtherImages = append(product.OtherImages, mustURL(t, server.URL+path))
+			}
+
+			err := store.CreateProduct(context.Background(), &product)
+			if !matchError(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			if err != nil {
+				t.Skip(err)
+			}
+
+			t.Log(product)
+		})
+	}
+}
+
+func TestImagePolicy_Client(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+	}))
+	defer server.Close()
+
+	client := ImagePolicy{Schemes: []string{"https"}, Hosts: []string{"*.example.com"}}.Client()
+
+	// The internal addresses are refused
+	response, err := client.Get(server.URL + "/image.png")
+	if err == nil {
+		_ = response.Body.Close()
+		t.Fatal("expected error for an internal address")
+	}
+
+	if !strings.Contains(err.Error(), "is not public") {
+		t.Fatalf("unexpected error '%v'", err)
+	}
+
+	tdt := []struct {
+		url         string
+		via         int
+		expectedErr string
+	}{
+		{
+			url: "https://cdn.example.com/image.png",
+			via: 1,
+		},
+		{
+			url:         "https://169.254.169.254/latest/meta-data",
+			via:         1,
+			expectedErr: "redirect to host '169.254.169.254' is not allowed",
+		},
+		{
+			url:         "http://cdn.example.com/image.png",
+			via:         1,
+			expectedErr: "redirect to 'http://cdn.example.com/image.png' is not allowed, it must be an absolute URL with scheme [https]",
+		},
+		{
+			url:         "https://cdn.example.com/image.png",
+			via:         maxImageRedirects,
+			expectedErr: "stopped after 5 redirects",
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodGet, v.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.CheckRedirect(request, make([]*http.Request, v.via))
+			if (err == nil && v.expectedErr != "") || (err != nil && err.Error() != v.expectedErr) {
+				t.Fatalf("expected error '%s' unexpected error '%v'", v.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"slices"
+	"strings"
+	"time"
+)
+
+// lifecycle allowed transitions of the model.Status, each status is related to the statuses that could follow it
+var lifecycle = map[model.Status][]model.Status{
+	model.StatusDraft:        {model.StatusInReview, model.StatusArchived},
+	model.StatusInReview:     {model.StatusDraft, model.StatusPublished},
+	model.StatusPublished:    {model.StatusDiscontinued},
+	model.StatusDiscontinued: {model.StatusPublished, model.StatusArchived},
+	model.StatusArchived:     {},
+}
+
+// publicStatuses statuses of the products listed when the model.ProductFilter does not contain statuses
+var publicStatuses = []model.Status{model.StatusPublished}
+
+// statusNames returns the statuses as strings, they are used as parameters of the validation rules
+func statusNames(statuses []model.Status) []string {
+	names := make([]string, 0, len(statuses))
+
+	for _, status := range statuses {
+		names = append(names, string(status))
+	}
+
+	return names
+}
+
+// TransitionProduct changes the model.Status of the model.Product identified by the model.SKU and records who made
+// the change and when. Returns the updated model.Product
+//
+// The transition must be allowed by the lifecycle, otherwise returns error2.Conflict:
+//
+//	draft -> in_review -> published -> discontinued -> archived
+//	in_review -> draft (the review was rejected)
+//	discontinued -> published (the product is sold again)
+//	draft -> archived (the draft was abandoned)
+func (s ProductStore) TransitionProduct(ctx context.Context, sku model.SKU, status model.Status) (model.Product, error) {
+	if scheme, err := s.schemes().Match(sku); err != nil {
+		return model.Product{}, s.invalid("sku", RuleFormat, err.Error(), skuParams(scheme))
+	}
+
+	if !status.IsValid() {
+		return model.Product{}, s.invalid("status", RuleEnum, fmt.Sprintf("unknown status '%s'", status), error2.RuleParams{"values": statusNames(model.Statuses)})
+	}
+
+	if s.Lifecycle == nil {
+		return model.Product{}, error2.NotImplemented("product lifecycle is not enabled")
+	}
+
+	product, err := s.Obtain(ctx, sku)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	if !slices.Contains(lifecycle[product.Status], status) {
+		return model.Product{}, error2.Conflict(fmt.Sprintf(
+			"the product could not change from '%s' to '%s', the allowed statuses are [%s]",
+			product.Status,
+			status,
+			strings.Join(statusNames(lifecycle[product.Status]), ", "),
+		))
+	}
+
+	transition := model.StatusTransition{
+		SKU:       sku,
+		From:      product.Status,
+		To:        status,
+		ChangedAt: time.Now().UTC(),
+	}
+
+	if caller, ok := CallerFrom(ctx); ok {
+		transition.ChangedBy = caller.Subject
+	}
+
+	if err = s.Lifecycle.Transition(ctx, transition); err != nil {
+		return model.Product{}, err
+	}
+
+	product.Status = status
+
+	s.logger().InfoContext(ctx, "product status changed", "sku", sku, "from", transition.From, "to", transition.To, "by", transition.ChangedBy)
+	return product, nil
+}
+
+// ObtainTransitions returns the history of the model.Status of the model.Product identified by the model.SKU
+func (s ProductStore) ObtainTransitions(ctx context.Context, sku model.SKU) ([]model.StatusTransition, error) {
+	if scheme, err := s.schemes().Match(sku); err != nil {
+		return nil, s.invalid("sku", RuleFormat, err.Error(), skuParams(scheme))
+	}
+
+	if s.Lifecycle == nil {
+		return nil, error2.NotImplemented("product lifecycle is not enabled")
+	}
+
+	if _, err := s.Obtain(ctx, sku); err != nil {
+		return nil, err
+	}
+
+	return s.Lifecycle.Transitions(ctx, sku)
+}
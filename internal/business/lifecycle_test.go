@@ -0,0 +1,201 @@
+package business
+
+import (
+	"context"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"github.com/yael-castro/products-api/internal/repository"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestProductStore_TransitionProduct(t *testing.T) {
+	tdt := []struct {
+		sku            model.SKU
+		status         model.Status
+		caller         *model.Caller
+		expectedStatus model.Status
+		expectedErr    error
+	}{
+		{
+			sku:            "FAL-1000001",
+			status:         model.StatusInReview,
+			caller:         &model.Caller{Subject: "editor"},
+			expectedStatus: model.StatusInReview,
+		},
+		{
+			sku:            "FAL-1000001",
+			status:         model.StatusDraft,
+			caller:         &model.Caller{Subject: "reviewer"},
+			expectedStatus: model.StatusDraft,
+		},
+		{
+			sku:         "FAL-1000001",
+			status:      model.StatusPublished,
+			expectedErr: error2.Conflict("the product could not change from 'draft' to 'published', the allowed statuses are [in_review, archived]"),
+		},
+		{
+			sku:            "FAL-1000001",
+			status:         model.StatusInReview,
+			expectedStatus: model.StatusInReview,
+		},
+		{
+			sku:            "FAL-1000001",
+			status:         model.StatusPublished,
+			caller:         &model.Caller{Subject: "reviewer"},
+			expectedStatus: model.StatusPublished,
+		},
+		{
+			sku:         "FAL-1000001",
+			status:      model.StatusPublished,
+			expectedErr: error2.Conflict("the product could not change from 'published' to 'published', the allowed statuses are [discontinued]"),
+		},
+		{
+			sku:            "FAL-1000001",
+			status:         model.StatusDiscontinued,
+			expectedStatus: model.StatusDiscontinued,
+		},
+		{
+			sku:            "FAL-1000001",
+			status:         model.StatusArchived,
+			expectedStatus: model.StatusArchived,
+		},
+		{
+			sku:         "FAL-1000001",
+			status:      model.StatusDraft,
+			expectedErr: error2.Conflict("the product could not change from 'archived' to 'draft', the allowed statuses are []"),
+		},
+		{
+			sku:         "FAL-1000001",
+			status:      "live",
+			expectedErr: error2.Violations{{Field: "status", Reason: "unknown status 'live'"}},
+		},
+		{
+			sku:         "FAL-1000002",
+			status:      model.StatusInReview,
+			expectedErr: error2.NotFound("product identified by sku 'FAL-1000002' does not exist"),
+		},
+	}
+
+	storage := &repository.MockStorage[model.SKU, model.Product]{
+		"FAL-1000001": {SKU: "FAL-1000001", Status: model.StatusDraft},
+	}
+
+	lifecycle := &repository.MockLifecycle{Storage: storage}
+	store := ProductStore{StorageManager: storage, Lifecycle: lifecycle}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := context.Background()
+			if v.caller != nil {
+				ctx = WithCaller(ctx, *v.caller)
+			}
+
+			product, err := store.TransitionProduct(ctx, v.sku, v.status)
+			if !matchError(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			if err != nil {
+				t.Skip(err)
+			}
+
+			if product.Status != v.expectedStatus {
+				t.Fatalf("expected status '%s' unexpected status '%s'", v.expectedStatus, product.Status)
+			}
+
+			if stored, _ := storage.Obtain(ctx, v.sku); stored.Status != v.expectedStatus {
+				t.Fatalf("expected stored status '%s' unexpected status '%s'", v.expectedStatus, stored.Status)
+			}
+		})
+	}
+
+	transitions, err := store.ObtainTransitions(context.Background(), "FAL-1000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedChanges := [][3]string{
+		{"draft", "in_review", "editor"},
+		{"in_review", "draft", "reviewer"},
+		{"draft", "in_review", ""},
+		{"in_review", "published", "reviewer"},
+		{"published", "discontinued", ""},
+		{"discontinued", "archived", ""},
+	}
+
+	changes := make([][3]string, 0, len(transitions))
+	for _, transition := range transitions {
+		if transition.ChangedAt.IsZero() {
+			t.Fatalf("missing time of the transition '%+v'", transition)
+		}
+
+		changes = append(changes, [3]string{string(transition.From), string(transition.To), transition.ChangedBy})
+	}
+
+	if !reflect.DeepEqual(expectedChanges, changes) {
+		t.Fatalf("expected transitions '%v' unexpected transitions '%v'", expectedChanges, changes)
+	}
+}
+
+func TestProductStore_ListProducts(t *testing.T) {
+	tdt := []struct {
+		filter        model.ProductFilter
+		expectedLen   int
+		expectedTotal int
+	}{
+		{
+			expectedLen:   2,
+			expectedTotal: 2,
+		},
+		{
+			filter:        model.ProductFilter{Statuses: []model.Status{model.StatusDraft, model.StatusInReview}},
+			expectedLen:   2,
+			expectedTotal: 2,
+		},
+		{
+			filter:        model.ProductFilter{Statuses: model.Statuses},
+			expectedLen:   5,
+			expectedTotal: 5,
+		},
+		{
+			filter:        model.ProductFilter{Brand: "NIKE", NameContains: "MAX", MinPrice: &[]float64{5}[0]},
+			expectedLen:   1,
+			expectedTotal: 1,
+		},
+		{
+			filter:        model.ProductFilter{Statuses: model.Statuses, Offset: 1, Limit: 2},
+			expectedLen:   2,
+			expectedTotal: 5,
+		},
+		{
+			filter:        model.ProductFilter{Statuses: model.Statuses, Offset: 4, Limit: 2},
+			expectedLen:   1,
+			expectedTotal: 5,
+		},
+	}
+
+	store := ProductStore{
+		StorageManager: &repository.MockStorage[model.SKU, model.Product]{
+			"FAL-1000001": {SKU: "FAL-1000001", Status: model.StatusDraft},
+			"FAL-1000002": {SKU: "FAL-1000002", Status: model.StatusInReview},
+			"FAL-1000003": {SKU: "FAL-1000003", Name: "Air Max 90", Brand: "Nike", Price: 10, Status: model.StatusPublished},
+			"FAL-1000004": {SKU: "FAL-1000004", Name: "Air Max 95", Brand: "Nike", Price: 2, Status: model.StatusPublished},
+			"FAL-1000005": {SKU: "FAL-1000005", Status: model.StatusArchived},
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			page, err := store.ListProducts(context.Background(), v.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(page.Items) != v.expectedLen || page.TotalCount != v.expectedTotal {
+				t.Fatalf("expected %d of %d products unexpected page '%+v'", v.expectedLen, v.expectedTotal, page)
+			}
+		})
+	}
+}
@@ -0,0 +1,559 @@
+package business
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"github.com/yael-castro/products-api/internal/repository"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MediaPath path of the URLs of the media served by the API, the rest of the path is the key of the repository.Blob
+const MediaPath = "/media/"
+
+// Default values of the MediaConfig
+const (
+	// DefaultMediaWorkers default number of images processed at the same time
+	DefaultMediaWorkers = 4
+	// DefaultMediaQueueSize default number of products waiting to be processed
+	DefaultMediaQueueSize = 1000
+	// DefaultMediaMaxSize default maximum number of bytes of the original images
+	DefaultMediaMaxSize = 10 << 20
+	// DefaultMediaTimeout default time limit to download each image
+	DefaultMediaTimeout = 30 * time.Second
+	// DefaultMediaMaxPixels default maximum number of pixels (width x height) of the original images
+	DefaultMediaMaxPixels = 40_000_000
+)
+
+// DefaultThumbnailWidths default widths of the thumbnails in pixels
+var DefaultThumbnailWidths = []int{160, 480}
+
+// DefaultUploadContentTypes default media types of the images uploaded by the clients
+var DefaultUploadContentTypes = []string{"image/png", "image/jpeg", "image/gif"}
+
+// MediaConfig settings of the *ImageIngester
+type MediaConfig struct {
+	// BaseURL absolute URL of the API used to build the media URLs (e.g. "https://api.example.com")
+	BaseURL string
+	// Workers number of images processed at the same time, if it is zero DefaultMediaWorkers is used
+	Workers int
+	// QueueSize number of products waiting to be processed, if it is zero DefaultMediaQueueSize is used.
+	// If the queue is full the images of the product are not ingested
+	QueueSize int
+	// MaxSize maximum number of bytes of the original images, if it is zero DefaultMediaMaxSize is used
+	MaxSize int64
+	// Timeout time limit to download each image, if it is zero DefaultMediaTimeout is used
+	Timeout time.Duration
+	// MaxPixels maximum number of pixels (width x height) of the original images, if it is zero DefaultMediaMaxPixels is used.
+	// The compressed images could be small and still require a lot of memory to be decoded
+	MaxPixels int64
+	// ThumbnailWidths widths of the thumbnails in pixels, if it is empty DefaultThumbnailWidths are used.
+	// The thumbnails keep the aspect ratio and the images are never enlarged
+	ThumbnailWidths []int
+	// UploadContentTypes media types allowed for the uploaded images, if it is empty DefaultUploadContentTypes are used.
+	// The media type is detected from the content, the type declared by the client is ignored
+	UploadContentTypes []string
+}
+
+// Validate checks that the MediaConfig only contains valid values
+func (c MediaConfig) Validate() error {
+	base, err := url.Parse(c.BaseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return fmt.Errorf(`base url '%s' must be an absolute http or https URL`, c.BaseURL)
+	}
+
+	for _, width := range c.ThumbnailWidths {
+		if width <= 0 {
+			return fmt.Errorf(`thumbnail width %d must be positive`, width)
+		}
+	}
+
+	for _, contentType := range c.UploadContentTypes {
+		if _, ok := uploadFormats[contentType]; !ok {
+			return fmt.Errorf(`upload content type '%s' is not supported`, contentType)
+		}
+	}
+
+	return nil
+}
+
+// "implement" constraint for *ImageIngester
+var _ MediaManager = (*ImageIngester)(nil)
+
+// ImageIngester downloads the images of the products in background, stores the originals and their thumbnails
+// into a repository.BlobStore and rewrites the image URLs of the products to the media URLs served by the API.
+//
+// The images are identified by the SHA-256 of their content, the original is stored as "<sha256>/original.<ext>"
+// and each thumbnail as "<sha256>/<width>w.<ext>"
+type ImageIngester struct {
+	config  MediaConfig
+	storage repository.StorageManager[model.SKU, model.Product]
+	images  repository.ImageManager
+	blobs   repository.BlobStore
+	client  *http.Client
+	logger  *slog.Logger
+
+	queue    chan model.SKU
+	wg       sync.WaitGroup
+	once     sync.Once
+	mutex    sync.RWMutex
+	closed   bool
+	products sync.Map
+}
+
+// NewImageIngester validates the MediaConfig and starts the workers of the *ImageIngester, the images of the products are
+// replaced using the repository.ImageManager. If the *http.Client is nil the client of an empty ImagePolicy is used
+// (see ImagePolicy.Client) and if the *slog.Logger is nil slog.Default is used
+func NewImageIngester(
+	config MediaConfig,
+	storage repository.StorageManager[model.SKU, model.Product],
+	images repository.ImageManager,
+	blobs repository.BlobStore,
+	client *http.Client,
+	logger *slog.Logger,
+) (*ImageIngester, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+
+	if config.Workers <= 0 {
+		config.Workers = DefaultMediaWorkers
+	}
+
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultMediaQueueSize
+	}
+
+	if config.MaxSize <= 0 {
+		config.MaxSize = DefaultMediaMaxSize
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultMediaTimeout
+	}
+
+	if config.MaxPixels <= 0 {
+		config.MaxPixels = DefaultMediaMaxPixels
+	}
+
+	if len(config.ThumbnailWidths) == 0 {
+		config.ThumbnailWidths = DefaultThumbnailWidths
+	}
+
+	if len(config.UploadContentTypes) == 0 {
+		config.UploadContentTypes = DefaultUploadContentTypes
+	}
+
+	if client == nil {
+		client = ImagePolicy{}.Client()
+	}
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	i := &ImageIngester{
+		config:  config,
+		storage: storage,
+		images:  images,
+		blobs:   blobs,
+		client:  client,
+		logger:  logger,
+		queue:   make(chan model.SKU, config.QueueSize),
+	}
+
+	i.wg.Add(config.Workers)
+
+	for range config.Workers {
+		go i.work()
+	}
+
+	return i, nil
+}
+
+// Enqueue schedules the ingestion of the images of the product, it never blocks.
+// The products already waiting in the queue are not enqueued again
+func (i *ImageIngester) Enqueue(ctx context.Context, sku model.SKU) {
+	if i == nil {
+		return
+	}
+
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	if i.closed {
+		return
+	}
+
+	if _, pending := i.products.LoadOrStore(sku, true); pending {
+		return
+	}
+
+	select {
+	case i.queue <- sku:
+	default:
+		i.products.Delete(sku)
+		i.logger.WarnContext(ctx, "media queue is full, the product images were not ingested", "sku", sku)
+	}
+}
+
+// MaxSize returns the maximum number of bytes of the original images
+func (i *ImageIngester) MaxSize() int64 {
+	return i.config.MaxSize
+}
+
+// Close stops receiving products and waits until the workers process the products in the queue or ctx is done
+func (i *ImageIngester) Close(ctx context.Context) error {
+	i.once.Do(func() {
+		i.mutex.Lock()
+		defer i.mutex.Unlock()
+
+		i.closed = true
+		close(i.queue)
+	})
+
+	done := make(chan struct{})
+
+	go func() {
+		i.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// work ingests the images of the products received from the queue
+func (i *ImageIngester) work() {
+	defer i.wg.Done()
+
+	for sku := range i.queue {
+		i.products.Delete(sku)
+
+		if err := i.Ingest(context.Background(), sku); err != nil {
+			i.logger.Error("product images were not ingested", "sku", sku, "error", err)
+		}
+	}
+}
+
+// owns indicates if the URL is a media URL served by the API
+func (i *ImageIngester) owns(u model.URL) bool {
+	if i == nil || u.URL == nil {
+		return false
+	}
+
+	return strings.HasPrefix(u.String(), i.config.BaseURL+MediaPath)
+}
+
+// mediaURL builds the media URL of the key
+func (i *ImageIngester) mediaURL(key string) (model.URL, error) {
+	u, err := url.Parse(i.config.BaseURL + MediaPath + key)
+	return model.URL{URL: u}, err
+}
+
+// Ingest downloads every image of the product that is not a media URL, stores it along with its thumbnails
+// and replaces its URL in the stored product. The images that could not be ingested keep their URLs
+func (i *ImageIngester) Ingest(ctx context.Context, sku model.SKU) error {
+	ctx, span := startSpan(ctx, "ingest images")
+
+	err := func() error {
+		product, err := i.storage.Obtain(ctx, sku)
+		if err != nil {
+			return err
+		}
+
+		urls := make(map[string]model.URL)
+
+		for _, image := range productImages(product) {
+			if i.owns(image.url) {
+				continue
+			}
+
+			raw := image.url.String()
+			if _, ok := urls[raw]; ok {
+				continue
+			}
+
+			key, err := i.ingestImage(ctx, image.url)
+			if err != nil {
+				i.logger.WarnContext(ctx, "image was not ingested", "sku", sku, "url", raw, "error", err)
+				continue
+			}
+
+			if urls[raw], err = i.mediaURL(key); err != nil {
+				return err
+			}
+		}
+
+		if len(urls) == 0 {
+			return nil
+		}
+
+		// Only the image columns are replaced and only if they were not changed while the images were downloaded,
+		// otherwise the product is obtained again to replace the ingested images into its current images
+		for attempt := 1; ; attempt++ {
+			change := model.ImageChange{SKU: sku, From: model.ImagesOf(product)}
+			change.To = replaceImages(change.From, urls)
+
+			if change.To.Equal(change.From) {
+				return nil
+			}
+
+			err = i.images.ReplaceImages(ctx, change)
+			if err == nil {
+				break
+			}
+
+			if attempt >= maxIngestAttempts || !errors.As(err, new(error2.Conflict)) {
+				return err
+			}
+
+			if product, err = i.storage.Obtain(ctx, sku); err != nil {
+				return err
+			}
+		}
+
+		i.logger.InfoContext(ctx, "product images ingested", "sku", sku, "images", len(urls))
+		return nil
+	}()
+
+	endSpan(span, err)
+	return err
+}
+
+// maxIngestAttempts number of times the images of a product are replaced when they are changed at the same time by a client
+const maxIngestAttempts = 3
+
+// replaceImages returns a copy of the images where the URLs ingested (indexed by their original URL) are replaced
+func replaceImages(images model.Images, urls map[string]model.URL) model.Images {
+	replaced := model.Images{PrincipalImage: images.PrincipalImage, OtherImages: slices.Clone(images.OtherImages)}
+
+	if images.PrincipalImage != nil && images.PrincipalImage.URL != nil {
+		if u, ok := urls[images.PrincipalImage.String()]; ok {
+			replaced.PrincipalImage = &u
+		}
+	}
+
+	for j, image := range replaced.OtherImages {
+		if u, ok := urls[image.String()]; ok {
+			replaced.OtherImages[j] = u
+		}
+	}
+
+	return replaced
+}
+
+// mediaFormats extensions of the image formats decoded by the ImageIngester, they are the formats of the standard library
+var mediaFormats = map[string]string{"png": "png", "jpeg": "jpg", "gif": "gif"}
+
+// ingestImage downloads the image and stores it, returns the key of the original
+func (i *ImageIngester) ingestImage(ctx context.Context, u model.URL) (string, error) {
+	data, err := i.download(ctx, u)
+	if err != nil {
+		return "", err
+	}
+
+	return i.store(ctx, data)
+}
+
+// store decodes the image, stores the original and the thumbnails and returns the key of the original
+func (i *ImageIngester) store(ctx context.Context, data []byte) (string, error) {
+	if _, err := i.inspect(data); err != nil {
+		return "", err
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("the image could not be decoded: %w", err)
+	}
+
+	ext, ok := mediaFormats[format]
+	if !ok {
+		return "", fmt.Errorf("unsupported image format '%s'", format)
+	}
+
+	hash, key := originalKey(data, ext)
+
+	for _, width := range i.config.ThumbnailWidths {
+		thumbnail := bytes.Buffer{}
+
+		if err = encodeImage(&thumbnail, resize(img, width), format); err != nil {
+			return "", err
+		}
+
+		if err = i.blobs.Put(ctx, fmt.Sprintf("%s/%dw.%s", hash, width, thumbnailExt(ext)), &thumbnail); err != nil {
+			return "", err
+		}
+	}
+
+	// The original is stored at the end, so its key only exists if all the thumbnails were stored
+	if err = i.blobs.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// inspect decodes the dimensions of the image and returns its format, the images with more pixels than the maximum
+// return error2.TooLarge. The dimensions are checked before decoding the image, so those images are never decoded
+func (i *ImageIngester) inspect(data []byte) (string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("the image could not be decoded: %w", err)
+	}
+
+	if pixels := int64(config.Width) * int64(config.Height); pixels > i.config.MaxPixels {
+		return "", error2.TooLarge(fmt.Sprintf(
+			"the image has %dx%d pixels, the maximum is %d pixels",
+			config.Width,
+			config.Height,
+			i.config.MaxPixels,
+		))
+	}
+
+	return format, nil
+}
+
+// originalKey returns the SHA-256 of the image content and the key of the original image with the extension
+func originalKey(data []byte, ext string) (hash, key string) {
+	sum := sha256.Sum256(data)
+	hash = hex.EncodeToString(sum[:])
+
+	return hash, fmt.Sprintf("%s/original.%s", hash, ext)
+}
+
+// download requests the image, the response must be successful, its Content-Type must be image/*
+// and its size must not exceed the maximum
+func (i *ImageIngester) download(ctx context.Context, u model.URL) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, i.config.Timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Accept", "image/*")
+
+	response, err := i.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "image/") {
+		return nil, fmt.Errorf("unexpected content type '%s'", response.Header.Get("Content-Type"))
+	}
+
+	if response.ContentLength > i.config.MaxSize {
+		return nil, fmt.Errorf("the image has %d bytes, the maximum is %d", response.ContentLength, i.config.MaxSize)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, i.config.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > i.config.MaxSize {
+		return nil, fmt.Errorf("the image exceeds the maximum of %d bytes", i.config.MaxSize)
+	}
+
+	return data, nil
+}
+
+// thumbnailExt extension of the thumbnails of the images with the extension, the GIF thumbnails are PNG images
+func thumbnailExt(ext string) string {
+	if ext == "gif" {
+		return "png"
+	}
+
+	return ext
+}
+
+// encodeImage encodes the thumbnail using the format of the original, JPEG images are kept as JPEG and the rest as PNG
+func encodeImage(w io.Writer, img image.Image, format string) error {
+	if format == "jpeg" {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	}
+
+	return png.Encode(w, img)
+}
+
+// resize scales down the image to the width keeping its aspect ratio, each pixel is the average of the pixels
+// it covers (box filter). If the image is not wider than the width it is returned as is
+func resize(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() <= width {
+		return img
+	}
+
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := range height {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+
+		for x := range width {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, n uint64
+
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(img.At(sx, sy)).(color.NRGBA64)
+					r, g, b, a, n = r+uint64(c.R), g+uint64(c.G), b+uint64(c.B), a+uint64(c.A), n+1
+				}
+			}
+
+			dst.SetNRGBA(x, y, color.NRGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(b / n >> 8), A: uint8(a / n >> 8)})
+		}
+	}
+
+	return dst
+}
+
+// ObtainMedia returns the repository.Blob identified by the key, if the media is not enabled returns error2.NotFound
+func (i *ImageIngester) ObtainMedia(ctx context.Context, key string) (repository.Blob, error) {
+	if i == nil {
+		return repository.Blob{}, error2.NotFound(fmt.Sprintf(`media '%s' does not exist`, key))
+	}
+
+	blob, err := i.blobs.Obtain(ctx, key)
+	if err != nil && errors.As(err, new(error2.Validation)) {
+		return repository.Blob{}, error2.NotFound(fmt.Sprintf(`media '%s' does not exist`, key))
+	}
+
+	return blob, err
+}
@@ -0,0 +1,201 @@
+package business
+
+import (
+	"bytes"
+	"context"
+	"github.com/yael-castro/products-api/internal/model"
+	"github.com/yael-castro/products-api/internal/repository"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testImage returns an image of the size filled with a single color
+func testImage(width, height int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := range height {
+		for x := range width {
+			img.SetNRGBA(x, y, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+
+	return img
+}
+
+func TestImageIngester_Ingest(t *testing.T) {
+	pngImage, jpegImage := bytes.Buffer{}, bytes.Buffer{}
+
+	if err := png.Encode(&pngImage, testImage(640, 320)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := jpeg.Encode(&jpegImage, testImage(100, 100), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(pngImage.Bytes())
+		case "/b.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			_, _ = w.Write(jpegImage.Bytes())
+		case "/corrupt.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("not an image"))
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tdt := []struct {
+		images         []string
+		maxPixels      int64
+		expectedImages []string
+		expectedKeys   int
+	}{
+		{
+			images:         []string{"/a.png", "/b.jpg", "/a.png"},
+			expectedImages: []string{"/media/", "/media/", "/media/"},
+			expectedKeys:   6,
+		},
+		// The images that could not be ingested keep their URLs
+		{
+			images:         []string{"/corrupt.png", "/page.html", "/missing.png", "/b.jpg"},
+			expectedImages: []string{"/corrupt.png", "/page.html", "/missing.png", "/media/"},
+			expectedKeys:   3,
+		},
+		// The images with more pixels than the maximum are not decoded
+		{
+			images:         []string{"/a.png", "/b.jpg"},
+			maxPixels:      100 * 100,
+			expectedImages: []string{"/a.png", "/media/"},
+			expectedKeys:   3,
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			product := model.Product{SKU: "FAL-1000001"}
+			for j, path := range v.images {
+				u := mustURL(t, server.URL+path)
+
+				if j == 0 {
+					product.PrincipalImage = &u
+					continue
+				}
+
+				product.OtherImages = append(product.OtherImages, u)
+			}
+
+			storage := &repository.MockStorage[model.SKU, model.Product]{product.SKU: product}
+			blobs := &repository.MockBlobs{}
+
+			config := MediaConfig{BaseURL: "https://api.example.com/", Workers: 1, MaxPixels: v.maxPixels}
+
+			ingester, err := NewImageIngester(config, storage, &repository.MockImages{Storage: storage}, blobs, server.Client(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ingester.Enqueue(context.Background(), product.SKU)
+
+			if err = ingester.Close(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			product, _ = storage.Obtain(context.Background(), product.SKU)
+
+			images := append(model.URLs{*product.PrincipalImage}, product.OtherImages...)
+			for j, expected := range v.expectedImages {
+				expected = strings.Replace(expected, "/media/", "https://api.example.com/media/", 1)
+				if !strings.HasPrefix(expected, "https://") {
+					expected = server.URL + expected
+				}
+
+				if !strings.HasPrefix(images[j].String(), expected) {
+					t.Fatalf("expected image %d '%s' unexpected image '%s'", j, expected, images[j])
+				}
+
+				if ingester.owns(images[j]) != strings.HasPrefix(expected, "https://api.example.com/media/") {
+					t.Fatalf("unexpected ownership of image %d '%s'", j, images[j])
+				}
+			}
+
+			keys := blobs.Keys()
+			if len(keys) != v.expectedKeys {
+				t.Fatalf("expected %d keys unexpected keys '%v'", v.expectedKeys, keys)
+			}
+
+			for _, image := range images {
+				if !ingester.owns(image) {
+					continue
+				}
+
+				key := strings.TrimPrefix(image.Path, MediaPath)
+				if !slices.Contains(keys, key) {
+					t.Fatalf("missing original '%s' into keys '%v'", key, keys)
+				}
+			}
+
+			t.Log(keys)
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	tdt := []struct {
+		width          int
+		height         int
+		resizeWidth    int
+		expectedBounds image.Rectangle
+	}{
+		{
+			width:          640,
+			height:         320,
+			resizeWidth:    160,
+			expectedBounds: image.Rect(0, 0, 160, 80),
+		},
+		{
+			width:          333,
+			height:         1000,
+			resizeWidth:    100,
+			expectedBounds: image.Rect(0, 0, 100, 300),
+		},
+		// The images are never enlarged
+		{
+			width:          100,
+			height:         50,
+			resizeWidth:    480,
+			expectedBounds: image.Rect(0, 0, 100, 50),
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			thumbnail := resize(testImage(v.width, v.height), v.resizeWidth)
+
+			if !reflect.DeepEqual(v.expectedBounds, thumbnail.Bounds()) {
+				t.Fatalf("expected bounds '%v' unexpected bounds '%v'", v.expectedBounds, thumbnail.Bounds())
+			}
+
+			expectedColor := color.NRGBA{R: 200, G: 100, B: 50, A: 255}
+			if c := color.NRGBAModel.Convert(thumbnail.At(0, 0)); c != expectedColor {
+				t.Fatalf("expected color '%v' unexpected color '%v'", expectedColor, c)
+			}
+		})
+	}
+}
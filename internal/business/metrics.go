@@ -0,0 +1,225 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+)
+
+// Outcomes of the ProductManager operations used as label values
+const (
+	OutcomeSuccess      = "success"
+	OutcomeInvalid      = "invalid"
+	OutcomeNotFound     = "not_found"
+	OutcomeConflict     = "conflict"
+	OutcomeUnauthorized = "unauthorized"
+	OutcomeForbidden    = "forbidden"
+	OutcomePending      = "pending"
+	OutcomeError        = "error"
+)
+
+// uniqueViolation PostgreSQL error code for unique constraint violations
+const uniqueViolation = "23505"
+
+// Metrics business metrics: outcomes of the ProductManager operations and validation failures by rule
+//
+// A nil *Metrics does not record anything
+type Metrics struct {
+	operations         *prometheus.CounterVec
+	validationFailures *prometheus.CounterVec
+}
+
+// NewMetrics builds the *Metrics and registers its collectors
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "product_operations_total",
+			Help: "Number of product operations by operation and outcome.",
+		}, []string{"operation", "outcome"}),
+		validationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "product_validation_failures_total",
+			Help: "Number of product validation failures by broken rule.",
+		}, []string{"rule"}),
+	}
+
+	for _, collector := range []prometheus.Collector{m.operations, m.validationFailures} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// operationCompleted records the outcome of the operation based on the error returned by it
+func (m *Metrics) operationCompleted(operation Operation, err error) {
+	if m == nil {
+		return
+	}
+
+	m.operations.WithLabelValues(string(operation), outcome(err)).Inc()
+}
+
+// operationHeld records the operation whose changes are held until they are approved
+func (m *Metrics) operationHeld(operation Operation) {
+	if m == nil {
+		return
+	}
+
+	m.operations.WithLabelValues(string(operation), OutcomePending).Inc()
+}
+
+// validationFailed records the broken validation rule
+func (m *Metrics) validationFailed(rule string) {
+	if m == nil {
+		return
+	}
+
+	m.validationFailures.WithLabelValues(rule).Inc()
+}
+
+// outcome classifies the error returned by an operation
+func outcome(err error) string {
+	switch err.(type) {
+	case nil:
+		return OutcomeSuccess
+	case error2.Validation, error2.Violations:
+		return OutcomeInvalid
+	case error2.NotFound:
+		return OutcomeNotFound
+	case error2.Conflict, error2.Duplicates:
+		return OutcomeConflict
+	case error2.Unauthorized:
+		return OutcomeUnauthorized
+	case error2.Forbidden:
+		return OutcomeForbidden
+	}
+
+	pgErr := &error2.PG{}
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return OutcomeConflict
+	}
+
+	return OutcomeError
+}
+
+// _ "implements" constraint for ProductMetrics
+var _ ProductManager = ProductMetrics{}
+
+// ProductMetrics decorates a ProductManager to record the outcome of each operation
+type ProductMetrics struct {
+	ProductManager
+	*Metrics
+}
+
+// CreateProduct records the outcome of ProductManager.CreateProduct
+func (p ProductMetrics) CreateProduct(ctx context.Context, product *model.Product) error {
+	err := p.ProductManager.CreateProduct(ctx, product)
+	p.operationCompleted(OperationCreate, err)
+	return err
+}
+
+// ObtainProduct records the outcome of ProductManager.ObtainProduct
+func (p ProductMetrics) ObtainProduct(ctx context.Context, sku model.SKU) (model.Product, error) {
+	product, err := p.ProductManager.ObtainProduct(ctx, sku)
+	p.operationCompleted(OperationObtain, err)
+	return product, err
+}
+
+// ObtainProductBatch records the outcome of ProductManager.ObtainProductBatch
+func (p ProductMetrics) ObtainProductBatch(ctx context.Context, skus []model.SKU) (model.ProductBatch, error) {
+	batch, err := p.ProductManager.ObtainProductBatch(ctx, skus)
+	p.operationCompleted(OperationObtainBatch, err)
+	return batch, err
+}
+
+// UpdateProduct records the outcome of ProductManager.UpdateProduct, the updates whose price change is held are pending
+func (p ProductMetrics) UpdateProduct(ctx context.Context, product model.Product) (*model.PriceChange, error) {
+	change, err := p.ProductManager.UpdateProduct(ctx, product)
+	if err == nil && change != nil {
+		p.operationHeld(OperationUpdate)
+		return change, nil
+	}
+
+	p.operationCompleted(OperationUpdate, err)
+	return change, err
+}
+
+// DeleteProduct records the outcome of ProductManager.DeleteProduct
+func (p ProductMetrics) DeleteProduct(ctx context.Context, sku model.SKU) error {
+	err := p.ProductManager.DeleteProduct(ctx, sku)
+	p.operationCompleted(OperationDelete, err)
+	return err
+}
+
+// ListProducts records the outcome of ProductManager.ListProducts
+func (p ProductMetrics) ListProducts(ctx context.Context, filter model.ProductFilter) (model.ProductPage, error) {
+	page, err := p.ProductManager.ListProducts(ctx, filter)
+	p.operationCompleted(OperationList, err)
+	return page, err
+}
+
+// ReserveSKUs records the outcome of ProductManager.ReserveSKUs
+func (p ProductMetrics) ReserveSKUs(ctx context.Context, reservation model.SKUReservation) (model.SKUBlock, error) {
+	block, err := p.ProductManager.ReserveSKUs(ctx, reservation)
+	p.operationCompleted(OperationReserveSKUs, err)
+	return block, err
+}
+
+// UploadImage records the outcome of ProductManager.UploadImage
+func (p ProductMetrics) UploadImage(ctx context.Context, sku model.SKU, upload model.ImageUpload) (model.Product, error) {
+	product, err := p.ProductManager.UploadImage(ctx, sku, upload)
+	p.operationCompleted(OperationUploadImage, err)
+	return product, err
+}
+
+// TransitionProduct records the outcome of ProductManager.TransitionProduct
+func (p ProductMetrics) TransitionProduct(ctx context.Context, sku model.SKU, status model.Status) (model.Product, error) {
+	product, err := p.ProductManager.TransitionProduct(ctx, sku, status)
+	p.operationCompleted(OperationTransition, err)
+	return product, err
+}
+
+// ObtainTransitions records the outcome of ProductManager.ObtainTransitions
+func (p ProductMetrics) ObtainTransitions(ctx context.Context, sku model.SKU) ([]model.StatusTransition, error) {
+	transitions, err := p.ProductManager.ObtainTransitions(ctx, sku)
+	p.operationCompleted(OperationObtainTransitions, err)
+	return transitions, err
+}
+
+// ListPriceChanges records the outcome of ProductManager.ListPriceChanges
+func (p ProductMetrics) ListPriceChanges(ctx context.Context, filter model.PriceChangeFilter) ([]model.PriceChange, error) {
+	changes, err := p.ProductManager.ListPriceChanges(ctx, filter)
+	p.operationCompleted(OperationListPriceChanges, err)
+	return changes, err
+}
+
+// ObtainPriceChange records the outcome of ProductManager.ObtainPriceChange
+func (p ProductMetrics) ObtainPriceChange(ctx context.Context, id uint) (model.PriceChange, error) {
+	change, err := p.ProductManager.ObtainPriceChange(ctx, id)
+	p.operationCompleted(OperationObtainPriceChange, err)
+	return change, err
+}
+
+// ReviewPriceChange records the outcome of ProductManager.ReviewPriceChange
+func (p ProductMetrics) ReviewPriceChange(ctx context.Context, review model.PriceReview) (model.PriceChange, error) {
+	change, err := p.ProductManager.ReviewPriceChange(ctx, review)
+	p.operationCompleted(OperationReviewPriceChange, err)
+	return change, err
+}
+
+// StartDuplicateReport records the outcome of ProductManager.StartDuplicateReport
+func (p ProductMetrics) StartDuplicateReport(ctx context.Context) (model.DuplicateReport, error) {
+	report, err := p.ProductManager.StartDuplicateReport(ctx)
+	p.operationCompleted(OperationStartDuplicateReport, err)
+	return report, err
+}
+
+// ObtainDuplicateReport records the outcome of ProductManager.ObtainDuplicateReport
+func (p ProductMetrics) ObtainDuplicateReport(ctx context.Context) (model.DuplicateReport, error) {
+	report, err := p.ProductManager.ObtainDuplicateReport(ctx)
+	p.operationCompleted(OperationObtainDuplicateReport, err)
+	return report, err
+}
@@ -0,0 +1,86 @@
+package business
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/yael-castro/products-api/internal/model"
+	"github.com/yael-castro/products-api/internal/repository"
+	"strconv"
+	"testing"
+)
+
+func TestProductMetrics(t *testing.T) {
+	tdt := []struct {
+		operation         func(ProductManager) error
+		expectedOperation Operation
+		expectedOutcome   string
+		expectedRule      string
+	}{
+		{
+			operation: func(p ProductManager) error {
+				_, err := p.ObtainProduct(context.Background(), "FAL-12345678")
+				return err
+			},
+			expectedOperation: OperationObtain,
+			expectedOutcome:   OutcomeSuccess,
+		},
+		{
+			operation: func(p ProductManager) error {
+				_, err := p.ObtainProduct(context.Background(), "FAL-87654321")
+				return err
+			},
+			expectedOperation: OperationObtain,
+			expectedOutcome:   OutcomeNotFound,
+		},
+		{
+			operation: func(p ProductManager) error {
+				return p.CreateProduct(context.Background(), &model.Product{SKU: "FAL-12345678", Name: "A"})
+			},
+			expectedOperation: OperationCreate,
+			expectedOutcome:   OutcomeInvalid,
+			expectedRule:      "name_min_length",
+		},
+		{
+			operation: func(p ProductManager) error {
+				return p.DeleteProduct(context.Background(), "invalid")
+			},
+			expectedOperation: OperationDelete,
+			expectedOutcome:   OutcomeInvalid,
+			expectedRule:      "sku_format",
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			metrics, err := NewMetrics(prometheus.NewRegistry())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			store := ProductMetrics{
+				ProductManager: ProductStore{
+					StorageManager: &repository.MockStorage[model.SKU, model.Product]{"FAL-12345678": {}},
+					Metrics:        metrics,
+				},
+				Metrics: metrics,
+			}
+
+			_ = v.operation(store)
+
+			count := testutil.ToFloat64(metrics.operations.WithLabelValues(string(v.expectedOperation), v.expectedOutcome))
+			if count != 1 {
+				t.Fatalf(`expected 1 operation '%s' with outcome '%s' unexpected count '%v'`, v.expectedOperation, v.expectedOutcome, count)
+			}
+
+			if v.expectedRule == "" {
+				t.Skip()
+			}
+
+			count = testutil.ToFloat64(metrics.validationFailures.WithLabelValues(v.expectedRule))
+			if count != 1 {
+				t.Fatalf(`expected 1 validation failure for rule '%s' unexpected count '%v'`, v.expectedRule, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package business
+
+import (
+	"context"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Operation identifies each operation of ProductManager
+type Operation string
+
+// Supported operations for Policies
+const (
+	// OperationCreate identifies ProductManager.CreateProduct
+	OperationCreate Operation = "create"
+	// OperationObtain identifies ProductManager.ObtainProduct
+	OperationObtain Operation = "obtain"
+	// OperationObtainBatch identifies ProductManager.ObtainProductBatch, if it is not listed the roles of OperationObtain are used
+	OperationObtainBatch Operation = "obtain_batch"
+	// OperationUpdate identifies ProductManager.UpdateProduct
+	OperationUpdate Operation = "update"
+	// OperationDelete identifies ProductManager.DeleteProduct
+	OperationDelete Operation = "delete"
+	// OperationList identifies ProductManager.ListProducts
+	OperationList Operation = "list"
+	// OperationReserveSKUs identifies ProductManager.ReserveSKUs, if it is not listed the roles of OperationCreate are used
+	OperationReserveSKUs Operation = "reserve_skus"
+	// OperationUploadImage identifies ProductManager.UploadImage, if it is not listed the roles of OperationUpdate are used
+	OperationUploadImage Operation = "upload_image"
+	// OperationListUnpublished identifies ProductManager.ListProducts when the listed statuses are not only the published status,
+	// if it is not listed the roles of OperationUpdate are used
+	OperationListUnpublished Operation = "list_unpublished"
+	// OperationTransition identifies ProductManager.TransitionProduct, if it is not listed the roles of OperationUpdate are used
+	OperationTransition Operation = "transition"
+	// OperationObtainTransitions identifies ProductManager.ObtainTransitions, if it is not listed the roles of OperationUpdate are used
+	OperationObtainTransitions Operation = "obtain_transitions"
+	// OperationListPriceChanges identifies ProductManager.ListPriceChanges, if it is not listed the roles of OperationUpdate are used
+	OperationListPriceChanges Operation = "list_price_changes"
+	// OperationObtainPriceChange identifies ProductManager.ObtainPriceChange, if it is not listed the roles of OperationUpdate are used
+	OperationObtainPriceChange Operation = "obtain_price_change"
+	// OperationReviewPriceChange identifies ProductManager.ReviewPriceChange, if it is not listed the roles of OperationUpdate are used
+	OperationReviewPriceChange Operation = "review_price_change"
+	// OperationStartDuplicateReport identifies ProductManager.StartDuplicateReport, if it is not listed the roles of OperationUpdate are used
+	OperationStartDuplicateReport Operation = "start_duplicate_report"
+	// OperationObtainDuplicateReport identifies ProductManager.ObtainDuplicateReport, if it is not listed the roles of OperationUpdate are used
+	OperationObtainDuplicateReport Operation = "obtain_duplicate_report"
+)
+
+// productFields functions to compare each field of model.Product indexed by its JSON name, they are used by field level rules
+var productFields = map[string]func(a, b model.Product) bool{
+	"name":  func(a, b model.Product) bool { return a.Name == b.Name },
+	"brand": func(a, b model.Product) bool { return a.Brand == b.Brand },
+	"size": func(a, b model.Product) bool {
+		return (a.Size == nil && b.Size == nil) || (a.Size != nil && b.Size != nil && *a.Size == *b.Size)
+	},
+	"price": func(a, b model.Product) bool { return a.Price == b.Price },
+	"principalImage": func(a, b model.Product) bool {
+		return (a.PrincipalImage == nil && b.PrincipalImage == nil) ||
+			(a.PrincipalImage != nil && b.PrincipalImage != nil && a.PrincipalImage.String() == b.PrincipalImage.String())
+	},
+	"otherImages": func(a, b model.Product) bool { return a.OtherImages.String() == b.OtherImages.String() },
+}
+
+// Policies role-based authorization rules for ProductManager
+type Policies struct {
+	// Operations roles allowed to perform each Operation, the operations that are not listed are allowed for any client
+	Operations map[Operation][]string `yaml:"operations"`
+	// Fields roles allowed to change each field of an existing model.Product (indexed by its JSON name),
+	// the fields that are not listed could be changed by any client allowed to perform OperationUpdate
+	Fields map[string][]string `yaml:"fields"`
+	// Statuses roles allowed to move a model.Product to each model.Status, the statuses that are not listed
+	// could be reached by any client allowed to perform OperationTransition
+	Statuses map[model.Status][]string `yaml:"statuses"`
+}
+
+// Validate checks that Policies only contains supported operations, fields and statuses
+func (p Policies) Validate() error {
+	for operation := range p.Operations {
+		switch operation {
+		case OperationCreate, OperationObtain, OperationObtainBatch, OperationUpdate, OperationDelete, OperationList,
+			OperationReserveSKUs, OperationUploadImage, OperationListUnpublished, OperationTransition, OperationObtainTransitions,
+			OperationListPriceChanges, OperationObtainPriceChange, OperationReviewPriceChange, OperationStartDuplicateReport,
+			OperationObtainDuplicateReport:
+		default:
+			return fmt.Errorf(`operation '%s' is not supported`, operation)
+		}
+	}
+
+	for field := range p.Fields {
+		if _, ok := productFields[field]; !ok {
+			return fmt.Errorf(`field '%s' is not supported`, field)
+		}
+	}
+
+	for status := range p.Statuses {
+		if !status.IsValid() {
+			return fmt.Errorf(`status '%s' is not supported`, status)
+		}
+	}
+
+	return nil
+}
+
+// authorize checks if the caller carried by ctx has any of the roles, if roles is nil any client is authorized
+func authorize(ctx context.Context, roles []string, action string) error {
+	if roles == nil {
+		return nil
+	}
+
+	caller, ok := CallerFrom(ctx)
+	if !ok {
+		return error2.Unauthorized(fmt.Sprintf("credentials are required to %s", action))
+	}
+
+	for _, role := range roles {
+		if caller.HasRole(role) {
+			return nil
+		}
+	}
+
+	return error2.Forbidden(fmt.Sprintf("one of the roles [%s] is required to %s", strings.Join(roles, ", "), action))
+}
+
+// _ "implements" constraint for ProductPolicy
+var _ ProductManager = ProductPolicy{}
+
+// ProductPolicy decorates a ProductManager to check the roles of the caller carried by context.Context before each operation
+type ProductPolicy struct {
+	ProductManager
+	Policies
+}
+
+// CreateProduct checks if the caller is allowed to create products
+func (p ProductPolicy) CreateProduct(ctx context.Context, product *model.Product) error {
+	if err := authorize(ctx, p.Operations[OperationCreate], "create products"); err != nil {
+		return err
+	}
+
+	return p.ProductManager.CreateProduct(ctx, product)
+}
+
+// ObtainProduct checks if the caller is allowed to obtain products
+func (p ProductPolicy) ObtainProduct(ctx context.Context, sku model.SKU) (model.Product, error) {
+	if err := authorize(ctx, p.Operations[OperationObtain], "obtain products"); err != nil {
+		return model.Product{}, err
+	}
+
+	return p.ProductManager.ObtainProduct(ctx, sku)
+}
+
+// ObtainProductBatch checks if the caller is allowed to obtain batches of products
+func (p ProductPolicy) ObtainProductBatch(ctx context.Context, skus []model.SKU) (model.ProductBatch, error) {
+	if err := authorize(ctx, p.roles(OperationObtainBatch, OperationObtain), "obtain products"); err != nil {
+		return model.ProductBatch{}, err
+	}
+
+	return p.ProductManager.ObtainProductBatch(ctx, skus)
+}
+
+// UpdateProduct checks if the caller is allowed to update products and to change every modified field
+func (p ProductPolicy) UpdateProduct(ctx context.Context, product model.Product) (*model.PriceChange, error) {
+	if err := authorize(ctx, p.Operations[OperationUpdate], "update products"); err != nil {
+		return nil, err
+	}
+
+	if len(p.Fields) > 0 {
+		current, err := p.ProductManager.ObtainProduct(ctx, product.SKU)
+		if err != nil {
+			return nil, err
+		}
+
+		fields := make([]string, 0, len(p.Fields))
+		for field := range p.Fields {
+			fields = append(fields, field)
+		}
+
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			if productFields[field](current, product) {
+				continue
+			}
+
+			if err := authorize(ctx, p.Fields[field], fmt.Sprintf("change the product %s", field)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return p.ProductManager.UpdateProduct(ctx, product)
+}
+
+// DeleteProduct checks if the caller is allowed to delete products
+func (p ProductPolicy) DeleteProduct(ctx context.Context, sku model.SKU) error {
+	if err := authorize(ctx, p.Operations[OperationDelete], "delete products"); err != nil {
+		return err
+	}
+
+	return p.ProductManager.DeleteProduct(ctx, sku)
+}
+
+// ListProducts checks if the caller is allowed to list products and, if the filter contains statuses other than the published status,
+// to list unpublished products
+func (p ProductPolicy) ListProducts(ctx context.Context, filter model.ProductFilter) (model.ProductPage, error) {
+	if err := authorize(ctx, p.Operations[OperationList], "list products"); err != nil {
+		return model.ProductPage{}, err
+	}
+
+	for _, status := range filter.Statuses {
+		if slices.Contains(publicStatuses, status) {
+			continue
+		}
+
+		if err := authorize(ctx, p.roles(OperationListUnpublished, OperationUpdate), "list unpublished products"); err != nil {
+			return model.ProductPage{}, err
+		}
+
+		break
+	}
+
+	return p.ProductManager.ListProducts(ctx, filter)
+}
+
+// ReserveSKUs checks if the caller is allowed to reserve SKUs
+func (p ProductPolicy) ReserveSKUs(ctx context.Context, reservation model.SKUReservation) (model.SKUBlock, error) {
+	if err := authorize(ctx, p.roles(OperationReserveSKUs, OperationCreate), "reserve skus"); err != nil {
+		return model.SKUBlock{}, err
+	}
+
+	return p.ProductManager.ReserveSKUs(ctx, reservation)
+}
+
+// UploadImage checks if the caller is allowed to upload images and to change the image field modified by the upload
+func (p ProductPolicy) UploadImage(ctx context.Context, sku model.SKU, upload model.ImageUpload) (model.Product, error) {
+	if err := authorize(ctx, p.roles(OperationUploadImage, OperationUpdate), "upload product images"); err != nil {
+		return model.Product{}, err
+	}
+
+	field := "otherImages"
+	if upload.Principal {
+		field = "principalImage"
+	}
+
+	if err := authorize(ctx, p.Fields[field], fmt.Sprintf("change the product %s", field)); err != nil {
+		return model.Product{}, err
+	}
+
+	return p.ProductManager.UploadImage(ctx, sku, upload)
+}
+
+// TransitionProduct checks if the caller is allowed to change the status of products and to move them to the status
+func (p ProductPolicy) TransitionProduct(ctx context.Context, sku model.SKU, status model.Status) (model.Product, error) {
+	if err := authorize(ctx, p.roles(OperationTransition, OperationUpdate), "change the product status"); err != nil {
+		return model.Product{}, err
+	}
+
+	if err := authorize(ctx, p.Statuses[status], fmt.Sprintf("move products to status %s", status)); err != nil {
+		return model.Product{}, err
+	}
+
+	return p.ProductManager.TransitionProduct(ctx, sku, status)
+}
+
+// ObtainTransitions checks if the caller is allowed to obtain the status history of products
+func (p ProductPolicy) ObtainTransitions(ctx context.Context, sku model.SKU) ([]model.StatusTransition, error) {
+	if err := authorize(ctx, p.roles(OperationObtainTransitions, OperationUpdate), "obtain the product status history"); err != nil {
+		return nil, err
+	}
+
+	return p.ProductManager.ObtainTransitions(ctx, sku)
+}
+
+// ListPriceChanges checks if the caller is allowed to list the price changes
+func (p ProductPolicy) ListPriceChanges(ctx context.Context, filter model.PriceChangeFilter) ([]model.PriceChange, error) {
+	if err := authorize(ctx, p.roles(OperationListPriceChanges, OperationUpdate), "list price changes"); err != nil {
+		return nil, err
+	}
+
+	return p.ProductManager.ListPriceChanges(ctx, filter)
+}
+
+// ObtainPriceChange checks if the caller is allowed to obtain a price change
+func (p ProductPolicy) ObtainPriceChange(ctx context.Context, id uint) (model.PriceChange, error) {
+	if err := authorize(ctx, p.roles(OperationObtainPriceChange, OperationUpdate), "obtain price changes"); err != nil {
+		return model.PriceChange{}, err
+	}
+
+	return p.ProductManager.ObtainPriceChange(ctx, id)
+}
+
+// ReviewPriceChange checks if the caller is allowed to review price changes
+func (p ProductPolicy) ReviewPriceChange(ctx context.Context, review model.PriceReview) (model.PriceChange, error) {
+	if err := authorize(ctx, p.roles(OperationReviewPriceChange, OperationUpdate), "review price changes"); err != nil {
+		return model.PriceChange{}, err
+	}
+
+	return p.ProductManager.ReviewPriceChange(ctx, review)
+}
+
+// StartDuplicateReport checks if the caller is allowed to start the duplicate report
+func (p ProductPolicy) StartDuplicateReport(ctx context.Context) (model.DuplicateReport, error) {
+	if err := authorize(ctx, p.roles(OperationStartDuplicateReport, OperationUpdate), "start the duplicate report"); err != nil {
+		return model.DuplicateReport{}, err
+	}
+
+	return p.ProductManager.StartDuplicateReport(ctx)
+}
+
+// ObtainDuplicateReport checks if the caller is allowed to obtain the duplicate report
+func (p ProductPolicy) ObtainDuplicateReport(ctx context.Context) (model.DuplicateReport, error) {
+	if err := authorize(ctx, p.roles(OperationObtainDuplicateReport, OperationUpdate), "obtain the duplicate report"); err != nil {
+		return model.DuplicateReport{}, err
+	}
+
+	return p.ProductManager.ObtainDuplicateReport(ctx)
+}
+
+// roles returns the roles allowed to perform the operation, if it is not listed the roles of the fallback operation are used
+func (p ProductPolicy) roles(operation, fallback Operation) []string {
+	roles, ok := p.Operations[operation]
+	if !ok {
+		return p.Operations[fallback]
+	}
+
+	return roles
+}
@@ -0,0 +1,378 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"github.com/yael-castro/products-api/internal/repository"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestProductPolicy_UpdateProduct(t *testing.T) {
+	product := model.Product{
+		SKU:            "FAL-1234567",
+		Price:          10,
+		Brand:          "Nike",
+		Name:           "Shoes",
+		PrincipalImage: &model.URL{URL: &url.URL{Scheme: "https", Host: "example.com"}},
+	}
+
+	changedPrice := product
+	changedPrice.Price = 1050
+
+	changedName := product
+	changedName.Name = "Running shoes"
+
+	tdt := []struct {
+		caller      *model.Caller
+		product     model.Product
+		expectedErr error
+	}{
+		{
+			product:     changedName,
+			expectedErr: error2.Unauthorized("credentials are required to update products"),
+		},
+		{
+			caller:      &model.Caller{Subject: "viewer"},
+			product:     changedName,
+			expectedErr: error2.Forbidden("one of the roles [catalog_editor, pricing_manager] is required to update products"),
+		},
+		{
+			caller:  &model.Caller{Subject: "editor", Roles: []string{"catalog_editor"}},
+			product: changedName,
+		},
+		{
+			caller:      &model.Caller{Subject: "editor", Roles: []string{"catalog_editor"}},
+			product:     changedPrice,
+			expectedErr: error2.Forbidden("one of the roles [pricing_manager] is required to change the product price"),
+		},
+		{
+			caller:  &model.Caller{Subject: "pricing", Roles: []string{"pricing_manager"}},
+			product: changedPrice,
+		},
+	}
+
+	policies := Policies{
+		Operations: map[Operation][]string{
+			OperationUpdate: {"catalog_editor", "pricing_manager"},
+		},
+		Fields: map[string][]string{
+			"price": {"pricing_manager"},
+		},
+	}
+
+	if err := policies.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			store := ProductPolicy{
+				ProductManager: ProductStore{
+					StorageManager: &repository.MockStorage[model.SKU, model.Product]{product.SKU: product},
+				},
+				Policies: policies,
+			}
+
+			ctx := context.Background()
+			if v.caller != nil {
+				ctx = WithCaller(ctx, *v.caller)
+			}
+
+			_, err := store.UpdateProduct(ctx, v.product)
+			if !errors.Is(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			if err != nil {
+				t.Skip(err)
+			}
+
+			t.Log(v.product)
+		})
+	}
+}
+
+func TestProductPolicy_DeleteProduct(t *testing.T) {
+	tdt := []struct {
+		caller      model.Caller
+		expectedErr error
+	}{
+		{
+			caller:      model.Caller{Subject: "editor", Roles: []string{"catalog_editor"}},
+			expectedErr: error2.Forbidden("one of the roles [admin] is required to delete products"),
+		},
+		{
+			caller: model.Caller{Subject: "admin", Roles: []string{"admin"}},
+		},
+	}
+
+	store := ProductPolicy{
+		ProductManager: ProductStore{
+			StorageManager: &repository.MockStorage[model.SKU, model.Product]{},
+		},
+		Policies: Policies{
+			Operations: map[Operation][]string{
+				OperationDelete: {"admin"},
+			},
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := store.DeleteProduct(WithCaller(context.Background(), v.caller), "FAL-1234567")
+			if !errors.Is(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			if err != nil {
+				t.Skip(err)
+			}
+
+			t.Log("SUCCESS")
+		})
+	}
+}
+
+func TestProductPolicy_ObtainProductBatch(t *testing.T) {
+	tdt := []struct {
+		caller      model.Caller
+		operations  map[Operation][]string
+		expectedErr error
+	}{
+		// The roles of OperationObtain are used if OperationObtainBatch is not listed
+		{
+			caller:      model.Caller{Subject: "viewer", Roles: []string{"viewer"}},
+			operations:  map[Operation][]string{OperationObtain: {"catalog_reader"}},
+			expectedErr: error2.Forbidden("one of the roles [catalog_reader] is required to obtain products"),
+		},
+		{
+			caller:     model.Caller{Subject: "reader", Roles: []string{"catalog_reader"}},
+			operations: map[Operation][]string{OperationObtain: {"catalog_reader"}},
+		},
+		{
+			caller:      model.Caller{Subject: "reader", Roles: []string{"catalog_reader"}},
+			operations:  map[Operation][]string{OperationObtain: {"catalog_reader"}, OperationObtainBatch: {"cart_service"}},
+			expectedErr: error2.Forbidden("one of the roles [cart_service] is required to obtain products"),
+		},
+		{
+			caller: model.Caller{Subject: "viewer", Roles: []string{"viewer"}},
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			store := ProductPolicy{
+				ProductManager: ProductStore{
+					StorageManager: &repository.MockStorage[model.SKU, model.Product]{},
+				},
+				Policies: Policies{Operations: v.operations},
+			}
+
+			_, err := store.ObtainProductBatch(WithCaller(context.Background(), v.caller), []model.SKU{"FAL-1234567"})
+			if !errors.Is(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			if err != nil {
+				t.Skip(err)
+			}
+
+			t.Log("SUCCESS")
+		})
+	}
+}
+
+func TestProductPolicy_UploadImage(t *testing.T) {
+	// The image uploads are not enabled, so the authorized callers obtain error2.NotImplemented
+	notImplemented := error2.NotImplemented("image uploads are not enabled")
+
+	tdt := []struct {
+		caller      model.Caller
+		principal   bool
+		policies    Policies
+		expectedErr error
+	}{
+		// The roles of OperationUpdate are used if OperationUploadImage is not listed
+		{
+			caller:      model.Caller{Subject: "viewer", Roles: []string{"viewer"}},
+			policies:    Policies{Operations: map[Operation][]string{OperationUpdate: {"catalog_editor"}}},
+			expectedErr: error2.Forbidden("one of the roles [catalog_editor] is required to upload product images"),
+		},
+		{
+			caller:      model.Caller{Subject: "editor", Roles: []string{"catalog_editor"}},
+			policies:    Policies{Operations: map[Operation][]string{OperationUpdate: {"catalog_editor"}}},
+			expectedErr: notImplemented,
+		},
+		{
+			caller:      model.Caller{Subject: "editor", Roles: []string{"catalog_editor"}},
+			policies:    Policies{Operations: map[Operation][]string{OperationUpdate: {"catalog_editor"}, OperationUploadImage: {"photographer"}}},
+			expectedErr: error2.Forbidden("one of the roles [photographer] is required to upload product images"),
+		},
+		// The field policies of the changed image field are checked
+		{
+			caller:      model.Caller{Subject: "photographer", Roles: []string{"photographer"}},
+			principal:   true,
+			policies:    Policies{Fields: map[string][]string{"principalImage": {"admin"}}},
+			expectedErr: error2.Forbidden("one of the roles [admin] is required to change the product principalImage"),
+		},
+		{
+			caller:      model.Caller{Subject: "photographer", Roles: []string{"photographer"}},
+			policies:    Policies{Fields: map[string][]string{"principalImage": {"admin"}}},
+			expectedErr: notImplemented,
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			store := ProductPolicy{
+				ProductManager: ProductStore{
+					StorageManager: &repository.MockStorage[model.SKU, model.Product]{},
+				},
+				Policies: v.policies,
+			}
+
+			_, err := store.UploadImage(WithCaller(context.Background(), v.caller), "FAL-1234567", model.ImageUpload{Principal: v.principal})
+			if !errors.Is(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			t.Log(err)
+		})
+	}
+}
+
+func TestProductPolicy_TransitionProduct(t *testing.T) {
+	// The product lifecycle is not enabled, so the authorized callers obtain error2.NotImplemented
+	notImplemented := error2.NotImplemented("product lifecycle is not enabled")
+
+	tdt := []struct {
+		caller      model.Caller
+		status      model.Status
+		policies    Policies
+		expectedErr error
+	}{
+		// The roles of OperationUpdate are used if OperationTransition is not listed
+		{
+			caller:      model.Caller{Subject: "viewer", Roles: []string{"viewer"}},
+			status:      model.StatusInReview,
+			policies:    Policies{Operations: map[Operation][]string{OperationUpdate: {"catalog_editor"}}},
+			expectedErr: error2.Forbidden("one of the roles [catalog_editor] is required to change the product status"),
+		},
+		{
+			caller:      model.Caller{Subject: "editor", Roles: []string{"catalog_editor"}},
+			status:      model.StatusInReview,
+			policies:    Policies{Operations: map[Operation][]string{OperationUpdate: {"catalog_editor"}}},
+			expectedErr: notImplemented,
+		},
+		// The roles of the target status are checked
+		{
+			caller:      model.Caller{Subject: "editor", Roles: []string{"catalog_editor"}},
+			status:      model.StatusPublished,
+			policies:    Policies{Statuses: map[model.Status][]string{model.StatusPublished: {"publisher"}}},
+			expectedErr: error2.Forbidden("one of the roles [publisher] is required to move products to status published"),
+		},
+		{
+			caller:      model.Caller{Subject: "publisher", Roles: []string{"publisher"}},
+			status:      model.StatusPublished,
+			policies:    Policies{Statuses: map[model.Status][]string{model.StatusPublished: {"publisher"}}},
+			expectedErr: notImplemented,
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			store := ProductPolicy{
+				ProductManager: ProductStore{
+					StorageManager: &repository.MockStorage[model.SKU, model.Product]{},
+				},
+				Policies: v.policies,
+			}
+
+			_, err := store.TransitionProduct(WithCaller(context.Background(), v.caller), "FAL-1234567", v.status)
+			if !errors.Is(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			t.Log(err)
+		})
+	}
+}
+
+func TestProductPolicy_ListProducts(t *testing.T) {
+	tdt := []struct {
+		caller      model.Caller
+		filter      model.ProductFilter
+		expectedErr error
+	}{
+		// The published products could be listed by any client allowed to list products
+		{
+			caller: model.Caller{Subject: "viewer", Roles: []string{"viewer"}},
+			filter: model.ProductFilter{Statuses: []model.Status{model.StatusPublished}},
+		},
+		{
+			caller:      model.Caller{Subject: "viewer", Roles: []string{"viewer"}},
+			filter:      model.ProductFilter{Statuses: []model.Status{model.StatusPublished, model.StatusDraft}},
+			expectedErr: error2.Forbidden("one of the roles [catalog_editor] is required to list unpublished products"),
+		},
+		{
+			caller: model.Caller{Subject: "editor", Roles: []string{"catalog_editor"}},
+			filter: model.ProductFilter{Statuses: []model.Status{model.StatusDraft}},
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			store := ProductPolicy{
+				ProductManager: ProductStore{
+					StorageManager: &repository.MockStorage[model.SKU, model.Product]{},
+				},
+				Policies: Policies{Operations: map[Operation][]string{OperationUpdate: {"catalog_editor"}}},
+			}
+
+			_, err := store.ListProducts(WithCaller(context.Background(), v.caller), v.filter)
+			if !errors.Is(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			t.Log(err)
+		})
+	}
+}
+
+func TestPolicies_Validate(t *testing.T) {
+	tdt := []struct {
+		policies    Policies
+		expectedErr bool
+	}{
+		{
+			policies: Policies{Operations: map[Operation][]string{OperationList: {"viewer"}}},
+		},
+		{
+			policies:    Policies{Operations: map[Operation][]string{"publish": {"admin"}}},
+			expectedErr: true,
+		},
+		{
+			policies:    Policies{Fields: map[string][]string{"color": {"admin"}}},
+			expectedErr: true,
+		},
+		{
+			policies:    Policies{Statuses: map[model.Status][]string{"live": {"admin"}}},
+			expectedErr: true,
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := v.policies.Validate()
+			if (err != nil) != v.expectedErr {
+				t.Fatalf("unexpected error '%v'", err)
+			}
+
+			t.Log(err)
+		})
+	}
+}
@@ -1,100 +1,301 @@
 package business
 
 import (
+	"cmp"
+	"context"
+	"fmt"
 	"github.com/yael-castro/products-api/internal/model"
 	error2 "github.com/yael-castro/products-api/internal/model/error"
 	"github.com/yael-castro/products-api/internal/repository"
+	"log/slog"
+	"net/http"
+	"slices"
 )
 
+// DefaultMaxBatchSize default maximum number of SKUs allowed by ProductManager.ObtainProductBatch
+const DefaultMaxBatchSize = 100
+
 // _ "implements" constraint for ProductStore
 var _ ProductManager = ProductStore{}
 
 // ProductStore manage the products management
 type ProductStore struct {
 	repository.StorageManager[model.SKU, model.Product]
+	// Logger is used to log the business events, if it is nil slog.Default is used
+	Logger *slog.Logger
+	// Metrics is used to record the validation failures, if it is nil nothing is recorded
+	Metrics *Metrics
+	// MaxBatchSize maximum number of SKUs allowed by ObtainProductBatch, if it is zero DefaultMaxBatchSize is used
+	MaxBatchSize int
+	// Rules is used to validate the product data, if it is nil the DefaultRuleSet is used
+	Rules *RuleEngine
+	// SKUSchemes formats of the SKUs, if it does not contain any scheme the model.DefaultSKUSchemes are used
+	SKUSchemes model.SKUSchemes
+	// Sequences is used to allocate the SKUs, if it is nil the SKUs are required to create products and could not be reserved
+	Sequences repository.SequenceManager
+	// MaxReservationSize maximum number of SKUs allowed by ReserveSKUs, if it is zero DefaultMaxReservationSize is used
+	MaxReservationSize int
+	// Images restrictions of the image URLs, the zero value only allows http and https URLs of any host
+	Images ImagePolicy
+	// ImageClient is used to verify the images if the ImagePolicy enables the verification, if it is nil ImagePolicy.Client is used
+	ImageClient *http.Client
+	// Media ingests the images of the created and updated products, if it is nil the image URLs are kept as they are received
+	Media *ImageIngester
+	// Lifecycle changes the status of the products and records their transitions, if it is nil the status could not be changed
+	Lifecycle repository.LifecycleManager
+	// Approvals holds the price changes that exceed the PriceApproval thresholds until they are approved,
+	// if it is nil the prices are changed without approval
+	Approvals repository.ApprovalManager
+	// PriceApproval thresholds of the price changes held by Approvals
+	PriceApproval PriceApproval
+	// Duplicates detects the new products that are likely duplicates of existing products, if it is nil the duplicates
+	// are not detected and the duplicate report is not enabled
+	Duplicates *DuplicateDetector
+	// Finder filters and paginates the listed products in the storage, if it is nil every product is read
+	// from the StorageManager and the products are filtered and paginated in memory
+	Finder repository.ProductFinder
 }
 
-// validateProductData validates if the model.Product received is valid, if the model.Product is not valid returns an error
-func (s ProductStore) validateProductData(product model.Product) error {
-	if err := product.SKU.IsValid(); err != nil {
-		return error2.Validation(err.Error())
+// logger returns the *slog.Logger used to log the business events
+func (s ProductStore) logger() *slog.Logger {
+	if s.Logger == nil {
+		return slog.Default()
 	}
 
-	switch {
-	case product.Name == "":
-		return error2.Validation("product name must not be blank")
-	case len(product.Name) < 3:
-		return error2.Validation("product name is too short")
-	case len(product.Name) > 50:
-		return error2.Validation("product name is too large")
+	return s.Logger
+}
+
+// rules returns the *RuleEngine used to validate the product data
+func (s ProductStore) rules() *RuleEngine {
+	if s.Rules == nil {
+		return defaultRuleEngine
+	}
 
-	case product.Brand == "":
-		return error2.Validation("product brand must not be blank")
-	case len(product.Brand) < 3:
-		return error2.Validation("product brand is too short")
-	case len(product.Brand) > 50:
-		return error2.Validation("product brand is too large")
+	return s.Rules
+}
 
-	case product.Size != nil && *product.Size == "":
-		return error2.Validation("product size must not be blank")
+// CreateProduct validates the model.Product and if it is valid, a record is created in the storage
+//
+// The model.SKU must match the model.SKUScheme of the brand or the tenant (see model.SKUSchemes.Resolve),
+// if it is blank the next free model.SKU of the scheme is allocated.
+// The duplicated images are removed and, if the ImagePolicy enables the verification, the images are requested before creating the record.
+// After creating the record the images are ingested in background by the *ImageIngester.
+// Every product is created as model.StatusDraft, the status received is ignored.
+// If the product is likely a duplicate of existing products of the same brand returns error2.Duplicates,
+// unless ctx allows the duplicates (see AllowDuplicates)
+func (s ProductStore) CreateProduct(ctx context.Context, product *model.Product) error {
+	product.OtherImages = s.Images.dedupe(product.PrincipalImage, product.OtherImages)
+	product.Status = model.StatusDraft
 
-	case product.Price < 1.0 || product.Price > 99_999_999.00:
-		return error2.Validation("invalid product price")
+	scheme := s.skuScheme(ctx, product.Brand)
+	allocate := product.SKU == "" && s.Sequences != nil
 
-	case product.PrincipalImage == nil:
-		return error2.Validation("principal image for product is required")
-	case product.PrincipalImage.URL == nil:
-		return error2.Validation("principal image for product is required")
+	check := func(sku model.SKU) (model.SKUScheme, error) {
+		if allocate {
+			return scheme, nil
+		}
+
+		return scheme, scheme.Validate(sku)
 	}
 
-	return nil
-}
+	_, span := startSpan(ctx, "validate product")
+	err := s.validateProductData(*product, check)
+	endSpan(span, err)
 
-// CreateProduct validates the model.Product and if it is valid, a record is created in the storage
-func (s ProductStore) CreateProduct(product *model.Product) error {
-	err := s.validateProductData(*product)
+	if err != nil {
+		s.logger().DebugContext(ctx, "invalid product data", "sku", product.SKU, "error", err)
+		return err
+	}
+
+	if err = s.verifyImages(ctx, *product); err != nil {
+		s.logger().DebugContext(ctx, "unverified product images", "sku", product.SKU, "error", err)
+		return err
+	}
+
+	if err = s.checkDuplicates(ctx, *product); err != nil {
+		return err
+	}
+
+	if allocate {
+		skus, err := s.allocateSKUs(ctx, scheme, 1)
+		if err != nil {
+			return err
+		}
+
+		product.SKU = skus[0]
+	}
+
+	err = s.Create(ctx, product)
 	if err != nil {
 		return err
 	}
 
-	return s.Create(product)
+	s.Media.Enqueue(ctx, product.SKU)
+
+	s.logger().InfoContext(ctx, "product created", "sku", product.SKU)
+	return nil
 }
 
 // ObtainProduct if the model.SKU received as parameter is valid, search into storage a record identifier by the model.SKU
 //
 // The model.SKU is validated before to search the model.Product into storage to avoid
-func (s ProductStore) ObtainProduct(sku model.SKU) (model.Product, error) {
-	if err := sku.IsValid(); err != nil {
-		return model.Product{}, error2.Validation(err.Error())
+func (s ProductStore) ObtainProduct(ctx context.Context, sku model.SKU) (model.Product, error) {
+	if scheme, err := s.schemes().Match(sku); err != nil {
+		return model.Product{}, s.invalid("sku", RuleFormat, err.Error(), skuParams(scheme))
 	}
 
-	return s.Obtain(sku)
+	return s.Obtain(ctx, sku)
+}
+
+// ObtainProductBatch validates every model.SKU and obtains the records using a single storage request.
+// The products keep the order of the model.SKUs (duplicated SKUs are ignored) and the SKUs that do not exist are reported as missing
+func (s ProductStore) ObtainProductBatch(ctx context.Context, skus []model.SKU) (model.ProductBatch, error) {
+	maxBatchSize := s.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = DefaultMaxBatchSize
+	}
+
+	switch {
+	case len(skus) == 0:
+		return model.ProductBatch{}, s.invalid("skus", RuleMinItems, "at least one sku is required", error2.RuleParams{"min": 1})
+	case len(skus) > maxBatchSize:
+		return model.ProductBatch{}, s.invalid("skus", RuleMaxItems, fmt.Sprintf("the batch contains %d skus, the maximum is %d", len(skus), maxBatchSize), error2.RuleParams{"max": maxBatchSize})
+	}
+
+	unique := make([]model.SKU, 0, len(skus))
+	seen := make(map[model.SKU]bool, len(skus))
+	v := &violations{metrics: s.Metrics}
+
+	for i, sku := range skus {
+		if scheme, err := s.schemes().Match(sku); err != nil {
+			v.add(fmt.Sprintf("/skus/%d", i), RuleFormat, fmt.Sprintf("invalid sku '%s': %s", sku, err), skuParams(scheme))
+			continue
+		}
+
+		if seen[sku] {
+			continue
+		}
+
+		seen[sku] = true
+		unique = append(unique, sku)
+	}
+
+	if err := v.err(); err != nil {
+		return model.ProductBatch{}, err
+	}
+
+	records, err := s.ObtainMany(ctx, unique)
+	if err != nil {
+		return model.ProductBatch{}, err
+	}
+
+	batch := model.ProductBatch{
+		Products: make(model.Products, 0, len(records)),
+		Missing:  make([]model.SKU, 0),
+	}
+
+	for _, sku := range unique {
+		product, ok := records[sku]
+		if !ok {
+			batch.Missing = append(batch.Missing, sku)
+			continue
+		}
+
+		batch.Products = append(batch.Products, product)
+	}
+
+	return batch, nil
 }
 
 // UpdateProduct updates the record of model.Product identified by the model.SKU
 //
-// The model.SKU is validated before updating the record to avoid unnecessary and wasted requests to storage
-func (s ProductStore) UpdateProduct(product model.Product) error {
-	err := s.validateProductData(product)
+// The model.SKU is validated before updating the record to avoid unnecessary and wasted requests to storage,
+// the images are handled as in CreateProduct. The status received is ignored, it is only changed by TransitionProduct.
+// If the price change exceeds the PriceApproval thresholds, the rest of the product is updated but the current price is kept
+// and the change is held until it is approved (see ReviewPriceChange), in that case returns the pending model.PriceChange.
+// The product and the pending change are stored in a single operation
+func (s ProductStore) UpdateProduct(ctx context.Context, product model.Product) (*model.PriceChange, error) {
+	product.OtherImages = s.Images.dedupe(product.PrincipalImage, product.OtherImages)
+	product.Status = ""
+
+	_, span := startSpan(ctx, "validate product")
+	err := s.validateProductData(product, s.schemes().Match)
+	endSpan(span, err)
+
 	if err != nil {
-		return err
+		s.logger().DebugContext(ctx, "invalid product data", "sku", product.SKU, "error", err)
+		return nil, err
+	}
+
+	if err = s.verifyImages(ctx, product); err != nil {
+		s.logger().DebugContext(ctx, "unverified product images", "sku", product.SKU, "error", err)
+		return nil, err
+	}
+
+	change, err := s.holdPrice(ctx, &product)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.Update(product.SKU, product)
+	if change != nil {
+		err = s.Approvals.RequestChange(ctx, product, change)
+	} else {
+		err = s.Update(ctx, product.SKU, product)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.Media.Enqueue(ctx, product.SKU)
+
+	s.logger().InfoContext(ctx, "product updated", "sku", product.SKU)
+
+	if change != nil {
+		s.logger().InfoContext(ctx, "price change requested", "id", change.ID, "sku", change.SKU, "price", change.Price, "by", change.RequestedBy)
+	}
+
+	return change, nil
 }
 
 // DeleteProduct deletes the record of model.Product identified by the model.SKU received
 //
 // The model.SKU is validated before de-registration to avoid unnecessary and wasted storage requests
-func (s ProductStore) DeleteProduct(sku model.SKU) error {
-	if err := sku.IsValid(); err != nil {
-		return error2.Validation(err.Error())
+func (s ProductStore) DeleteProduct(ctx context.Context, sku model.SKU) error {
+	if scheme, err := s.schemes().Match(sku); err != nil {
+		return s.invalid("sku", RuleFormat, err.Error(), skuParams(scheme))
+	}
+
+	err := s.Delete(ctx, sku)
+	if err != nil {
+		return err
 	}
 
-	return s.Delete(sku)
+	s.logger().InfoContext(ctx, "product deleted", "sku", sku)
+	return nil
 }
 
-// ListProducts returns all records of model.Product from the storage
-func (s ProductStore) ListProducts() (model.Products, error) {
-	return s.List()
+// ListProducts returns the page of the records of model.Product from the storage that match the model.ProductFilter ordered by SKU,
+// if the filter does not contain statuses only the published products are listed
+func (s ProductStore) ListProducts(ctx context.Context, filter model.ProductFilter) (model.ProductPage, error) {
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = publicStatuses
+	}
+
+	if s.Finder != nil {
+		return s.Finder.Find(ctx, filter)
+	}
+
+	products, err := s.List(repository.WithStatuses(ctx, filter.Statuses))
+	if err != nil {
+		return model.ProductPage{}, err
+	}
+
+	// The order is required to paginate the products, the memory storages do not keep any order
+	slices.SortFunc(products, func(a, b model.Product) int {
+		return cmp.Compare(a.SKU, b.SKU)
+	})
+
+	// The storages could ignore the statuses carried by the context
+	return filter.Page(products), nil
 }
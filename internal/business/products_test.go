@@ -1,12 +1,14 @@
 package business
 
 import (
+	"context"
 	"errors"
 	"github.com/yael-castro/products-api/internal/model"
 	error2 "github.com/yael-castro/products-api/internal/model/error"
 	"github.com/yael-castro/products-api/internal/repository"
 	"net/url"
 	"reflect"
+	"slices"
 	"strconv"
 	"testing"
 )
@@ -19,15 +21,15 @@ func TestProductStore_ObtainProduct(t *testing.T) {
 	}{
 		{
 			sku:         "invalid",
-			expectedErr: error2.Validation("missing prefix 'FAL-'"),
+			expectedErr: error2.Violations{{Field: "sku", Reason: "missing prefix 'FAL-'"}},
 		},
 		{
 			sku:         "FAL-0",
-			expectedErr: error2.Validation("invalid suffix '0'"),
+			expectedErr: error2.Violations{{Field: "sku", Reason: "invalid suffix '0'"}},
 		},
 		{
 			sku:         "FAL-100000000000",
-			expectedErr: error2.Validation("invalid suffix '100000000000'"),
+			expectedErr: error2.Violations{{Field: "sku", Reason: "invalid suffix '100000000000'"}},
 		},
 		{
 			sku:             model.SKU("FAL-" + strconv.Itoa(99_999_999)),
@@ -43,9 +45,9 @@ func TestProductStore_ObtainProduct(t *testing.T) {
 
 	for i, v := range tdt {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			product, err := store.ObtainProduct(v.sku)
+			product, err := store.ObtainProduct(context.Background(), v.sku)
 
-			if !errors.Is(err, v.expectedErr) {
+			if !matchError(err, v.expectedErr) {
 				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
 			}
 
@@ -62,6 +64,72 @@ func TestProductStore_ObtainProduct(t *testing.T) {
 	}
 }
 
+func TestProductStore_ObtainProductBatch(t *testing.T) {
+	tdt := []struct {
+		skus          []model.SKU
+		expectedBatch model.ProductBatch
+		expectedErr   error
+	}{
+		{
+			skus:        []model.SKU{},
+			expectedErr: error2.Violations{{Field: "skus", Reason: "at least one sku is required"}},
+		},
+		{
+			skus:        []model.SKU{"FAL-1000001", "FAL-1000002", "FAL-1000003", "FAL-1000004"},
+			expectedErr: error2.Violations{{Field: "skus", Reason: "the batch contains 4 skus, the maximum is 3"}},
+		},
+		{
+			skus: []model.SKU{"invalid", "FAL-1000001", "FAL-0"},
+			expectedErr: error2.Violations{
+				{Field: "skus", Reason: "invalid sku 'invalid': missing prefix 'FAL-'"},
+				{Field: "skus", Reason: "invalid sku 'FAL-0': invalid suffix '0'"},
+			},
+		},
+		{
+			skus: []model.SKU{"FAL-1000003", "FAL-1000002", "FAL-1000001"},
+			expectedBatch: model.ProductBatch{
+				Products: model.Products{{SKU: "FAL-1000003"}, {SKU: "FAL-1000001"}},
+				Missing:  []model.SKU{"FAL-1000002"},
+			},
+		},
+		{
+			skus: []model.SKU{"FAL-1000001", "FAL-1000001"},
+			expectedBatch: model.ProductBatch{
+				Products: model.Products{{SKU: "FAL-1000001"}},
+				Missing:  []model.SKU{},
+			},
+		},
+	}
+
+	store := ProductStore{
+		StorageManager: &repository.MockStorage[model.SKU, model.Product]{
+			"FAL-1000001": model.Product{SKU: "FAL-1000001"},
+			"FAL-1000003": model.Product{SKU: "FAL-1000003"},
+		},
+		MaxBatchSize: 3,
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			batch, err := store.ObtainProductBatch(context.Background(), v.skus)
+
+			if !matchError(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			if err != nil {
+				t.Skip(err)
+			}
+
+			if !reflect.DeepEqual(v.expectedBatch, batch) {
+				t.Fatalf("expected batch '%v' unexpected batch '%v'", v.expectedBatch, batch)
+			}
+
+			t.Log(batch)
+		})
+	}
+}
+
 func TestProductStore_DeleteProduct(t *testing.T) {
 	tdt := []struct {
 		sku         model.SKU
@@ -69,15 +137,15 @@ func TestProductStore_DeleteProduct(t *testing.T) {
 	}{
 		{
 			sku:         "invalid",
-			expectedErr: error2.Validation("missing prefix 'FAL-'"),
+			expectedErr: error2.Violations{{Field: "sku", Reason: "missing prefix 'FAL-'"}},
 		},
 		{
 			sku:         "FAL-0",
-			expectedErr: error2.Validation("invalid suffix '0'"),
+			expectedErr: error2.Violations{{Field: "sku", Reason: "invalid suffix '0'"}},
 		},
 		{
 			sku:         "FAL-100000000000",
-			expectedErr: error2.Validation("invalid suffix '100000000000'"),
+			expectedErr: error2.Violations{{Field: "sku", Reason: "invalid suffix '100000000000'"}},
 		},
 		{
 			sku: model.SKU("FAL-" + strconv.Itoa(99_999_999)),
@@ -90,9 +158,9 @@ func TestProductStore_DeleteProduct(t *testing.T) {
 
 	for i, v := range tdt {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			err := store.DeleteProduct(v.sku)
+			err := store.DeleteProduct(context.Background(), v.sku)
 
-			if !errors.Is(err, v.expectedErr) {
+			if !matchError(err, v.expectedErr) {
 				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
 			}
 
@@ -116,41 +184,41 @@ func TestProductStore_CreateProduct(t *testing.T) {
 				Price:          10,
 				Brand:          "Nike",
 				Name:           "Shoes",
-				PrincipalImage: &model.URL{URL: &url.URL{}},
+				PrincipalImage: &model.URL{URL: &url.URL{Scheme: "https", Host: "example.com"}},
 			},
 		},
 		{
 			product: model.Product{
 				SKU: "FAL-1",
 			},
-			expectedErr: error2.Validation("invalid suffix '1'"),
+			expectedErr: error2.Violations{{Field: "sku", Reason: "invalid suffix '1'"}},
 		},
 		{
 			product: model.Product{
 				SKU: "FAL-1234567",
 			},
-			expectedErr: error2.Validation("product name must not be blank"),
+			expectedErr: error2.Violations{{Field: "name", Reason: "product name must not be blank"}},
 		},
 		{
 			product: model.Product{
 				SKU:  "FAL-1234567",
 				Name: "A",
 			},
-			expectedErr: error2.Validation("product name is too short"),
+			expectedErr: error2.Violations{{Field: "name", Reason: "product name is too short"}},
 		},
 		{
 			product: model.Product{
 				SKU:  "FAL-1234567",
 				Name: string(make([]byte, 51)),
 			},
-			expectedErr: error2.Validation("product name is too large"),
+			expectedErr: error2.Violations{{Field: "name", Reason: "product name is too large"}},
 		},
 		{
 			product: model.Product{
 				SKU:  "FAL-1234567",
 				Name: "AAA",
 			},
-			expectedErr: error2.Validation("product brand must not be blank"),
+			expectedErr: error2.Violations{{Field: "brand", Reason: "product brand must not be blank"}},
 		},
 		{
 			product: model.Product{
@@ -158,7 +226,7 @@ func TestProductStore_CreateProduct(t *testing.T) {
 				Name:  "AAA",
 				Brand: "AA",
 			},
-			expectedErr: error2.Validation("product brand is too short"),
+			expectedErr: error2.Violations{{Field: "brand", Reason: "product brand is too short"}},
 		},
 		{
 			product: model.Product{
@@ -166,7 +234,7 @@ func TestProductStore_CreateProduct(t *testing.T) {
 				Name:  "AAA",
 				Brand: string(make([]byte, 51)),
 			},
-			expectedErr: error2.Validation("product brand is too large"),
+			expectedErr: error2.Violations{{Field: "brand", Reason: "product brand is too large"}},
 		},
 	}
 
@@ -176,8 +244,8 @@ func TestProductStore_CreateProduct(t *testing.T) {
 
 	for i, v := range tdt {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			err := store.CreateProduct(&v.product)
-			if !errors.Is(err, v.expectedErr) {
+			err := store.CreateProduct(context.Background(), &v.product)
+			if !matchError(err, v.expectedErr) {
 				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
 			}
 
@@ -201,41 +269,41 @@ func TestProductStore_UpdateProduct(t *testing.T) {
 				Price:          10,
 				Brand:          "Nike",
 				Name:           "Shoes",
-				PrincipalImage: &model.URL{URL: &url.URL{}},
+				PrincipalImage: &model.URL{URL: &url.URL{Scheme: "https", Host: "example.com"}},
 			},
 		},
 		{
 			product: model.Product{
 				SKU: "FAL-1",
 			},
-			expectedErr: error2.Validation("invalid suffix '1'"),
+			expectedErr: error2.Violations{{Field: "sku", Reason: "invalid suffix '1'"}},
 		},
 		{
 			product: model.Product{
 				SKU: "FAL-1234567",
 			},
-			expectedErr: error2.Validation("product name must not be blank"),
+			expectedErr: error2.Violations{{Field: "name", Reason: "product name must not be blank"}},
 		},
 		{
 			product: model.Product{
 				SKU:  "FAL-1234567",
 				Name: "A",
 			},
-			expectedErr: error2.Validation("product name is too short"),
+			expectedErr: error2.Violations{{Field: "name", Reason: "product name is too short"}},
 		},
 		{
 			product: model.Product{
 				SKU:  "FAL-1234567",
 				Name: string(make([]byte, 51)),
 			},
-			expectedErr: error2.Validation("product name is too large"),
+			expectedErr: error2.Violations{{Field: "name", Reason: "product name is too large"}},
 		},
 		{
 			product: model.Product{
 				SKU:  "FAL-1234567",
 				Name: "AAA",
 			},
-			expectedErr: error2.Validation("product brand must not be blank"),
+			expectedErr: error2.Violations{{Field: "brand", Reason: "product brand must not be blank"}},
 		},
 		{
 			product: model.Product{
@@ -243,7 +311,7 @@ func TestProductStore_UpdateProduct(t *testing.T) {
 				Name:  "AAA",
 				Brand: "AA",
 			},
-			expectedErr: error2.Validation("product brand is too short"),
+			expectedErr: error2.Violations{{Field: "brand", Reason: "product brand is too short"}},
 		},
 		{
 			product: model.Product{
@@ -251,7 +319,7 @@ func TestProductStore_UpdateProduct(t *testing.T) {
 				Name:  "AAA",
 				Brand: string(make([]byte, 51)),
 			},
-			expectedErr: error2.Validation("product brand is too large"),
+			expectedErr: error2.Violations{{Field: "brand", Reason: "product brand is too large"}},
 		},
 	}
 
@@ -261,8 +329,8 @@ func TestProductStore_UpdateProduct(t *testing.T) {
 
 	for i, v := range tdt {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			err := store.UpdateProduct(v.product)
-			if !errors.Is(err, v.expectedErr) {
+			_, err := store.UpdateProduct(context.Background(), v.product)
+			if !matchError(err, v.expectedErr) {
 				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
 			}
 
@@ -274,3 +342,29 @@ func TestProductStore_UpdateProduct(t *testing.T) {
 		})
 	}
 }
+
+// matchError reports whether err matches the expected error. Every expected error2.Violation must be into the
+// error2.Violations of err (same field and reason), other errors are compared using errors.Is
+func matchError(err, expected error) bool {
+	var expectedViolations error2.Violations
+	if !errors.As(expected, &expectedViolations) {
+		return errors.Is(err, expected)
+	}
+
+	var violations error2.Violations
+	if !errors.As(err, &violations) {
+		return false
+	}
+
+	for _, expected := range expectedViolations {
+		contained := slices.ContainsFunc(violations, func(violation error2.Violation) bool {
+			return violation.Field == expected.Field && violation.Reason == expected.Reason
+		})
+
+		if !contained {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,606 @@
+package business
+
+import (
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// valueKind kind of the values of a product field, it defines the rules supported by the field
+type valueKind uint8
+
+const (
+	textKind valueKind = iota
+	numberKind
+	listKind
+)
+
+// ruleValue value of a product field prepared to be checked by the rules
+type ruleValue struct {
+	present bool
+	text    string
+	number  float64
+	items   []string
+}
+
+// ruleField product field that could be checked by the rules
+type ruleField struct {
+	name  string
+	label string
+	kind  valueKind
+	value func(model.Product) ruleValue
+}
+
+// ruleFields product fields supported by the rules indexed by their JSON names, in the order they are checked
+var ruleFields = []ruleField{
+	{
+		name: "sku", label: "product sku", kind: textKind,
+		value: func(p model.Product) ruleValue { return ruleValue{present: p.SKU != "", text: string(p.SKU)} },
+	},
+	{
+		name: "name", label: "product name", kind: textKind,
+		value: func(p model.Product) ruleValue { return ruleValue{present: p.Name != "", text: p.Name} },
+	},
+	{
+		name: "brand", label: "product brand", kind: textKind,
+		value: func(p model.Product) ruleValue { return ruleValue{present: p.Brand != "", text: p.Brand} },
+	},
+	{
+		name: "size", label: "product size", kind: textKind,
+		value: func(p model.Product) ruleValue {
+			if p.Size == nil {
+				return ruleValue{}
+			}
+
+			return ruleValue{present: true, text: *p.Size}
+		},
+	},
+	{
+		name: "price", label: "product price", kind: numberKind,
+		value: func(p model.Product) ruleValue { return ruleValue{present: true, number: p.Price} },
+	},
+	{
+		name: "principalImage", label: "principal image", kind: textKind,
+		value: func(p model.Product) ruleValue {
+			if p.PrincipalImage == nil || p.PrincipalImage.URL == nil {
+				return ruleValue{}
+			}
+
+			return ruleValue{present: true, text: p.PrincipalImage.String()}
+		},
+	},
+	{
+		name: "otherImages", label: "other images", kind: listKind,
+		value: func(p model.Product) ruleValue {
+			items := make([]string, 0, len(p.OtherImages))
+			for _, image := range p.OtherImages {
+				if image.URL == nil {
+					items = append(items, "")
+					continue
+				}
+
+				items = append(items, image.String())
+			}
+
+			return ruleValue{present: len(items) > 0, items: items}
+		},
+	},
+}
+
+// findRuleField returns the ruleField identified by the JSON name
+func findRuleField(name string) (ruleField, bool) {
+	for _, field := range ruleFields {
+		if field.name == name {
+			return field, true
+		}
+	}
+
+	return ruleField{}, false
+}
+
+// ruleKinds kinds of values supported by each rule, the rules of the items of a list are the rules supported by textKind
+var ruleKinds = map[string][]valueKind{
+	RuleRequired:  {textKind, numberKind, listKind},
+	RuleNotBlank:  {textKind},
+	RuleMinLength: {textKind},
+	RuleMaxLength: {textKind},
+	RuleRange:     {numberKind},
+	RulePattern:   {textKind},
+	RuleEnum:      {textKind, numberKind},
+	RuleMinItems:  {listKind},
+	RuleMaxItems:  {listKind},
+}
+
+// Rule validation rule of a product field
+type Rule struct {
+	// Rule identifier of the rule: required, notBlank, minLength, maxLength, range, pattern, enum, minItems or maxItems
+	Rule string `yaml:"rule" json:"rule"`
+	// Min lower bound of minLength, range and minItems
+	Min *float64 `yaml:"min,omitempty" json:"min,omitempty"`
+	// Max upper bound of maxLength, range and maxItems
+	Max *float64 `yaml:"max,omitempty" json:"max,omitempty"`
+	// Pattern regular expression (RE2 syntax) of the rule pattern, the whole value must match it
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	// Values allowed values of the rule enum
+	Values []string `yaml:"values,omitempty" json:"values,omitempty"`
+	// Message reason reported when the rule is broken, if it is blank a message is built from the rule
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// compile checks that the Rule is supported by the kind of value and compiles its pattern
+func (r Rule) compile(kind valueKind) (Rule, error) {
+	kinds, ok := ruleKinds[r.Rule]
+	if !ok {
+		return r, fmt.Errorf(`rule '%s' is not supported`, r.Rule)
+	}
+
+	if !slices.Contains(kinds, kind) && !(kind == listKind && slices.Contains(kinds, textKind)) {
+		return r, fmt.Errorf(`rule '%s' is not supported by the field`, r.Rule)
+	}
+
+	switch r.Rule {
+	case RuleMinLength, RuleMinItems:
+		if r.Min == nil {
+			return r, fmt.Errorf(`rule '%s' requires min`, r.Rule)
+		}
+	case RuleMaxLength, RuleMaxItems:
+		if r.Max == nil {
+			return r, fmt.Errorf(`rule '%s' requires max`, r.Rule)
+		}
+	case RuleRange:
+		if r.Min == nil && r.Max == nil {
+			return r, fmt.Errorf(`rule '%s' requires min or max`, r.Rule)
+		}
+
+		if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+			return r, fmt.Errorf(`rule '%s' requires min lower than max`, r.Rule)
+		}
+	case RulePattern:
+		pattern, err := regexp.Compile(`^(?:` + r.Pattern + `)$`)
+		if err != nil {
+			return r, fmt.Errorf(`rule '%s' has an invalid pattern: %w`, r.Rule, err)
+		}
+
+		r.pattern = pattern
+	case RuleEnum:
+		if len(r.Values) == 0 {
+			return r, fmt.Errorf(`rule '%s' requires values`, r.Rule)
+		}
+	}
+
+	return r, nil
+}
+
+// params returns the parameters of the Rule reported by the violations
+func (r Rule) params() error2.RuleParams {
+	params := error2.RuleParams{}
+
+	switch r.Rule {
+	case RuleMinLength, RuleMinItems:
+		params["min"] = *r.Min
+	case RuleMaxLength, RuleMaxItems:
+		params["max"] = *r.Max
+	case RuleRange:
+		if r.Min != nil {
+			params["min"] = *r.Min
+		}
+
+		if r.Max != nil {
+			params["max"] = *r.Max
+		}
+	case RulePattern:
+		params["pattern"] = r.Pattern
+	case RuleEnum:
+		params["values"] = r.Values
+	}
+
+	if len(params) == 0 {
+		return nil
+	}
+
+	return params
+}
+
+// message returns the reason reported when the value identified by the label breaks the Rule
+func (r Rule) message(label string) string {
+	if r.Message != "" {
+		return r.Message
+	}
+
+	switch r.Rule {
+	case RuleRequired:
+		return label + " is required"
+	case RuleNotBlank:
+		return label + " must not be blank"
+	case RuleMinLength:
+		return label + " is too short"
+	case RuleMaxLength:
+		return label + " is too large"
+	case RuleRange:
+		switch {
+		case r.Min == nil:
+			return fmt.Sprintf("%s must not be greater than %s", label, formatFloat(*r.Max))
+		case r.Max == nil:
+			return fmt.Sprintf("%s must not be less than %s", label, formatFloat(*r.Min))
+		}
+
+		return fmt.Sprintf("%s must be between %s and %s", label, formatFloat(*r.Min), formatFloat(*r.Max))
+	case RulePattern:
+		return fmt.Sprintf("%s does not match the pattern '%s'", label, r.Pattern)
+	case RuleEnum:
+		return fmt.Sprintf("%s must be one of [%s]", label, strings.Join(r.Values, ", "))
+	case RuleMinItems:
+		return fmt.Sprintf("%s requires at least %s items", label, formatFloat(*r.Min))
+	case RuleMaxItems:
+		return fmt.Sprintf("%s allows at most %s items", label, formatFloat(*r.Max))
+	}
+
+	return label + " is invalid"
+}
+
+// broken indicates if the value breaks the Rule, the rules different from required and minItems are not checked for missing values
+func (r Rule) broken(value ruleValue) bool {
+	switch r.Rule {
+	case RuleRequired:
+		return !value.present
+	case RuleMinItems:
+		return float64(len(value.items)) < *r.Min
+	}
+
+	if !value.present {
+		return false
+	}
+
+	switch r.Rule {
+	case RuleNotBlank:
+		return strings.TrimSpace(value.text) == ""
+	case RuleMinLength:
+		return float64(utf8.RuneCountInString(value.text)) < *r.Min
+	case RuleMaxLength:
+		return float64(utf8.RuneCountInString(value.text)) > *r.Max
+	case RuleRange:
+		return (r.Min != nil && value.number < *r.Min) || (r.Max != nil && value.number > *r.Max)
+	case RulePattern:
+		return !r.pattern.MatchString(value.text)
+	case RuleEnum:
+		if value.text == "" {
+			return !slices.Contains(r.Values, formatFloat(value.number))
+		}
+
+		return !slices.Contains(r.Values, value.text)
+	case RuleMaxItems:
+		return float64(len(value.items)) > *r.Max
+	}
+
+	return false
+}
+
+// formatFloat formats the number without trailing zeros (e.g. 3 instead of 3.000000)
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Predicate condition over a product field, every non-empty criteria must be met
+type Predicate struct {
+	// Field JSON name of the product field
+	Field string `yaml:"field" json:"field"`
+	// Present indicates if the field must be present (true) or missing (false)
+	Present *bool `yaml:"present,omitempty" json:"present,omitempty"`
+	// In the value of the field must be one of the values
+	In []string `yaml:"in,omitempty" json:"in,omitempty"`
+	// Min the numeric value of the field must not be less than Min
+	Min *float64 `yaml:"min,omitempty" json:"min,omitempty"`
+	// Max the numeric value of the field must not be greater than Max
+	Max *float64 `yaml:"max,omitempty" json:"max,omitempty"`
+}
+
+// matches indicates if the model.Product meets the Predicate
+func (p Predicate) matches(field ruleField, product model.Product) bool {
+	value := field.value(product)
+
+	if p.Present != nil && *p.Present != value.present {
+		return false
+	}
+
+	if len(p.In) > 0 {
+		text := value.text
+		if field.kind == numberKind {
+			text = formatFloat(value.number)
+		}
+
+		if !slices.ContainsFunc(p.In, func(s string) bool { return strings.EqualFold(s, text) }) {
+			return false
+		}
+	}
+
+	if p.Min != nil && value.number < *p.Min {
+		return false
+	}
+
+	if p.Max != nil && value.number > *p.Max {
+		return false
+	}
+
+	return true
+}
+
+// Condition cross-field rules, the rules are checked only by the products that meet the Predicate
+type Condition struct {
+	// Name describes the condition, it is included in the errors of the RuleSet
+	Name string `yaml:"name" json:"name"`
+	// When predicate that the product must meet
+	When Predicate `yaml:"when" json:"when"`
+	// Then rules of each field indexed by its JSON name, they are checked after the rules of the field
+	Then map[string][]Rule `yaml:"then" json:"then"`
+}
+
+// RuleSet declarative validation rules for the product data, it is loaded from YAML or JSON files (see rules.example.yaml)
+//
+// The SKU format is defined by the model.SKUSchemes, it is not part of the RuleSet
+type RuleSet struct {
+	// Fields rules of each field indexed by its JSON name, the rules are checked in order and only the first
+	// broken rule of each value is reported
+	Fields map[string][]Rule `yaml:"fields" json:"fields"`
+	// Brands rules that replace the rules of Fields for the products of each brand (case-insensitive),
+	// the fields that are not listed keep the rules of Fields
+	Brands map[string]map[string][]Rule `yaml:"brands" json:"brands"`
+	// Conditions cross-field rules
+	Conditions []Condition `yaml:"conditions" json:"conditions"`
+}
+
+// DefaultRuleSet rules applied when none RuleSet is configured
+func DefaultRuleSet() RuleSet {
+	minText, maxText := float64(minTextLength), float64(maxTextLength)
+	minAmount, maxAmount := minPrice, maxPrice
+
+	text := func(label string) []Rule {
+		return []Rule{
+			{Rule: RuleRequired, Message: fmt.Sprintf("product %s must not be blank", label)},
+			{Rule: RuleMinLength, Min: &minText},
+			{Rule: RuleMaxLength, Max: &maxText},
+		}
+	}
+
+	return RuleSet{
+		Fields: map[string][]Rule{
+			"name":           text("name"),
+			"brand":          text("brand"),
+			"size":           {{Rule: RuleNotBlank}},
+			"price":          {{Rule: RuleRange, Min: &minAmount, Max: &maxAmount, Message: "invalid product price"}},
+			"principalImage": {{Rule: RuleRequired, Message: "principal image for product is required"}},
+		},
+	}
+}
+
+// compiledRules RuleSet ready to check products
+type compiledRules struct {
+	fields     map[string][]Rule
+	brands     map[string]map[string][]Rule
+	conditions []Condition
+}
+
+// compileRules compiles every rule of the map, the fields must be supported
+func compileRules(rules map[string][]Rule, scope string) (map[string][]Rule, error) {
+	compiled := make(map[string][]Rule, len(rules))
+
+	for name, fieldRules := range rules {
+		field, ok := findRuleField(name)
+		if !ok {
+			return nil, fmt.Errorf(`%s: field '%s' is not supported`, scope, name)
+		}
+
+		compiled[name] = make([]Rule, 0, len(fieldRules))
+
+		for _, rule := range fieldRules {
+			rule, err := rule.compile(field.kind)
+			if err != nil {
+				return nil, fmt.Errorf(`%s: field '%s': %w`, scope, name, err)
+			}
+
+			compiled[name] = append(compiled[name], rule)
+		}
+	}
+
+	return compiled, nil
+}
+
+// compile checks the RuleSet and compiles its rules
+func (s RuleSet) compile() (*compiledRules, error) {
+	fields, err := compileRules(s.Fields, "fields")
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := &compiledRules{
+		fields:     fields,
+		brands:     make(map[string]map[string][]Rule, len(s.Brands)),
+		conditions: make([]Condition, 0, len(s.Conditions)),
+	}
+
+	for brand, rules := range s.Brands {
+		if compiled.brands[strings.ToLower(brand)], err = compileRules(rules, fmt.Sprintf("brand '%s'", brand)); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, condition := range s.Conditions {
+		scope := fmt.Sprintf("condition %d", i)
+		if condition.Name != "" {
+			scope = fmt.Sprintf("condition '%s'", condition.Name)
+		}
+
+		if _, ok := findRuleField(condition.When.Field); !ok {
+			return nil, fmt.Errorf(`%s: field '%s' is not supported`, scope, condition.When.Field)
+		}
+
+		if condition.Then, err = compileRules(condition.Then, scope); err != nil {
+			return nil, err
+		}
+
+		compiled.conditions = append(compiled.conditions, condition)
+	}
+
+	return compiled, nil
+}
+
+// Validate checks that the RuleSet only contains supported fields and rules with valid parameters
+func (s RuleSet) Validate() error {
+	_, err := s.compile()
+	return err
+}
+
+// rules returns the rules of the field for the model.Product, including the brand overrides and the conditions
+func (c *compiledRules) rules(field ruleField, product model.Product) []Rule {
+	rules := c.fields[field.name]
+
+	if overrides, ok := c.brands[strings.ToLower(product.Brand)][field.name]; ok {
+		rules = overrides
+	}
+
+	for _, condition := range c.conditions {
+		then, ok := condition.Then[field.name]
+		if !ok {
+			continue
+		}
+
+		when, _ := findRuleField(condition.When.Field)
+		if condition.When.matches(when, product) {
+			rules = append(slices.Clip(rules), then...)
+		}
+	}
+
+	return rules
+}
+
+// validate checks the model.Product against the rules and returns every broken rule,
+// only the first broken rule of each value is reported
+func (c *compiledRules) validate(product model.Product) error2.Violations {
+	violations := make(error2.Violations, 0)
+
+	for _, field := range ruleFields {
+		rules := c.rules(field, product)
+		if len(rules) == 0 {
+			continue
+		}
+
+		value := field.value(product)
+		pointer := "/" + field.name
+
+		if field.kind != listKind {
+			if rule, ok := firstBroken(rules, value, nil); ok {
+				violations = append(violations, error2.Violated(pointer, rule.Rule, rule.message(field.label), rule.params()))
+			}
+
+			continue
+		}
+
+		listRules := []string{RuleRequired, RuleMinItems, RuleMaxItems}
+
+		if rule, ok := firstBroken(rules, value, listRules); ok {
+			violations = append(violations, error2.Violated(pointer, rule.Rule, rule.message(field.label), rule.params()))
+		}
+
+		itemRules := slices.DeleteFunc(slices.Clone(rules), func(r Rule) bool { return slices.Contains(listRules, r.Rule) })
+
+		for i, item := range value.items {
+			if rule, ok := firstBroken(itemRules, ruleValue{present: true, text: item}, nil); ok {
+				itemPointer := pointer + "/" + strconv.Itoa(i)
+				violations = append(violations, error2.Violated(itemPointer, rule.Rule, rule.message(fmt.Sprintf("image %d", i)), rule.params()))
+			}
+		}
+	}
+
+	return violations
+}
+
+// firstBroken returns the first rule broken by the value, if only is not nil the other rules are ignored
+func firstBroken(rules []Rule, value ruleValue, only []string) (Rule, bool) {
+	for _, rule := range rules {
+		if only != nil && !slices.Contains(only, rule.Rule) {
+			continue
+		}
+
+		if rule.broken(value) {
+			return rule, true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// RuleEngine checks the product data against a RuleSet, the RuleSet could be replaced at any time (hot reload)
+// without interrupting the validations in progress
+type RuleEngine struct {
+	rules atomic.Pointer[compiledRules]
+}
+
+// NewRuleEngine builds a *RuleEngine that checks the RuleSet, if the RuleSet is invalid returns an error
+func NewRuleEngine(set RuleSet) (*RuleEngine, error) {
+	engine := &RuleEngine{}
+	return engine, engine.Reload(set)
+}
+
+// Reload replaces the RuleSet, if the RuleSet is invalid the current one is kept and an error is returned
+func (e *RuleEngine) Reload(set RuleSet) error {
+	compiled, err := set.compile()
+	if err != nil {
+		return fmt.Errorf("invalid rule set: %w", err)
+	}
+
+	e.rules.Store(compiled)
+	return nil
+}
+
+// Validate checks the model.Product and returns every broken rule, if the model.Product is valid returns an empty list
+func (e *RuleEngine) Validate(product model.Product) error2.Violations {
+	return e.rules.Load().validate(product)
+}
+
+// ProductViolations broken rules of a product
+type ProductViolations struct {
+	SKU        model.SKU         `json:"sku"`
+	Violations error2.Violations `json:"violations"`
+}
+
+// RuleReport result of checking the existing products against a RuleSet
+type RuleReport struct {
+	// Checked number of checked products
+	Checked int `json:"checked"`
+	// Invalid products that break at least one rule
+	Invalid []ProductViolations `json:"invalid"`
+}
+
+// Check validates every model.Product and reports the products that break any rule,
+// it is used to know the impact of a RuleSet before activating it
+func (e *RuleEngine) Check(products model.Products) RuleReport {
+	report := RuleReport{
+		Checked: len(products),
+		Invalid: make([]ProductViolations, 0),
+	}
+
+	for _, product := range products {
+		if violations := e.Validate(product); len(violations) > 0 {
+			report.Invalid = append(report.Invalid, ProductViolations{SKU: product.SKU, Violations: violations})
+		}
+	}
+
+	return report
+}
+
+// defaultRuleEngine *RuleEngine of the DefaultRuleSet
+var defaultRuleEngine = func() *RuleEngine {
+	engine, err := NewRuleEngine(DefaultRuleSet())
+	if err != nil {
+		panic(err)
+	}
+
+	return engine
+}()
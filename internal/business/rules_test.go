@@ -0,0 +1,217 @@
+package business
+
+import (
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const testRuleSet = `
+fields:
+  name:
+    - rule: required
+    - rule: maxLength
+      max: 10
+  brand:
+    - rule: enum
+      values: [Nike, Adidas]
+  size:
+    - rule: pattern
+      pattern: '[SML]|X{1,3}L'
+  price:
+    - rule: range
+      min: 1
+      max: 1000
+  otherImages:
+    - rule: maxItems
+      max: 2
+    - rule: pattern
+      pattern: 'https://.*'
+brands:
+  adidas:
+    price:
+      - rule: range
+        min: 50
+conditions:
+  - name: sized products require images
+    when:
+      field: size
+      present: true
+    then:
+      otherImages:
+        - rule: minItems
+          min: 1
+`
+
+func TestRuleEngine_Validate(t *testing.T) {
+	image := model.URL{URL: &url.URL{Scheme: "https", Host: "example.com"}}
+	size, invalidSize := "XL", "XXXXL"
+
+	tdt := []struct {
+		product            model.Product
+		expectedViolations error2.Violations
+	}{
+		{
+			product: model.Product{SKU: "FAL-1234567", Name: "Shoes", Brand: "Nike", Price: 10},
+		},
+		{
+			product: model.Product{SKU: "FAL-1234567", Name: "Running shoes", Brand: "Puma", Price: 1001},
+			expectedViolations: error2.Violations{
+				{Field: "name", Pointer: "/name", Rule: RuleMaxLength, Params: error2.RuleParams{"max": 10.0}, Reason: "product name is too large"},
+				{Field: "brand", Pointer: "/brand", Rule: RuleEnum, Params: error2.RuleParams{"values": []string{"Nike", "Adidas"}}, Reason: "product brand must be one of [Nike, Adidas]"},
+				{Field: "price", Pointer: "/price", Rule: RuleRange, Params: error2.RuleParams{"min": 1.0, "max": 1000.0}, Reason: "product price must be between 1 and 1000"},
+			},
+		},
+		// Brand override
+		{
+			product: model.Product{SKU: "FAL-1234567", Name: "Shoes", Brand: "Adidas", Price: 5000},
+		},
+		{
+			product: model.Product{SKU: "FAL-1234567", Name: "Shoes", Brand: "Adidas", Price: 10},
+			expectedViolations: error2.Violations{
+				{Field: "price", Pointer: "/price", Rule: RuleRange, Params: error2.RuleParams{"min": 50.0}, Reason: "product price must not be less than 50"},
+			},
+		},
+		// Cross-field condition
+		{
+			product: model.Product{SKU: "FAL-1234567", Name: "Shoes", Brand: "Nike", Size: &size, Price: 10, OtherImages: model.URLs{image}},
+		},
+		{
+			product: model.Product{SKU: "FAL-1234567", Name: "Shoes", Brand: "Nike", Size: &invalidSize, Price: 10},
+			expectedViolations: error2.Violations{
+				{Field: "size", Pointer: "/size", Rule: RulePattern, Params: error2.RuleParams{"pattern": "[SML]|X{1,3}L"}, Reason: "product size does not match the pattern '[SML]|X{1,3}L'"},
+				{Field: "otherImages", Pointer: "/otherImages", Rule: RuleMinItems, Params: error2.RuleParams{"min": 1.0}, Reason: "other images requires at least 1 items"},
+			},
+		},
+		// Nested items
+		{
+			product: model.Product{SKU: "FAL-1234567", Name: "Shoes", Brand: "Nike", Price: 10, OtherImages: model.URLs{image, {URL: &url.URL{Path: "a.png"}}, image}},
+			expectedViolations: error2.Violations{
+				{Field: "otherImages", Pointer: "/otherImages", Rule: RuleMaxItems, Params: error2.RuleParams{"max": 2.0}, Reason: "other images allows at most 2 items"},
+				{Field: "otherImages", Pointer: "/otherImages/1", Rule: RulePattern, Params: error2.RuleParams{"pattern": "https://.*"}, Reason: "image 1 does not match the pattern 'https://.*'"},
+			},
+		},
+	}
+
+	set := RuleSet{}
+	if err := yaml.Unmarshal([]byte(testRuleSet), &set); err != nil {
+		t.Fatal(err)
+	}
+
+	engine, err := NewRuleEngine(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			violations := engine.Validate(v.product)
+			if len(v.expectedViolations) == 0 && len(violations) == 0 {
+				t.Skip("SUCCESS")
+			}
+
+			if !reflect.DeepEqual(v.expectedViolations, violations) {
+				t.Fatalf("expected violations '%+v' unexpected violations '%+v'", v.expectedViolations, violations)
+			}
+		})
+	}
+}
+
+func TestRuleSet_Validate(t *testing.T) {
+	tdt := []struct {
+		rules       string
+		expectedErr string
+	}{
+		{
+			rules: testRuleSet,
+		},
+		{
+			rules:       "fields: {color: [{rule: required}]}",
+			expectedErr: "fields: field 'color' is not supported",
+		},
+		{
+			rules:       "fields: {name: [{rule: unique}]}",
+			expectedErr: "fields: field 'name': rule 'unique' is not supported",
+		},
+		{
+			rules:       "fields: {price: [{rule: minLength, min: 1}]}",
+			expectedErr: "fields: field 'price': rule 'minLength' is not supported by the field",
+		},
+		{
+			rules:       "fields: {name: [{rule: maxLength}]}",
+			expectedErr: "fields: field 'name': rule 'maxLength' requires max",
+		},
+		{
+			rules:       "fields: {price: [{rule: range, min: 10, max: 1}]}",
+			expectedErr: "fields: field 'price': rule 'range' requires min lower than max",
+		},
+		{
+			rules:       "brands: {nike: {size: [{rule: pattern, pattern: '('}]}}",
+			expectedErr: "brand 'nike': field 'size': rule 'pattern' has an invalid pattern: error parsing regexp: missing closing ): `^(?:()$`",
+		},
+		{
+			rules:       "conditions: [{name: expensive, when: {field: cost}}]",
+			expectedErr: "condition 'expensive': field 'cost' is not supported",
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			set := RuleSet{}
+			if err := yaml.Unmarshal([]byte(v.rules), &set); err != nil {
+				t.Fatal(err)
+			}
+
+			err := set.Validate()
+			if (err == nil && v.expectedErr != "") || (err != nil && err.Error() != v.expectedErr) {
+				t.Fatalf("expected error '%s' unexpected error '%v'", v.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestRuleEngine_Reload(t *testing.T) {
+	engine, err := NewRuleEngine(DefaultRuleSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	product := model.Product{SKU: "FAL-1234567", Name: "Sh", Brand: "Nike", Price: 10, PrincipalImage: &model.URL{URL: &url.URL{Scheme: "https", Host: "example.com"}}}
+
+	if violations := engine.Validate(product); len(violations) != 1 {
+		t.Fatalf("expected 1 violation unexpected violations '%+v'", violations)
+	}
+
+	if err = engine.Reload(RuleSet{Fields: map[string][]Rule{"name": {{Rule: RuleRequired}}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if violations := engine.Validate(product); len(violations) != 0 {
+		t.Fatalf("unexpected violations '%+v'", violations)
+	}
+
+	// The invalid rule sets are not activated
+	if err = engine.Reload(RuleSet{Fields: map[string][]Rule{"name": {{Rule: RuleMinLength}}}}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if violations := engine.Validate(product); len(violations) != 0 {
+		t.Fatalf("unexpected violations '%+v'", violations)
+	}
+
+	report := engine.Check(model.Products{product, {SKU: "FAL-1234568"}})
+	expectedReport := RuleReport{
+		Checked: 2,
+		Invalid: []ProductViolations{
+			{SKU: "FAL-1234568", Violations: error2.Violations{{Field: "name", Pointer: "/name", Rule: RuleRequired, Reason: "product name is required"}}},
+		},
+	}
+
+	if !reflect.DeepEqual(expectedReport, report) {
+		t.Fatalf("expected report '%+v' unexpected report '%+v'", expectedReport, report)
+	}
+}
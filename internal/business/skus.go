@@ -0,0 +1,137 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+)
+
+// DefaultMaxReservationSize default maximum number of SKUs allowed by ProductManager.ReserveSKUs
+const DefaultMaxReservationSize = 1000
+
+// maxAllocationAttempts maximum number of requests made to the sequence to allocate free SKUs,
+// the SKUs created by the clients could be taken already
+const maxAllocationAttempts = 5
+
+// errAllocationDisabled the SKUs could not be allocated because the ProductStore does not have sequences
+var errAllocationDisabled = errors.New("sku allocation is not configured")
+
+// schemes returns the model.SKUSchemes used to validate and allocate the SKUs
+func (s ProductStore) schemes() model.SKUSchemes {
+	if len(s.SKUSchemes.Schemes) == 0 {
+		return model.DefaultSKUSchemes
+	}
+
+	return s.SKUSchemes
+}
+
+// skuScheme returns the model.SKUScheme of the brand or the tenant (the subject of the caller carried by ctx)
+func (s ProductStore) skuScheme(ctx context.Context, brand string) model.SKUScheme {
+	caller, _ := CallerFrom(ctx)
+	return s.schemes().Resolve(brand, caller.Subject)
+}
+
+// allocateSKUs obtains n free SKUs of the model.SKUScheme from its sequence, the SKUs that already exist in the storage are skipped.
+// If the scheme is exhausted or the free SKUs could not be found returns error2.Conflict
+func (s ProductStore) allocateSKUs(ctx context.Context, scheme model.SKUScheme, n int) ([]model.SKU, error) {
+	if s.Sequences == nil {
+		return nil, errAllocationDisabled
+	}
+
+	ctx, span := startSpan(ctx, "allocate skus")
+
+	skus, err := func() ([]model.SKU, error) {
+		skus := make([]model.SKU, 0, n)
+
+		for attempt := 0; attempt < maxAllocationAttempts && len(skus) < n; attempt++ {
+			values, err := s.Sequences.NextValues(ctx, scheme.Name, n-len(skus))
+			if err != nil {
+				return nil, err
+			}
+
+			candidates := make([]model.SKU, 0, len(values))
+
+			for _, value := range values {
+				sku, err := scheme.Format(value)
+				if err != nil {
+					// The sequence passed the last number of the scheme
+					return nil, error2.Conflict(err.Error())
+				}
+
+				candidates = append(candidates, sku)
+			}
+
+			taken, err := s.ObtainMany(ctx, candidates)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, sku := range candidates {
+				if _, ok := taken[sku]; !ok {
+					skus = append(skus, sku)
+				}
+			}
+		}
+
+		if len(skus) < n {
+			return nil, error2.Conflict(fmt.Sprintf(`%d free skus of the scheme '%s' could not be allocated, the allocated skus were already taken`, n, scheme.Name))
+		}
+
+		return skus, nil
+	}()
+
+	endSpan(span, err)
+	return skus, err
+}
+
+// ReserveSKUs allocates a block of free SKUs for offline use, the reserved SKUs are never allocated again
+//
+// The model.SKUScheme is the one indicated by the reservation, otherwise the scheme of the brand or the tenant
+func (s ProductStore) ReserveSKUs(ctx context.Context, reservation model.SKUReservation) (model.SKUBlock, error) {
+	maxReservationSize := s.MaxReservationSize
+	if maxReservationSize <= 0 {
+		maxReservationSize = DefaultMaxReservationSize
+	}
+
+	v := &violations{metrics: s.Metrics}
+
+	switch {
+	case reservation.Count < 1:
+		v.add("/count", RuleRange, "at least one sku is required", error2.RuleParams{"min": 1, "max": maxReservationSize})
+	case reservation.Count > maxReservationSize:
+		v.add("/count", RuleRange, fmt.Sprintf("the reservation contains %d skus, the maximum is %d", reservation.Count, maxReservationSize), error2.RuleParams{"min": 1, "max": maxReservationSize})
+	}
+
+	scheme, ok := s.skuScheme(ctx, reservation.Brand), true
+
+	if reservation.Scheme != "" {
+		if scheme, ok = s.schemes().Find(reservation.Scheme); !ok {
+			v.add("/scheme", RuleEnum, fmt.Sprintf("sku scheme '%s' does not exist", reservation.Scheme), error2.RuleParams{"values": s.schemeNames()})
+		}
+	}
+
+	if err := v.err(); err != nil {
+		return model.SKUBlock{}, err
+	}
+
+	skus, err := s.allocateSKUs(ctx, scheme, reservation.Count)
+	if err != nil {
+		return model.SKUBlock{}, err
+	}
+
+	s.logger().InfoContext(ctx, "skus reserved", "scheme", scheme.Name, "count", len(skus), "first", skus[0], "last", skus[len(skus)-1])
+	return model.SKUBlock{Scheme: scheme.Name, SKUs: skus}, nil
+}
+
+// schemeNames returns the names of the supported model.SKUScheme
+func (s ProductStore) schemeNames() []string {
+	names := make([]string, 0, len(s.schemes().Schemes))
+
+	for _, scheme := range s.schemes().Schemes {
+		names = append(names, scheme.Name)
+	}
+
+	return names
+}
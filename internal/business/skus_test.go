@@ -0,0 +1,210 @@
+package business
+
+import (
+	"context"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"github.com/yael-castro/products-api/internal/repository"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// testSKUSchemes schemes used by the tests, see skus.example.yaml
+var testSKUSchemes = model.SKUSchemes{
+	Default: "fal",
+	Schemes: []model.SKUScheme{
+		model.DefaultSKUScheme,
+		{Name: "acme", Prefix: "ACM-", Digits: 6, CheckDigit: model.CheckDigitLuhn, Brands: []string{"Acme"}},
+		{Name: "partner", Prefix: "PTR-", Digits: 8, CheckDigit: model.CheckDigitMod11, Tenants: []string{"partner-service"}},
+		{Name: "tiny", Prefix: "TNY-", Digits: 1, Max: 3},
+	},
+}
+
+func TestProductStore_CreateProduct_allocation(t *testing.T) {
+	image := &model.URL{URL: &url.URL{Scheme: "https", Host: "example.com"}}
+
+	tdt := []struct {
+		caller      model.Caller
+		product     model.Product
+		expectedSKU model.SKU
+		expectedErr error
+	}{
+		// The SKU of the default scheme is the next free SKU
+		{
+			product:     model.Product{Name: "Shoes", Brand: "Nike", Price: 10, PrincipalImage: image},
+			expectedSKU: "FAL-1000001",
+		},
+		// The scheme of the brand
+		{
+			product:     model.Product{Name: "Shoes", Brand: "acme", Price: 10, PrincipalImage: image},
+			expectedSKU: "ACM-0000018",
+		},
+		// The scheme of the tenant
+		{
+			caller:      model.Caller{Subject: "partner-service"},
+			product:     model.Product{Name: "Shoes", Brand: "Nike", Price: 10, PrincipalImage: image},
+			expectedSKU: "PTR-000000019",
+		},
+		{
+			product:     model.Product{SKU: "ACM-0000017", Name: "Shoes", Brand: "Acme", Price: 10, PrincipalImage: image},
+			expectedErr: error2.Violations{{Field: "sku", Reason: "invalid check digit '7'"}},
+		},
+		{
+			product:     model.Product{SKU: "FAL-1000005", Name: "Shoes", Brand: "Acme", Price: 10, PrincipalImage: image},
+			expectedErr: error2.Violations{{Field: "sku", Reason: "missing prefix 'ACM-'"}},
+		},
+		{
+			product:     model.Product{SKU: "ACM-0000026", Name: "Shoes", Brand: "Acme", Price: 10, PrincipalImage: image},
+			expectedSKU: "ACM-0000026",
+		},
+	}
+
+	store := ProductStore{
+		StorageManager: &repository.MockStorage[model.SKU, model.Product]{
+			// The SKUs created by the clients are skipped
+			"FAL-1000000": model.Product{SKU: "FAL-1000000"},
+		},
+		SKUSchemes: testSKUSchemes,
+		Sequences:  &repository.MockSequences{},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := context.Background()
+			if v.caller.Subject != "" {
+				ctx = WithCaller(ctx, v.caller)
+			}
+
+			err := store.CreateProduct(ctx, &v.product)
+			if !matchError(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			if err != nil {
+				t.Skip(err)
+			}
+
+			if v.product.SKU != v.expectedSKU {
+				t.Fatalf("expected sku '%s' unexpected sku '%s'", v.expectedSKU, v.product.SKU)
+			}
+		})
+	}
+}
+
+func TestProductStore_ReserveSKUs(t *testing.T) {
+	tdt := []struct {
+		reservation   model.SKUReservation
+		expectedBlock model.SKUBlock
+		expectedErr   error
+	}{
+		{
+			reservation: model.SKUReservation{Count: 0},
+			expectedErr: error2.Violations{{Field: "count", Reason: "at least one sku is required"}},
+		},
+		{
+			reservation: model.SKUReservation{Count: 4},
+			expectedErr: error2.Violations{{Field: "count", Reason: "the reservation contains 4 skus, the maximum is 3"}},
+		},
+		{
+			reservation: model.SKUReservation{Scheme: "unknown", Count: 1},
+			expectedErr: error2.Violations{{Field: "scheme", Reason: "sku scheme 'unknown' does not exist"}},
+		},
+		{
+			reservation:   model.SKUReservation{Count: 3},
+			expectedBlock: model.SKUBlock{Scheme: "fal", SKUs: []model.SKU{"FAL-1000000", "FAL-1000002", "FAL-1000003"}},
+		},
+		{
+			reservation:   model.SKUReservation{Brand: "Acme", Count: 2},
+			expectedBlock: model.SKUBlock{Scheme: "acme", SKUs: []model.SKU{"ACM-0000018", "ACM-0000026"}},
+		},
+		{
+			reservation:   model.SKUReservation{Scheme: "partner", Brand: "Acme", Count: 1},
+			expectedBlock: model.SKUBlock{Scheme: "partner", SKUs: []model.SKU{"PTR-000000019"}},
+		},
+		// The reserved SKUs are never allocated again
+		{
+			reservation:   model.SKUReservation{Count: 1},
+			expectedBlock: model.SKUBlock{Scheme: "fal", SKUs: []model.SKU{"FAL-1000004"}},
+		},
+		{
+			reservation:   model.SKUReservation{Scheme: "tiny", Count: 3},
+			expectedBlock: model.SKUBlock{Scheme: "tiny", SKUs: []model.SKU{"TNY-1", "TNY-2", "TNY-3"}},
+		},
+		{
+			reservation: model.SKUReservation{Scheme: "tiny", Count: 1},
+			expectedErr: error2.Conflict("sku scheme 'tiny' is exhausted"),
+		},
+	}
+
+	store := ProductStore{
+		StorageManager: &repository.MockStorage[model.SKU, model.Product]{
+			"FAL-1000001": model.Product{SKU: "FAL-1000001"},
+		},
+		SKUSchemes:         testSKUSchemes,
+		Sequences:          &repository.MockSequences{},
+		MaxReservationSize: 3,
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			block, err := store.ReserveSKUs(context.Background(), v.reservation)
+			if !matchError(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			if err != nil {
+				t.Skip(err)
+			}
+
+			if !reflect.DeepEqual(v.expectedBlock, block) {
+				t.Fatalf("expected block '%v' unexpected block '%v'", v.expectedBlock, block)
+			}
+		})
+	}
+}
+
+func TestSKUSchemes_Check(t *testing.T) {
+	tdt := []struct {
+		schemes     model.SKUSchemes
+		expectedErr string
+	}{
+		{
+			schemes: testSKUSchemes,
+		},
+		{
+			schemes:     model.SKUSchemes{},
+			expectedErr: "at least one sku scheme is required",
+		},
+		{
+			schemes:     model.SKUSchemes{Schemes: []model.SKUScheme{{Name: "Acme", Prefix: "ACM-"}}},
+			expectedErr: "invalid sku scheme name 'Acme'",
+		},
+		{
+			schemes:     model.SKUSchemes{Schemes: []model.SKUScheme{{Name: "acme", Prefix: "ACM-", CheckDigit: "verhoeff"}}},
+			expectedErr: "sku scheme 'acme' has an unsupported check digit 'verhoeff'",
+		},
+		{
+			schemes:     model.SKUSchemes{Schemes: []model.SKUScheme{{Name: "acme", Prefix: "ACM-", Digits: 3, Max: 10000}}},
+			expectedErr: "sku scheme 'acme' has a max with more than 3 digits",
+		},
+		{
+			schemes:     model.SKUSchemes{Schemes: []model.SKUScheme{{Name: "acme", Prefix: "ACM-"}, {Name: "acme", Prefix: "ACME-"}}},
+			expectedErr: "sku scheme 'acme' is duplicated",
+		},
+		{
+			schemes:     model.SKUSchemes{Default: "fal", Schemes: []model.SKUScheme{{Name: "acme", Prefix: "ACM-"}}},
+			expectedErr: "default sku scheme 'fal' does not exist",
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := v.schemes.Check()
+			if (err == nil && v.expectedErr != "") || (err != nil && err.Error() != v.expectedErr) {
+				t.Fatalf("expected error '%s' unexpected error '%v'", v.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package business
+
+import (
+	"context"
+	"github.com/yael-castro/products-api/internal/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName name of the instrumentation scope used by the tracers of the business layer
+const InstrumentationName = "github.com/yael-castro/products-api/internal/business"
+
+// startSpan starts a child span of the span carried by ctx using the same trace.TracerProvider,
+// if ctx does not carry any span the started span is a no-op span
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(InstrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records the error (if any) and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// _ "implements" constraint for ProductTracing
+var _ ProductManager = ProductTracing{}
+
+// ProductTracing decorates a ProductManager to start a span for each operation
+type ProductTracing struct {
+	ProductManager
+	trace.Tracer
+}
+
+// CreateProduct traces ProductManager.CreateProduct
+func (p ProductTracing) CreateProduct(ctx context.Context, product *model.Product) (err error) {
+	ctx, span := p.Start(ctx, "ProductManager.CreateProduct", trace.WithAttributes(attribute.String("product.sku", string(product.SKU))))
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.CreateProduct(ctx, product)
+}
+
+// ObtainProduct traces ProductManager.ObtainProduct
+func (p ProductTracing) ObtainProduct(ctx context.Context, sku model.SKU) (product model.Product, err error) {
+	ctx, span := p.Start(ctx, "ProductManager.ObtainProduct", trace.WithAttributes(attribute.String("product.sku", string(sku))))
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.ObtainProduct(ctx, sku)
+}
+
+// ObtainProductBatch traces ProductManager.ObtainProductBatch
+func (p ProductTracing) ObtainProductBatch(ctx context.Context, skus []model.SKU) (batch model.ProductBatch, err error) {
+	ctx, span := p.Start(ctx, "ProductManager.ObtainProductBatch", trace.WithAttributes(attribute.Int("batch.size", len(skus))))
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.ObtainProductBatch(ctx, skus)
+}
+
+// UpdateProduct traces ProductManager.UpdateProduct
+func (p ProductTracing) UpdateProduct(ctx context.Context, product model.Product) (change *model.PriceChange, err error) {
+	ctx, span := p.Start(ctx, "ProductManager.UpdateProduct", trace.WithAttributes(attribute.String("product.sku", string(product.SKU))))
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.UpdateProduct(ctx, product)
+}
+
+// DeleteProduct traces ProductManager.DeleteProduct
+func (p ProductTracing) DeleteProduct(ctx context.Context, sku model.SKU) (err error) {
+	ctx, span := p.Start(ctx, "ProductManager.DeleteProduct", trace.WithAttributes(attribute.String("product.sku", string(sku))))
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.DeleteProduct(ctx, sku)
+}
+
+// ListProducts traces ProductManager.ListProducts
+func (p ProductTracing) ListProducts(ctx context.Context, filter model.ProductFilter) (page model.ProductPage, err error) {
+	ctx, span := p.Start(ctx, "ProductManager.ListProducts", trace.WithAttributes(
+		attribute.StringSlice("product.statuses", statusNames(filter.Statuses)),
+		attribute.Int("page.offset", filter.Offset),
+		attribute.Int("page.limit", filter.Limit),
+	))
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.ListProducts(ctx, filter)
+}
+
+// ReserveSKUs traces ProductManager.ReserveSKUs
+func (p ProductTracing) ReserveSKUs(ctx context.Context, reservation model.SKUReservation) (block model.SKUBlock, err error) {
+	ctx, span := p.Start(ctx, "ProductManager.ReserveSKUs", trace.WithAttributes(
+		attribute.String("sku.scheme", reservation.Scheme),
+		attribute.Int("sku.count", reservation.Count),
+	))
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.ReserveSKUs(ctx, reservation)
+}
+
+// UploadImage traces ProductManager.UploadImage
+func (p ProductTracing) UploadImage(ctx context.Context, sku model.SKU, upload model.ImageUpload) (product model.Product, err error) {
+	ctx, span := p.Start(ctx, "ProductManager.UploadImage", trace.WithAttributes(
+		attribute.String("product.sku", string(sku)),
+		attribute.Bool("image.principal", upload.Principal),
+	))
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.UploadImage(ctx, sku, upload)
+}
+
+// TransitionProduct traces ProductManager.TransitionProduct
+func (p ProductTracing) TransitionProduct(ctx context.Context, sku model.SKU, status model.Status) (product model.Product, err error) {
+	ctx, span := p.Start(ctx, "ProductManager.TransitionProduct", trace.WithAttributes(
+		attribute.String("product.sku", string(sku)),
+		attribute.String("product.status", string(status)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.TransitionProduct(ctx, sku, status)
+}
+
+// ObtainTransitions traces ProductManager.ObtainTransitions
+func (p ProductTracing) ObtainTransitions(ctx context.Context, sku model.SKU) (transitions []model.StatusTransition, err error) {
+	ctx, span := p.Start(ctx, "ProductManager.ObtainTransitions", trace.WithAttributes(attribute.String("product.sku", string(sku))))
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.ObtainTransitions(ctx, sku)
+}
+
+// ListPriceChanges traces ProductManager.ListPriceChanges
+func (p ProductTracing) ListPriceChanges(ctx context.Context, filter model.PriceChangeFilter) (changes []model.PriceChange, err error) {
+	statuses := make([]string, 0, len(filter.Statuses))
+	for _, status := range filter.Statuses {
+		statuses = append(statuses, string(status))
+	}
+
+	ctx, span := p.Start(ctx, "ProductManager.ListPriceChanges", trace.WithAttributes(
+		attribute.StringSlice("price_change.statuses", statuses),
+		attribute.String("product.sku", string(filter.SKU)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.ListPriceChanges(ctx, filter)
+}
+
+// ObtainPriceChange traces ProductManager.ObtainPriceChange
+func (p ProductTracing) ObtainPriceChange(ctx context.Context, id uint) (change model.PriceChange, err error) {
+	ctx, span := p.Start(ctx, "ProductManager.ObtainPriceChange", trace.WithAttributes(attribute.Int("price_change.id", int(id))))
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.ObtainPriceChange(ctx, id)
+}
+
+// ReviewPriceChange traces ProductManager.ReviewPriceChange
+func (p ProductTracing) ReviewPriceChange(ctx context.Context, review model.PriceReview) (change model.PriceChange, err error) {
+	ctx, span := p.Start(ctx, "ProductManager.ReviewPriceChange", trace.WithAttributes(
+		attribute.Int("price_change.id", int(review.ID)),
+		attribute.String("price_change.status", string(review.Status)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.ReviewPriceChange(ctx, review)
+}
+
+// StartDuplicateReport traces ProductManager.StartDuplicateReport
+func (p ProductTracing) StartDuplicateReport(ctx context.Context) (report model.DuplicateReport, err error) {
+	ctx, span := p.Start(ctx, "ProductManager.StartDuplicateReport")
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.StartDuplicateReport(ctx)
+}
+
+// ObtainDuplicateReport traces ProductManager.ObtainDuplicateReport
+func (p ProductTracing) ObtainDuplicateReport(ctx context.Context) (report model.DuplicateReport, err error) {
+	ctx, span := p.Start(ctx, "ProductManager.ObtainDuplicateReport")
+	defer func() { endSpan(span, err) }()
+
+	return p.ProductManager.ObtainDuplicateReport(ctx)
+}
@@ -0,0 +1,229 @@
+package business
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// uploadFormats functions to remove the metadata of the uploaded images indexed by their media type
+var uploadFormats = map[string]func([]byte) ([]byte, error){
+	"image/png":  stripPNG,
+	"image/jpeg": stripJPEG,
+	// The GIF images do not carry EXIF metadata
+	"image/gif": func(data []byte) ([]byte, error) { return data, nil },
+}
+
+// prepare reads the image uploaded by a client, detects its media type from the content and removes its metadata
+// (e.g. EXIF, XMP and text chunks). Returns the content to store and its media URL, the image is not stored yet
+func (i *ImageIngester) prepare(ctx context.Context, content io.Reader) ([]byte, model.URL, error) {
+	_, span := startSpan(ctx, "prepare image")
+
+	data, u, err := func() ([]byte, model.URL, error) {
+		data, err := io.ReadAll(io.LimitReader(content, i.config.MaxSize+1))
+		if err != nil {
+			return nil, model.URL{}, err
+		}
+
+		if int64(len(data)) > i.config.MaxSize {
+			return nil, model.URL{}, error2.TooLarge(fmt.Sprintf("the image exceeds the maximum of %d bytes", i.config.MaxSize))
+		}
+
+		mediaType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+		if !slices.Contains(i.config.UploadContentTypes, mediaType) {
+			return nil, model.URL{}, error2.UnsupportedMediaType(fmt.Sprintf(
+				"media type '%s' is not supported, the supported types are [%s]",
+				mediaType,
+				strings.Join(i.config.UploadContentTypes, ", "),
+			))
+		}
+
+		if data, err = uploadFormats[mediaType](data); err != nil {
+			return nil, model.URL{}, error2.Unprocessable(fmt.Sprintf("the image could not be decoded: %v", err))
+		}
+
+		format, err := i.inspect(data)
+		if err != nil {
+			if errors.As(err, new(error2.TooLarge)) {
+				return nil, model.URL{}, err
+			}
+
+			return nil, model.URL{}, error2.Unprocessable(err.Error())
+		}
+
+		_, key := originalKey(data, mediaFormats[format])
+
+		u, err := i.mediaURL(key)
+		return data, u, err
+	}()
+
+	endSpan(span, err)
+	return data, u, err
+}
+
+// jpegMetadata markers of the JPEG segments removed from the uploaded images:
+// APP1 (EXIF and XMP), APP13 (Photoshop IRB) and COM (comments)
+var jpegMetadata = map[byte]bool{0xE1: true, 0xED: true, 0xFE: true}
+
+// stripJPEG removes the metadata segments located before the start of scan (SOS) of the JPEG image
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errors.New("missing jpeg start of image")
+	}
+
+	stripped := bytes.NewBuffer(make([]byte, 0, len(data)))
+	stripped.Write(data[:2])
+
+	for offset := 2; ; {
+		if offset+4 > len(data) || data[offset] != 0xFF {
+			return nil, errors.New("invalid jpeg segment")
+		}
+
+		marker := data[offset+1]
+
+		// The segments after the start of scan are the compressed image data
+		if marker == 0xDA {
+			stripped.Write(data[offset:])
+			return stripped.Bytes(), nil
+		}
+
+		end := offset + 2 + int(binary.BigEndian.Uint16(data[offset+2:]))
+		if end > len(data) {
+			return nil, errors.New("truncated jpeg segment")
+		}
+
+		if !jpegMetadata[marker] {
+			stripped.Write(data[offset:end])
+		}
+
+		offset = end
+	}
+}
+
+// pngSignature first bytes of every PNG image
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// pngMetadata types of the PNG chunks removed from the uploaded images
+var pngMetadata = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+// stripPNG removes the metadata chunks of the PNG image, the chunks after IEND are discarded
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(pngSignature)) {
+		return nil, errors.New("missing png signature")
+	}
+
+	stripped := bytes.NewBuffer(make([]byte, 0, len(data)))
+	stripped.WriteString(pngSignature)
+
+	for offset := len(pngSignature); ; {
+		if offset+12 > len(data) {
+			return nil, errors.New("truncated png chunk")
+		}
+
+		// Each chunk contains its length, type, data and CRC
+		end := offset + 12 + int(binary.BigEndian.Uint32(data[offset:]))
+		if end > len(data) || end < offset {
+			return nil, errors.New("truncated png chunk")
+		}
+
+		chunk := string(data[offset+4 : offset+8])
+
+		if !pngMetadata[chunk] {
+			stripped.Write(data[offset:end])
+		}
+
+		if chunk == "IEND" {
+			return stripped.Bytes(), nil
+		}
+
+		offset = end
+	}
+}
+
+// maxUploadAttempts number of times the images of a product are replaced when they are changed at the same time by other client
+const maxUploadAttempts = 3
+
+// UploadImage stores the image uploaded for the model.Product identified by the model.SKU and sets it as the principal image
+// or appends it to the other images. Returns the updated model.Product
+//
+// The image must not exceed the maximum size of the MediaConfig and its media type, detected from the content,
+// must be one of the upload content types. The metadata of the image is removed before storing it.
+// The image is only stored if the product is valid with it and only the images of the product are updated,
+// they are updated again if other client changed them at the same time
+func (s ProductStore) UploadImage(ctx context.Context, sku model.SKU, upload model.ImageUpload) (model.Product, error) {
+	if scheme, err := s.schemes().Match(sku); err != nil {
+		return model.Product{}, s.invalid("sku", RuleFormat, err.Error(), skuParams(scheme))
+	}
+
+	if s.Media == nil {
+		return model.Product{}, error2.NotImplemented("image uploads are not enabled")
+	}
+
+	// The product is obtained before reading the image to avoid reading images of products that do not exist
+	product, err := s.Obtain(ctx, sku)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	data, u, err := s.Media.prepare(ctx, upload.Content)
+	if err != nil {
+		s.logger().DebugContext(ctx, "invalid product image", "sku", sku, "filename", upload.Filename, "error", err)
+		return model.Product{}, err
+	}
+
+	stored := false
+
+	for attempt := 1; ; attempt++ {
+		change := model.ImageChange{SKU: sku, From: model.ImagesOf(product), To: model.ImagesOf(product)}
+
+		if upload.Principal {
+			change.To.PrincipalImage = &u
+		} else {
+			change.To.OtherImages = append(slices.Clone(change.To.OtherImages), u)
+		}
+
+		change.To.OtherImages = s.Images.dedupe(change.To.PrincipalImage, change.To.OtherImages)
+		product.PrincipalImage, product.OtherImages = change.To.PrincipalImage, change.To.OtherImages
+
+		_, span := startSpan(ctx, "validate product")
+		err = s.validateProductData(product, s.schemes().Match)
+		endSpan(span, err)
+
+		if err != nil {
+			s.logger().DebugContext(ctx, "invalid product data", "sku", sku, "error", err)
+			return model.Product{}, err
+		}
+
+		if !stored {
+			if _, err = s.Media.store(ctx, data); err != nil {
+				return model.Product{}, err
+			}
+
+			stored = true
+		}
+
+		err = s.Media.images.ReplaceImages(ctx, change)
+		if err == nil {
+			break
+		}
+
+		if attempt >= maxUploadAttempts || !errors.As(err, new(error2.Conflict)) {
+			return model.Product{}, err
+		}
+
+		if product, err = s.Obtain(ctx, sku); err != nil {
+			return model.Product{}, err
+		}
+	}
+
+	s.logger().InfoContext(ctx, "product image uploaded", "sku", sku, "filename", upload.Filename, "url", u.String(), "principal", upload.Principal)
+	return product, nil
+}
@@ -0,0 +1,290 @@
+package business
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"github.com/yael-castro/products-api/internal/repository"
+	"hash/crc32"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// withEXIF inserts an APP1 (EXIF) segment and a comment segment after the start of image of the JPEG image
+func withEXIF(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	segment := func(marker byte, payload string) []byte {
+		return append(binary.BigEndian.AppendUint16([]byte{0xFF, marker}, uint16(len(payload)+2)), payload...)
+	}
+
+	exif := "Exif\x00\x00GPS 19.4326N 99.1332W"
+	comment := "taken by somebody"
+
+	result := append([]byte{}, data[:2]...)
+	result = append(result, segment(0xE1, exif)...)
+	result = append(result, segment(0xFE, comment)...)
+
+	return append(result, data[2:]...)
+}
+
+// withText inserts a tEXt chunk after the IHDR chunk of the PNG image
+func withText(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	text := "Author\x00somebody"
+
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)))
+	chunk = append(chunk, "tEXt"+text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	// The IHDR chunk has 25 bytes (length, type, 13 bytes of data and CRC)
+	offset := len(pngSignature) + 25
+
+	return append(append(append([]byte{}, data[:offset]...), chunk...), data[offset:]...)
+}
+
+func TestProductStore_UploadImage(t *testing.T) {
+	pngImage, jpegImage, gifImage, largeImage := bytes.Buffer{}, bytes.Buffer{}, bytes.Buffer{}, bytes.Buffer{}
+
+	if err := png.Encode(&pngImage, testImage(64, 32)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := png.Encode(&largeImage, testImage(128, 64)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := jpeg.Encode(&jpegImage, testImage(64, 64), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gif.Encode(&gifImage, testImage(16, 16), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tdt := []struct {
+		sku             model.SKU
+		content         []byte
+		principal       bool
+		disabled        bool
+		expectedImages  int
+		expectedMissing []string
+		expectedErr     error
+	}{
+		{
+			sku:             "FAL-1000001",
+			content:         withText(t, pngImage.Bytes()),
+			expectedImages:  2,
+			expectedMissing: []string{"tEXt", "somebody"},
+		},
+		{
+			sku:             "FAL-1000001",
+			content:         withEXIF(t, jpegImage.Bytes()),
+			principal:       true,
+			expectedImages:  1,
+			expectedMissing: []string{"Exif", "GPS", "somebody"},
+		},
+		// The GIF images are not allowed by the MediaConfig
+		{
+			sku:         "FAL-1000001",
+			content:     gifImage.Bytes(),
+			expectedErr: error2.UnsupportedMediaType("media type 'image/gif' is not supported, the supported types are [image/png, image/jpeg]"),
+		},
+		// The media type is detected from the content
+		{
+			sku:         "FAL-1000001",
+			content:     []byte("<html><body>not an image</body></html>"),
+			expectedErr: error2.UnsupportedMediaType("media type 'text/html' is not supported, the supported types are [image/png, image/jpeg]"),
+		},
+		{
+			sku:         "FAL-1000001",
+			content:     append(append([]byte{}, pngImage.Bytes()...), make([]byte, 4096)...),
+			expectedErr: error2.TooLarge("the image exceeds the maximum of 4096 bytes"),
+		},
+		{
+			sku:         "FAL-1000001",
+			content:     pngImage.Bytes()[:40],
+			expectedErr: error2.Unprocessable("the image could not be decoded: truncated png chunk"),
+		},
+		{
+			sku:         "FAL-1000001",
+			content:     largeImage.Bytes(),
+			expectedErr: error2.TooLarge("the image has 128x64 pixels, the maximum is 4096 pixels"),
+		},
+		// The image is not stored if the product is not valid with it
+		{
+			sku:         "FAL-1000003",
+			content:     pngImage.Bytes(),
+			expectedErr: error2.Violations{{Field: "name", Reason: "product name must not be blank"}},
+		},
+		{
+			sku:         "FAL-1000002",
+			content:     pngImage.Bytes(),
+			expectedErr: error2.NotFound("product identified by sku 'FAL-1000002' does not exist"),
+		},
+		{
+			sku:         "FAL-1000001",
+			content:     pngImage.Bytes(),
+			disabled:    true,
+			expectedErr: error2.NotImplemented("image uploads are not enabled"),
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			principalImage := mustURL(t, "https://example.com/a.png")
+
+			storage := &repository.MockStorage[model.SKU, model.Product]{
+				"FAL-1000001": {SKU: "FAL-1000001", Name: "Shoes", Brand: "Nike", Price: 10, PrincipalImage: &principalImage},
+				"FAL-1000003": {SKU: "FAL-1000003", Brand: "Nike", Price: 10, PrincipalImage: &principalImage},
+			}
+
+			blobs := &repository.MockBlobs{}
+
+			config := MediaConfig{
+				BaseURL:            "https://api.example.com",
+				Workers:            1,
+				MaxSize:            4096,
+				MaxPixels:          4096,
+				UploadContentTypes: []string{"image/png", "image/jpeg"},
+			}
+
+			ingester, err := NewImageIngester(config, storage, &repository.MockImages{Storage: storage}, blobs, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer func() { _ = ingester.Close(context.Background()) }()
+
+			store := ProductStore{StorageManager: storage, Media: ingester}
+			if v.disabled {
+				store.Media = nil
+			}
+
+			upload := model.ImageUpload{Filename: "image", Content: bytes.NewReader(v.content), Principal: v.principal}
+
+			product, err := store.UploadImage(context.Background(), v.sku, upload)
+			if !matchError(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			if err != nil {
+				if keys := blobs.Keys(); len(keys) > 0 {
+					t.Fatalf("unexpected keys '%v'", keys)
+				}
+
+				t.Skip(err)
+			}
+
+			if images := len(productImages(product)); images != v.expectedImages {
+				t.Fatalf("expected %d images unexpected %d images", v.expectedImages, images)
+			}
+
+			uploaded := *product.PrincipalImage
+			if !v.principal {
+				uploaded = product.OtherImages[len(product.OtherImages)-1]
+			}
+
+			if !ingester.owns(uploaded) {
+				t.Fatalf("unexpected image URL '%s'", uploaded)
+			}
+
+			if stored, _ := storage.Obtain(context.Background(), v.sku); stored.OtherImages.String() != product.OtherImages.String() {
+				t.Fatalf("expected stored images '%s' unexpected images '%s'", product.OtherImages, stored.OtherImages)
+			}
+
+			blob, err := blobs.Obtain(context.Background(), strings.TrimPrefix(uploaded.Path, MediaPath))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := io.ReadAll(blob.Content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, metadata := range v.expectedMissing {
+				if bytes.Contains(data, []byte(metadata)) {
+					t.Fatalf("unexpected metadata '%s' in the stored image", metadata)
+				}
+			}
+
+			t.Log(product)
+		})
+	}
+}
+
+// racingImages changes the product saved into the storage before the first replacement of its images,
+// as another client would do at the same time
+type racingImages struct {
+	*repository.MockImages
+	change func(*model.Product)
+}
+
+// ReplaceImages changes the product once and replaces its images
+func (r *racingImages) ReplaceImages(ctx context.Context, change model.ImageChange) error {
+	if r.change != nil {
+		product := (*r.MockImages.Storage)[change.SKU]
+		r.change(&product)
+		(*r.MockImages.Storage)[change.SKU] = product
+		r.change = nil
+	}
+
+	return r.MockImages.ReplaceImages(ctx, change)
+}
+
+func TestProductStore_UploadImage_concurrent(t *testing.T) {
+	pngImage := bytes.Buffer{}
+
+	if err := png.Encode(&pngImage, testImage(64, 32)); err != nil {
+		t.Fatal(err)
+	}
+
+	principalImage, otherImage := mustURL(t, "https://example.com/a.png"), mustURL(t, "https://example.com/b.png")
+
+	storage := &repository.MockStorage[model.SKU, model.Product]{
+		"FAL-1000001": {SKU: "FAL-1000001", Name: "Shoes", Brand: "Nike", Price: 10, PrincipalImage: &principalImage},
+	}
+
+	// Other client uploads an image and changes the price while the image is uploaded
+	images := &racingImages{
+		MockImages: &repository.MockImages{Storage: storage},
+		change: func(product *model.Product) {
+			product.OtherImages = append(product.OtherImages, otherImage)
+			product.Price = 20
+		},
+	}
+
+	ingester, err := NewImageIngester(MediaConfig{BaseURL: "https://api.example.com", Workers: 1}, storage, images, &repository.MockBlobs{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = ingester.Close(context.Background()) }()
+
+	store := ProductStore{StorageManager: storage, Media: ingester}
+
+	upload := model.ImageUpload{Filename: "image", Content: bytes.NewReader(pngImage.Bytes())}
+
+	if _, err = store.UploadImage(context.Background(), "FAL-1000001", upload); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := storage.Obtain(context.Background(), "FAL-1000001")
+
+	if len(stored.OtherImages) != 2 || stored.OtherImages[0].String() != otherImage.String() || !ingester.owns(stored.OtherImages[1]) {
+		t.Fatalf("unexpected images '%s'", stored.OtherImages)
+	}
+
+	if stored.Price != 20 {
+		t.Fatalf("expected price 20 unexpected price %v", stored.Price)
+	}
+}
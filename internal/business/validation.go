@@ -0,0 +1,125 @@
+package business
+
+import (
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"strings"
+	"unicode"
+)
+
+// Identifiers of the validation rules, they are included in every error2.Violation
+const (
+	RuleRequired  = "required"
+	RuleNotBlank  = "notBlank"
+	RuleMinLength = "minLength"
+	RuleMaxLength = "maxLength"
+	RuleRange     = "range"
+	RuleFormat    = "format"
+	RulePattern   = "pattern"
+	RuleEnum      = "enum"
+	RuleURL       = "url"
+	RuleMinItems  = "minItems"
+	RuleMaxItems  = "maxItems"
+	RuleHost      = "host"
+	RuleReachable = "reachable"
+)
+
+// Limits of the product fields used by the DefaultRuleSet
+const (
+	minTextLength = 3
+	maxTextLength = 50
+	minPrice      = 1.0
+	maxPrice      = 99_999_999.00
+)
+
+// skuParams parameters of the rule RuleFormat for the model.SKU of the model.SKUScheme
+func skuParams(scheme model.SKUScheme) error2.RuleParams {
+	min, max := scheme.Bounds()
+	params := error2.RuleParams{"prefix": scheme.Prefix, "min": min, "max": max}
+
+	if scheme.Digits > 0 {
+		params["digits"] = scheme.Digits
+	}
+
+	if scheme.CheckDigit != model.CheckDigitNone {
+		params["checkDigit"] = scheme.CheckDigit
+	}
+
+	return params
+}
+
+// skuCheck validates the model.SKU of the product data, it returns the model.SKUScheme used to report the violation
+type skuCheck func(model.SKU) (model.SKUScheme, error)
+
+// violations collects the broken validation rules and records each one using the *Metrics
+type violations struct {
+	error2.Violations
+	metrics *Metrics
+}
+
+// add records the broken rule of the value located by the JSON pointer
+func (v *violations) add(pointer, rule, reason string, params error2.RuleParams) {
+	violation := error2.Violated(pointer, rule, reason, params)
+
+	v.metrics.validationFailed(ruleLabel(violation.Field, rule))
+	v.Violations = append(v.Violations, violation)
+}
+
+// err returns the collected error2.Violations, if none rule was broken returns nil
+func (v *violations) err() error {
+	if len(v.Violations) == 0 {
+		return nil
+	}
+
+	return v.Violations
+}
+
+// validateProductData validates the model.SKU using the skuCheck and every field of the model.Product, including the nested
+// image URLs, against the rules of the *RuleEngine and the ImagePolicy.
+// All broken rules are collected and returned as error2.Violations, if the model.Product is valid returns nil
+func (s ProductStore) validateProductData(product model.Product, check skuCheck) error {
+	v := &violations{metrics: s.Metrics}
+
+	if scheme, err := check(product.SKU); err != nil {
+		v.add("/sku", RuleFormat, err.Error(), skuParams(scheme))
+	}
+
+	for _, violation := range s.rules().Validate(product) {
+		v.add(violation.Pointer, violation.Rule, violation.Reason, violation.Params)
+	}
+
+	s.Images.check(product, v, s.Media.owns)
+
+	return v.err()
+}
+
+// invalid records the broken validation rule of a value that is not part of the product data
+// (e.g. the SKU received as path parameter) and returns the error2.Violations of the field
+func (s ProductStore) invalid(field, rule, message string, params error2.RuleParams) error {
+	s.Metrics.validationFailed(ruleLabel(field, rule))
+	return error2.Violations{{Field: field, Rule: rule, Params: params, Reason: message}}
+}
+
+// ruleLabel label used by the metrics for the rule broken by the field, e.g. "name_min_length"
+func ruleLabel(field, rule string) string {
+	return snakeCase(field) + "_" + snakeCase(rule)
+}
+
+// snakeCase converts camel case identifiers into snake case, e.g. "principalImage" is "principal_image"
+func snakeCase(s string) string {
+	b := strings.Builder{}
+
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package business
+
+import (
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestProductStore_validateProductData(t *testing.T) {
+	blank := " "
+	image := model.URL{URL: &url.URL{Scheme: "https", Host: "example.com"}}
+
+	tdt := []struct {
+		product            model.Product
+		expectedViolations error2.Violations
+	}{
+		{
+			product: model.Product{
+				SKU:            "FAL-1234567",
+				Name:           "Shoes",
+				Brand:          "Nike",
+				Price:          10,
+				PrincipalImage: &image,
+				OtherImages:    []model.URL{image},
+			},
+		},
+		{
+			product: model.Product{
+				SKU:   "FAL-1",
+				Name:  "Sh",
+				Size:  &blank,
+				Price: 0,
+				OtherImages: []model.URL{
+					image,
+					{URL: &url.URL{Path: "image.png"}},
+					{URL: &url.URL{Scheme: "ftp", Host: "example.com"}},
+				},
+			},
+			expectedViolations: error2.Violations{
+				{Field: "sku", Pointer: "/sku", Rule: RuleFormat, Params: skuParams(model.DefaultSKUScheme), Reason: "invalid suffix '1'"},
+				{Field: "name", Pointer: "/name", Rule: RuleMinLength, Params: error2.RuleParams{"min": float64(minTextLength)}, Reason: "product name is too short"},
+				{Field: "brand", Pointer: "/brand", Rule: RuleRequired, Reason: "product brand must not be blank"},
+				{Field: "size", Pointer: "/size", Rule: RuleNotBlank, Reason: "product size must not be blank"},
+				{Field: "price", Pointer: "/price", Rule: RuleRange, Params: error2.RuleParams{"min": minPrice, "max": maxPrice}, Reason: "invalid product price"},
+				{Field: "principalImage", Pointer: "/principalImage", Rule: RuleRequired, Reason: "principal image for product is required"},
+				{Field: "otherImages", Pointer: "/otherImages/1", Rule: RuleURL, Params: error2.RuleParams{"schemes": defaultImageSchemes}, Reason: "image 1 must be an absolute URL with scheme [http, https]"},
+				{Field: "otherImages", Pointer: "/otherImages/2", Rule: RuleURL, Params: error2.RuleParams{"schemes": defaultImageSchemes}, Reason: "image 2 must be an absolute URL with scheme [http, https]"},
+			},
+		},
+	}
+
+	store := ProductStore{}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := store.validateProductData(v.product, store.schemes().Match)
+			if v.expectedViolations == nil {
+				if err != nil {
+					t.Fatalf("unexpected error '%v'", err)
+				}
+
+				t.Skip("SUCCESS")
+			}
+
+			violations, ok := err.(error2.Violations)
+			if !ok {
+				t.Fatalf("expected violations unexpected error '%v'", err)
+			}
+
+			if !reflect.DeepEqual(v.expectedViolations, violations) {
+				t.Fatalf("expected violations '%+v' unexpected violations '%+v'", v.expectedViolations, violations)
+			}
+		})
+	}
+}
@@ -2,13 +2,34 @@
 package dependency
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	productsapi "github.com/yael-castro/products-api"
 	"github.com/yael-castro/products-api/internal/business"
 	"github.com/yael-castro/products-api/internal/handler"
+	"github.com/yael-castro/products-api/internal/handler/productpb"
+	"github.com/yael-castro/products-api/internal/logging"
+	"github.com/yael-castro/products-api/internal/model"
 	"github.com/yael-castro/products-api/internal/repository"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Profile defines options of dependency injection
@@ -50,11 +71,14 @@ func NewInjector(p Profile) Injector {
 	panic(fmt.Sprintf(`invalid profile: "%d" is not supported`, p))
 }
 
-// handlerDefault InjectorFunc for *handler.Groups that uses a Default Profile
+// handlerDefault InjectorFunc for *Application that uses a Default Profile
+//
+// The resources opened during the injection are registered in the *Application to be closed during the shutdown,
+// so even if the injection fails the *Application must be closed
 func handlerDefault(a any) error {
-	h, ok := a.(*http.Handler)
+	app, ok := a.(*Application)
 	if !ok {
-		return fmt.Errorf(`an instance of "%T" is required not "%T"`, h, a)
+		return fmt.Errorf(`an instance of "%T" is required not "%T"`, app, a)
 	}
 
 	gormDSN := os.Getenv("GORM_DSN")
@@ -62,21 +86,648 @@ func handlerDefault(a any) error {
 		return errors.New("missing environment variable GORM_DSN")
 	}
 
-	db, err := repository.NewGormDB(gormDSN)
+	logger, err := loggerDefault()
+	if err != nil {
+		return err
+	}
+
+	tracerProvider, shutdownTracerProvider, err := tracerProviderDefault()
+	if err != nil {
+		return err
+	}
+
+	app.OnClose("tracer provider", shutdownTracerProvider)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	db, err := repository.NewGormDB(gormDSN, &gorm.Config{Logger: repository.NewGormLogger(logger)})
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	app.OnClose("database connection pool", func(context.Context) error {
+		return sqlDB.Close()
+	})
+
+	gormMetrics, err := repository.NewGormMetrics(registry)
+	if err != nil {
+		return err
+	}
+
+	if err = db.Use(gormMetrics); err != nil {
+		return err
+	}
+
+	if err = db.Use(repository.GormTracing{Tracer: tracerProvider.Tracer(repository.InstrumentationName)}); err != nil {
+		return err
+	}
+
+	if err = registry.Register(collectors.NewDBStatsCollector(sqlDB, "products")); err != nil {
+		return err
+	}
+
+	businessMetrics, err := business.NewMetrics(registry)
+	if err != nil {
+		return err
+	}
+
+	httpMetrics, err := handler.Metrics(registry)
+	if err != nil {
+		return err
+	}
+
+	unaryMetrics, streamMetrics, err := handler.GRPCMetrics(registry)
 	if err != nil {
 		return err
 	}
 
 	groups := handler.Groups{}
 
-	groups.ProductManager = handler.ProductStore{
-		ProductManager: business.ProductStore{
-			StorageManager: repository.ProductStore{
-				DB: db,
+	policies, err := policiesDefault(logger)
+	if err != nil {
+		return err
+	}
+
+	maxBatchSize, err := intDefault("PRODUCT_BATCH_MAX_SIZE")
+	if err != nil {
+		return err
+	}
+
+	rules, err := rulesDefault(app, logger)
+	if err != nil {
+		return err
+	}
+
+	skuSchemes, err := skuSchemesDefault(logger)
+	if err != nil {
+		return err
+	}
+
+	sequences := repository.Sequences{DB: db}
+
+	maxReservationSize, err := intDefault("SKU_RESERVATION_MAX_SIZE")
+	if err != nil {
+		return err
+	}
+
+	imagePolicy, err := imagePolicyDefault(logger)
+	if err != nil {
+		return err
+	}
+
+	priceApproval, err := priceApprovalDefault(logger)
+	if err != nil {
+		return err
+	}
+
+	var storage repository.StorageManager[model.SKU, model.Product] = repository.StorageTracing[model.SKU, model.Product]{
+		StorageManager: repository.ProductStore{
+			DB: db,
+		},
+		Tracer: tracerProvider.Tracer(repository.InstrumentationName),
+	}
+
+	media, err := mediaDefault(app, logger, storage, repository.Images{DB: db}, imagePolicy.Client())
+	if err != nil {
+		return err
+	}
+
+	duplicates, err := duplicatesDefault(app, logger, storage)
+	if err != nil {
+		return err
+	}
+
+	var manager business.ProductManager = business.ProductTracing{
+		ProductManager: business.ProductMetrics{
+			ProductManager: business.ProductPolicy{
+				ProductManager: business.ProductStore{
+					StorageManager:     storage,
+					Logger:             logger,
+					Metrics:            businessMetrics,
+					MaxBatchSize:       maxBatchSize,
+					Rules:              rules,
+					SKUSchemes:         skuSchemes,
+					Sequences:          sequences,
+					MaxReservationSize: maxReservationSize,
+					Images:             imagePolicy,
+					ImageClient:        imagePolicy.Client(),
+					Media:              media,
+					Lifecycle:          repository.Lifecycle{DB: db},
+					Approvals:          repository.Approvals{DB: db},
+					PriceApproval:      priceApproval,
+					Duplicates:         duplicates,
+					Finder:             repository.ProductStore{DB: db},
+				},
+				Policies: policies,
 			},
+			Metrics: businessMetrics,
+		},
+		Tracer: tracerProvider.Tracer(business.InstrumentationName),
+	}
+
+	var maxUploadSize int64
+	if media != nil {
+		maxUploadSize = media.MaxSize()
+	}
+
+	groups.ProductManager = handler.ProductStore{ProductManager: manager, MaxUploadSize: maxUploadSize}
+	groups.MediaServer = handler.MediaStore{MediaManager: media}
+
+	health := repository.Health{DB: db, Sequences: make([]string, 0, len(skuSchemes.Schemes))}
+	for _, scheme := range skuSchemes.Schemes {
+		health.Sequences = append(health.Sequences, scheme.Name)
+	}
+
+	checkTimeout, err := durationDefault("HEALTH_CHECK_TIMEOUT")
+	if err != nil {
+		return err
+	}
+
+	probes := &handler.Health{
+		Checks: map[string]handler.Check{
+			"database":   health.Ping,
+			"migrations": health.Migrations,
 		},
+		Timeout: checkTimeout,
+		Logger:  logger,
 	}
 
-	*h = handler.NewHttpHandler(groups)
+	app.OnDrain(probes.Drain)
+
+	graphQLConfig, err := graphQLDefault()
+	if err != nil {
+		return err
+	}
+
+	groups.GraphQLExecutor, err = handler.NewGraphQLSchema(manager, graphQLConfig)
+	if err != nil {
+		return err
+	}
+
+	doc, err := handler.ParseOpenAPI(productsapi.OpenAPI)
+	if err != nil {
+		return err
+	}
+
+	groups.Documentation = handler.NewDocs(doc)
+
+	groups.Monitor = handler.Monitoring{
+		Gatherer: registry,
+		Health:   probes,
+	}
+
+	authenticator, err := authenticatorDefault(logger)
+	if err != nil {
+		return err
+	}
+
+	rateLimitConfig, err := rateLimitDefault(logger)
+	if err != nil {
+		return err
+	}
+
+	rateLimitStore := handler.NewMemoryRateLimitStore()
+
+	middlewares := []gin.HandlerFunc{
+		handler.RequestID(),
+		handler.Tracing(tracerProvider.Tracer(handler.InstrumentationName), otel.GetTextMapPropagator()),
+		httpMetrics,
+		handler.Logger(logger, splitList(os.Getenv("LOG_REDACTED_HEADERS"))...),
+		handler.Recovery(logger),
+		handler.AuthenticationRateLimit(rateLimitStore, rateLimitConfig.Authentication),
+		handler.Authentication(authenticator, "/graphql"),
+		handler.RateLimit(rateLimitStore, rateLimitConfig),
+		openAPIValidatorDefault(doc),
+	}
+
+	app.Handler = handler.NewHttpHandler(groups, middlewares...)
+
+	unaryLogger, streamLogger := handler.GRPCLogger(logger)
+	unaryAuthenticationRateLimit, streamAuthenticationRateLimit := handler.GRPCAuthenticationRateLimit(rateLimitStore, rateLimitConfig.Authentication)
+	unaryAuthentication, streamAuthentication := handler.GRPCAuthentication(authenticator)
+	unaryRateLimit, streamRateLimit := handler.GRPCRateLimit(rateLimitStore, rateLimitConfig)
+
+	app.GRPCServer = grpcServerDefault(
+		app,
+		manager,
+		grpc.StatsHandler(otelgrpc.NewServerHandler(
+			otelgrpc.WithTracerProvider(tracerProvider),
+			otelgrpc.WithPropagators(otel.GetTextMapPropagator()),
+		)),
+		grpc.ChainUnaryInterceptor(unaryLogger, unaryMetrics, unaryAuthenticationRateLimit, unaryAuthentication, unaryRateLimit),
+		grpc.ChainStreamInterceptor(streamLogger, streamMetrics, streamAuthenticationRateLimit, streamAuthentication, streamRateLimit),
+	)
 	return nil
 }
+
+// grpcServerDefault builds the *grpc.Server with the products.v1.ProductService, the health service and the server reflection,
+// the options set the tracing and the interceptors shared with the HTTP server (logging, metrics, authentication and rate limiting)
+//
+// The health service reports NOT_SERVING since the server starts draining
+func grpcServerDefault(app *Application, manager business.ProductManager, options ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(options...)
+
+	productpb.RegisterProductServiceServer(server, handler.ProductService{ProductManager: manager})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(productpb.ProductService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	app.OnDrain(healthServer.Shutdown)
+
+	reflection.Register(server)
+
+	return server
+}
+
+// loggerDefault builds the *slog.Logger based on the environment variable LOG_LEVEL and sets it as default *slog.Logger,
+// that way the log records written by the standard log package are also structured
+func loggerDefault() (*slog.Logger, error) {
+	level, err := logging.ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid environment variable LOG_LEVEL: %w", err)
+	}
+
+	logger := logging.NewLogger(os.Stdout, level)
+	slog.SetDefault(logger)
+
+	return logger, nil
+}
+
+// authenticatorDefault builds the *handler.Authenticator based on the file indicated by the environment variable AUTH_CONFIG_FILE
+//
+// If the environment variable is missing, no credentials are accepted, so only the safe methods are allowed
+func authenticatorDefault(logger *slog.Logger) (*handler.Authenticator, error) {
+	config := handler.AuthConfig{}
+
+	path := os.Getenv("AUTH_CONFIG_FILE")
+	if path == "" {
+		logger.Warn("missing environment variable AUTH_CONFIG_FILE, every request that requires authentication will be rejected")
+		return handler.NewAuthenticator(config)
+	}
+
+	if err := loadConfig(path, &config); err != nil {
+		return nil, err
+	}
+
+	return handler.NewAuthenticator(config)
+}
+
+// policiesDefault loads the business.Policies from the file indicated by the environment variable POLICIES_FILE
+//
+// If the environment variable is missing, every authenticated client is allowed to perform any operation
+func policiesDefault(logger *slog.Logger) (business.Policies, error) {
+	policies := business.Policies{}
+
+	path := os.Getenv("POLICIES_FILE")
+	if path == "" {
+		logger.Warn("missing environment variable POLICIES_FILE, every authenticated client is allowed to perform any operation")
+		return policies, nil
+	}
+
+	if err := loadConfig(path, &policies); err != nil {
+		return policies, err
+	}
+
+	return policies, policies.Validate()
+}
+
+// defaultRulesReloadInterval default interval to check the changes of the validation rules file
+const defaultRulesReloadInterval = 10 * time.Second
+
+// rulesDefault builds the *business.RuleEngine based on the file indicated by the environment variable RULES_FILE,
+// the file is checked every RULES_RELOAD_INTERVAL and the rules are reloaded when it changes.
+// If the changed file is invalid, the previous rules are kept
+//
+// If the environment variable is missing, the business.DefaultRuleSet is used
+func rulesDefault(app *Application, logger *slog.Logger) (*business.RuleEngine, error) {
+	path := os.Getenv("RULES_FILE")
+	if path == "" {
+		logger.Info("missing environment variable RULES_FILE, the default validation rules are used")
+		return nil, nil
+	}
+
+	interval, err := durationDefault("RULES_RELOAD_INTERVAL")
+	if err != nil {
+		return nil, err
+	}
+
+	if interval <= 0 {
+		interval = defaultRulesReloadInterval
+	}
+
+	set := business.RuleSet{}
+	if err = loadConfig(path, &set); err != nil {
+		return nil, err
+	}
+
+	engine, err := business.NewRuleEngine(set)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid configuration file '%s': %w`, path, err)
+	}
+
+	watch(app, "validation rules watcher", path, interval, func() {
+		set := business.RuleSet{}
+
+		err := loadConfig(path, &set)
+		if err == nil {
+			err = engine.Reload(set)
+		}
+
+		if err != nil {
+			logger.Error("validation rules were not reloaded, the previous rules are kept", "file", path, "error", err)
+			return
+		}
+
+		logger.Info("validation rules reloaded", "file", path)
+	})
+
+	return engine, nil
+}
+
+// skuSchemesDefault loads the model.SKUSchemes from the file indicated by the environment variable SKU_SCHEMES_FILE
+//
+// If the environment variable is missing, the model.DefaultSKUSchemes is used
+func skuSchemesDefault(logger *slog.Logger) (model.SKUSchemes, error) {
+	path := os.Getenv("SKU_SCHEMES_FILE")
+	if path == "" {
+		logger.Info("missing environment variable SKU_SCHEMES_FILE, the default sku scheme is used")
+		return model.DefaultSKUSchemes, nil
+	}
+
+	schemes := model.SKUSchemes{}
+	if err := loadConfig(path, &schemes); err != nil {
+		return schemes, err
+	}
+
+	if err := schemes.Check(); err != nil {
+		return schemes, fmt.Errorf(`invalid configuration file '%s': %w`, path, err)
+	}
+
+	return schemes, nil
+}
+
+// imagePolicyDefault loads the business.ImagePolicy from the file indicated by the environment variable IMAGE_POLICY_FILE
+//
+// If the environment variable is missing, the http and https image URLs of any host are allowed and they are not verified
+func imagePolicyDefault(logger *slog.Logger) (business.ImagePolicy, error) {
+	policy := business.ImagePolicy{}
+
+	path := os.Getenv("IMAGE_POLICY_FILE")
+	if path == "" {
+		logger.Info("missing environment variable IMAGE_POLICY_FILE, the image URLs of any host are allowed and they are not verified")
+		return policy, nil
+	}
+
+	if err := loadConfig(path, &policy); err != nil {
+		return policy, err
+	}
+
+	if err := policy.Validate(); err != nil {
+		return policy, fmt.Errorf(`invalid configuration file '%s': %w`, path, err)
+	}
+
+	return policy, nil
+}
+
+// mediaDefault builds the *business.ImageIngester that stores the product images into the directory indicated by the
+// environment variable MEDIA_DIR, the media URLs are built using MEDIA_BASE_URL (required along with MEDIA_DIR).
+// The pool of MEDIA_WORKERS workers processes the products waiting in a queue of MEDIA_QUEUE_SIZE products, the images
+// larger than MEDIA_MAX_SIZE bytes or slower than MEDIA_DOWNLOAD_TIMEOUT are not ingested and a thumbnail is made for each
+// width of MEDIA_THUMBNAIL_WIDTHS (comma separated list). The images with more than MEDIA_MAX_PIXELS pixels are not decoded.
+// The images are downloaded using the *http.Client of the image policy. The products in the queue are processed before shutting down
+//
+// If the environment variable MEDIA_DIR is missing, the image URLs are kept as they are received
+func mediaDefault(
+	app *Application,
+	logger *slog.Logger,
+	storage repository.StorageManager[model.SKU, model.Product],
+	images repository.ImageManager,
+	client *http.Client,
+) (*business.ImageIngester, error) {
+	dir := os.Getenv("MEDIA_DIR")
+	if dir == "" {
+		logger.Info("missing environment variable MEDIA_DIR, the product images are not ingested and the image uploads are not enabled")
+		return nil, nil
+	}
+
+	config := business.MediaConfig{BaseURL: os.Getenv("MEDIA_BASE_URL")}
+
+	var err error
+
+	if config.Workers, err = intDefault("MEDIA_WORKERS"); err != nil {
+		return nil, err
+	}
+
+	if config.QueueSize, err = intDefault("MEDIA_QUEUE_SIZE"); err != nil {
+		return nil, err
+	}
+
+	maxSize, err := intDefault("MEDIA_MAX_SIZE")
+	if err != nil {
+		return nil, err
+	}
+
+	config.MaxSize = int64(maxSize)
+
+	maxPixels, err := intDefault("MEDIA_MAX_PIXELS")
+	if err != nil {
+		return nil, err
+	}
+
+	config.MaxPixels = int64(maxPixels)
+
+	if config.Timeout, err = durationDefault("MEDIA_DOWNLOAD_TIMEOUT"); err != nil {
+		return nil, err
+	}
+
+	for _, item := range splitList(os.Getenv("MEDIA_THUMBNAIL_WIDTHS")) {
+		width, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid environment variable MEDIA_THUMBNAIL_WIDTHS: %w", err)
+		}
+
+		config.ThumbnailWidths = append(config.ThumbnailWidths, width)
+	}
+
+	config.UploadContentTypes = splitList(os.Getenv("MEDIA_UPLOAD_CONTENT_TYPES"))
+
+	ingester, err := business.NewImageIngester(config, storage, images, repository.FileBlobs{Root: dir}, client, logger)
+	if err != nil {
+		return nil, fmt.Errorf("invalid environment variable MEDIA_BASE_URL, MEDIA_THUMBNAIL_WIDTHS or MEDIA_UPLOAD_CONTENT_TYPES: %w", err)
+	}
+
+	app.OnClose("image ingester", ingester.Close)
+
+	return ingester, nil
+}
+
+// duplicatesDefault builds the *business.DuplicateDetector that considers duplicates the products of the same brand whose
+// name similarity is at least DUPLICATE_THRESHOLD (from 0 to 1), if it is missing business.DefaultDuplicateThreshold is used.
+// The running duplicate report is completed before shutting down
+func duplicatesDefault(app *Application, logger *slog.Logger, storage repository.StorageManager[model.SKU, model.Product]) (*business.DuplicateDetector, error) {
+	threshold, err := floatDefault("DUPLICATE_THRESHOLD")
+	if err != nil {
+		return nil, err
+	}
+
+	detector, err := business.NewDuplicateDetector(threshold, storage, logger)
+	if err != nil {
+		return nil, fmt.Errorf("invalid environment variable DUPLICATE_THRESHOLD: %w", err)
+	}
+
+	app.OnClose("duplicate report", detector.Close)
+
+	return detector, nil
+}
+
+// priceApprovalDefault builds the business.PriceApproval based on the environment variables PRICE_APPROVAL_PERCENTAGE and
+// PRICE_APPROVAL_AMOUNT, the price changes bigger than any of them are held until they are approved
+//
+// If both environment variables are missing, the prices are changed without approval
+func priceApprovalDefault(logger *slog.Logger) (approval business.PriceApproval, err error) {
+	if approval.Percentage, err = floatDefault("PRICE_APPROVAL_PERCENTAGE"); err != nil {
+		return
+	}
+
+	if approval.Amount, err = floatDefault("PRICE_APPROVAL_AMOUNT"); err != nil {
+		return
+	}
+
+	if err = approval.Validate(); err != nil {
+		return approval, fmt.Errorf("invalid environment variable PRICE_APPROVAL_PERCENTAGE or PRICE_APPROVAL_AMOUNT: %w", err)
+	}
+
+	if approval == (business.PriceApproval{}) {
+		logger.Info("missing environment variables PRICE_APPROVAL_PERCENTAGE and PRICE_APPROVAL_AMOUNT, the prices are changed without approval")
+	}
+
+	return approval, nil
+}
+
+// rateLimitDefault loads the handler.RateLimitConfig from the file indicated by the environment variable RATE_LIMIT_FILE
+//
+// If the environment variable is missing, the requests are not limited
+func rateLimitDefault(logger *slog.Logger) (handler.RateLimitConfig, error) {
+	config := handler.RateLimitConfig{}
+
+	path := os.Getenv("RATE_LIMIT_FILE")
+	if path == "" {
+		logger.Warn("missing environment variable RATE_LIMIT_FILE, the requests are not limited")
+		return config, nil
+	}
+
+	return config, loadConfig(path, &config)
+}
+
+// graphQLDefault builds the handler.GraphQLConfig based on the environment variables GRAPHQL_MAX_DEPTH,
+// GRAPHQL_MAX_COMPLEXITY and GRAPHQL_MAX_PAGE_SIZE, the missing ones take the default values
+func graphQLDefault() (config handler.GraphQLConfig, err error) {
+	limits := map[string]*int{
+		"GRAPHQL_MAX_DEPTH":      &config.MaxDepth,
+		"GRAPHQL_MAX_COMPLEXITY": &config.MaxComplexity,
+		"GRAPHQL_MAX_PAGE_SIZE":  &config.MaxPageSize,
+	}
+
+	for name, limit := range limits {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+
+		if *limit, err = strconv.Atoi(value); err != nil {
+			return config, fmt.Errorf("invalid environment variable %s: %w", name, err)
+		}
+	}
+
+	return config, nil
+}
+
+// openAPIValidatorDefault builds the handler.OpenAPIValidator based on the embedded OpenAPI document
+//
+// The responses are validated only if the environment variable OPENAPI_VALIDATE_RESPONSES is "true" and gin runs in test mode
+func openAPIValidatorDefault(doc *openapi3.T) gin.HandlerFunc {
+	options := handler.OpenAPIOptions{
+		ValidateResponses: gin.Mode() == gin.TestMode && os.Getenv("OPENAPI_VALIDATE_RESPONSES") == "true",
+	}
+
+	return handler.OpenAPIValidator(doc, options)
+}
+
+// loadConfig decodes the YAML (or JSON) configuration file into v
+func loadConfig(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err = yaml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf(`invalid configuration file '%s': %w`, path, err)
+	}
+
+	return nil
+}
+
+// durationDefault parses the duration in the environment variable, if it is missing returns zero
+func durationDefault(name string) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid environment variable %s: %w", name, err)
+	}
+
+	return d, nil
+}
+
+// intDefault reads the integer from the environment variable, if the variable is missing returns zero
+func intDefault(name string) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid environment variable %s: %w", name, err)
+	}
+
+	return i, nil
+}
+
+// floatDefault reads the number from the environment variable, if the variable is missing returns zero
+func floatDefault(name string) (float64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid environment variable %s: %w", name, err)
+	}
+
+	return f, nil
+}
+
+// splitList splits a comma separated list ignoring the blank items
+func splitList(list string) []string {
+	items := make([]string, 0)
+
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
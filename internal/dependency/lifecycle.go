@@ -0,0 +1,82 @@
+package dependency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"net/http"
+	"sync"
+)
+
+// Closer releases a resource (e.g. a connection pool or a background worker), it must return before ctx is done
+type Closer func(ctx context.Context) error
+
+// namedCloser Closer along with the name of the resource, which is used in the errors
+type namedCloser struct {
+	name  string
+	close Closer
+}
+
+// Application is the http.Handler along with the hooks required to manage its lifecycle
+//
+// The dependencies that hold resources or run background workers register their hooks during the dependency injection,
+// the hooks are called by the server during the shutdown
+type Application struct {
+	// Handler main http.Handler of the server
+	Handler http.Handler
+	// GRPCServer gRPC server, it runs on its own port
+	GRPCServer *grpc.Server
+
+	mutex    sync.Mutex
+	drainers []func()
+	closers  []namedCloser
+}
+
+// OnDrain registers a function called when the shutdown starts, before the server stops accepting connections
+func (a *Application) OnDrain(f func()) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.drainers = append(a.drainers, f)
+}
+
+// OnClose registers a Closer called after the server stopped, the Closers are called in reverse order of registration
+// so every resource is closed before the resources it depends on
+func (a *Application) OnClose(name string, c Closer) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.closers = append(a.closers, namedCloser{name: name, close: c})
+}
+
+// Drain calls the functions registered by OnDrain
+func (a *Application) Drain() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, drain := range a.drainers {
+		drain()
+	}
+}
+
+// Close calls every Closer registered by OnClose in reverse order, even if some of them fails,
+// the returned error joins the errors of every failed Closer
+//
+// The Closers are called at most once
+func (a *Application) Close(ctx context.Context) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	errs := make([]error, 0)
+
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		if err := a.closers[i].close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("closing %s: %w", a.closers[i].name, err))
+		}
+	}
+
+	a.closers = nil
+
+	return errors.Join(errs...)
+}
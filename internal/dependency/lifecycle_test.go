@@ -0,0 +1,65 @@
+package dependency
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestApplication_Close(t *testing.T) {
+	tdt := []struct {
+		closers       []string
+		failing       map[string]bool
+		expectedOrder []string
+		expectedError string
+	}{
+		{
+			closers:       []string{"database", "tracer", "worker"},
+			expectedOrder: []string{"worker", "tracer", "database"},
+		},
+		// Every Closer is called even if some of them fails
+		{
+			closers:       []string{"database", "tracer", "worker"},
+			failing:       map[string]bool{"tracer": true, "worker": true},
+			expectedOrder: []string{"worker", "tracer", "database"},
+			expectedError: "closing worker: failure\nclosing tracer: failure",
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			app := &Application{}
+			order := make([]string, 0)
+
+			for _, name := range v.closers {
+				name := name
+
+				app.OnClose(name, func(context.Context) error {
+					order = append(order, name)
+
+					if v.failing[name] {
+						return errors.New("failure")
+					}
+
+					return nil
+				})
+			}
+
+			err := app.Close(context.Background())
+			if (err == nil && v.expectedError != "") || (err != nil && err.Error() != v.expectedError) {
+				t.Fatalf(`expected error '%s' got '%v'`, v.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(order, v.expectedOrder) {
+				t.Fatalf(`expected order %v got %v`, v.expectedOrder, order)
+			}
+
+			// The Closers are called at most once
+			if err = app.Close(context.Background()); err != nil || len(order) != len(v.expectedOrder) {
+				t.Fatal("closers called more than once")
+			}
+		})
+	}
+}
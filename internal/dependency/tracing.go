@@ -0,0 +1,68 @@
+package dependency
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"os"
+)
+
+// serviceName default name of the service used in the traces, it could be overridden by the environment variable OTEL_SERVICE_NAME
+const serviceName = "products-api"
+
+// tracerProviderDefault builds the trace.TracerProvider based on the environment variable OTEL_TRACES_EXPORTER
+// and sets it as global trace.TracerProvider along with the W3C trace context propagator
+//
+// Supported exporters:
+//   - "none" (default) traces are not recorded
+//   - "stdout" traces are written into the standard output
+//   - "otlp" traces are sent using OTLP/HTTP, the collector endpoint is configured by OTEL_EXPORTER_OTLP_ENDPOINT (default "localhost:4318")
+//
+// The returned function flushes the pending spans and stops the exporter
+func tracerProviderDefault() (trace.TracerProvider, func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch name := os.Getenv("OTEL_TRACES_EXPORTER"); name {
+	case "", "none":
+		provider := noop.NewTracerProvider()
+		otel.SetTracerProvider(provider)
+
+		return provider, func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New()
+	case "otlp":
+		exporter, err = otlptracehttp.New(context.Background())
+	default:
+		return nil, nil, fmt.Errorf(`invalid environment variable OTEL_TRACES_EXPORTER: exporter '%s' is not supported`, name)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := resource.New(
+		context.Background(),
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+
+	return provider, provider.Shutdown, nil
+}
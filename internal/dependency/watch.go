@@ -0,0 +1,58 @@
+package dependency
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// watchFile polls the file each interval and calls onChange when its modification time or size changes,
+// the changes made while the file is missing are ignored. It returns when ctx is done
+func watchFile(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	last, _ := os.Stat(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+
+		last = info
+		onChange()
+	}
+}
+
+// watch runs watchFile in background until the *Application is closed
+func watch(app *Application, name, path string, interval time.Duration, onChange func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		watchFile(ctx, path, interval, onChange)
+	}()
+
+	app.OnClose(name, func(ctx context.Context) error {
+		cancel()
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
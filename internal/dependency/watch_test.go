@@ -0,0 +1,47 @@
+package dependency
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("fields: {}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &Application{}
+	changes := make(chan struct{}, 1)
+
+	watch(app, "watcher", path, 10*time.Millisecond, func() {
+		changes <- struct{}{}
+	})
+
+	select {
+	case <-changes:
+		t.Fatal("unexpected change")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(path, []byte("fields: {name: [{rule: required}]}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("expected change")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The watcher stops when the application is closed
+	if err := app.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yael-castro/products-api/internal/business"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// APIKeyHeader header used to receive the API keys
+const APIKeyHeader = "X-API-Key"
+
+// defaultRolesClaim default JWT claim that contains the roles granted to the client
+const defaultRolesClaim = "roles"
+
+// AuthConfig configuration for the Authenticator
+type AuthConfig struct {
+	// APIKeys static API keys accepted by the server
+	APIKeys []APIKey `yaml:"apiKeys"`
+	// JWT configuration used to verify the JWT bearer tokens
+	JWT JWTConfig `yaml:"jwt"`
+}
+
+// APIKey static API key, the key itself is never stored, only its hash
+type APIKey struct {
+	// Name identifies the client that owns the API key, it is used as model.Caller subject
+	Name string `yaml:"name"`
+	// Hash hexadecimal SHA-256 hash of the API key
+	Hash string `yaml:"hash"`
+	// Roles roles granted to the client that owns the API key
+	Roles []string `yaml:"roles"`
+}
+
+// JWTConfig configuration used to verify the JWT bearer tokens offline
+type JWTConfig struct {
+	// Issuer expected value of the "iss" claim
+	Issuer string `yaml:"issuer"`
+	// Audience expected value of the "aud" claim
+	Audience string `yaml:"audience"`
+	// Leeway time allowed to compensate clock skew when the "exp", "nbf" and "iat" claims are validated
+	Leeway time.Duration `yaml:"leeway"`
+	// HS256Secrets secrets used to verify tokens signed with HS256
+	HS256Secrets []string `yaml:"hs256Secrets"`
+	// JWKSFile path of the JSON Web Key Set file with the public keys used to verify tokens signed with RS256 and ES256
+	JWKSFile string `yaml:"jwksFile"`
+	// RolesClaim claim that contains the roles granted to the client, default value "roles"
+	RolesClaim string `yaml:"rolesClaim"`
+}
+
+// Authenticator verifies the credentials sent by the clients: static API keys and JWT bearer tokens
+type Authenticator struct {
+	apiKeys    map[[sha256.Size]byte]APIKey
+	secrets    []jwt.VerificationKey
+	keys       jsonWebKeys
+	parser     *jwt.Parser
+	rolesClaim string
+}
+
+// NewAuthenticator builds an *Authenticator based on the AuthConfig
+//
+// If any key to verify JWT is configured, the issuer and the audience are required
+func NewAuthenticator(config AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{
+		apiKeys:    make(map[[sha256.Size]byte]APIKey, len(config.APIKeys)),
+		rolesClaim: config.JWT.RolesClaim,
+	}
+
+	if a.rolesClaim == "" {
+		a.rolesClaim = defaultRolesClaim
+	}
+
+	for _, key := range config.APIKeys {
+		hash, err := hex.DecodeString(strings.TrimPrefix(key.Hash, "sha256:"))
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf(`invalid hash for API key '%s': a hexadecimal SHA-256 hash is required`, key.Name)
+		}
+
+		if key.Name == "" {
+			return nil, errors.New("API key name must not be blank")
+		}
+
+		a.apiKeys[[sha256.Size]byte(hash)] = key
+	}
+
+	for _, secret := range config.JWT.HS256Secrets {
+		a.secrets = append(a.secrets, []byte(secret))
+	}
+
+	if config.JWT.JWKSFile != "" {
+		data, err := os.ReadFile(config.JWT.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+
+		a.keys, err = parseJWKS(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(a.secrets) == 0 && len(a.keys) == 0 {
+		return a, nil
+	}
+
+	if config.JWT.Issuer == "" || config.JWT.Audience == "" {
+		return nil, errors.New("issuer and audience are required to verify JWT")
+	}
+
+	a.parser = jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithIssuer(config.JWT.Issuer),
+		jwt.WithAudience(config.JWT.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(config.JWT.Leeway),
+	)
+
+	return a, nil
+}
+
+// AuthenticateAPIKey returns the model.Caller that owns the API key
+func (a *Authenticator) AuthenticateAPIKey(key string) (model.Caller, error) {
+	apiKey, ok := a.apiKeys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return model.Caller{}, error2.Unauthorized("invalid API key")
+	}
+
+	return model.Caller{Subject: apiKey.Name, Roles: apiKey.Roles}, nil
+}
+
+// AuthenticateBearer verifies the signature, issuer, audience and expiration of the JWT and returns the model.Caller identified by it
+func (a *Authenticator) AuthenticateBearer(token string) (model.Caller, error) {
+	if a.parser == nil {
+		return model.Caller{}, error2.Unauthorized("bearer tokens are not supported")
+	}
+
+	claims := jwt.MapClaims{}
+
+	_, err := a.parser.ParseWithClaims(token, claims, a.keyFunc)
+	if err != nil {
+		return model.Caller{}, error2.Unauthorized(fmt.Sprintf("invalid bearer token: %v", err))
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return model.Caller{}, error2.Unauthorized(`invalid bearer token: missing "sub" claim`)
+	}
+
+	return model.Caller{Subject: subject, Roles: roles(claims[a.rolesClaim])}, nil
+}
+
+// Authenticate verifies the credentials sent in the request, the bool value is false if the request does not contain credentials
+func (a *Authenticator) Authenticate(r *http.Request) (model.Caller, bool, error) {
+	return a.authenticate(r.Header.Get(APIKeyHeader), r.Header.Get("Authorization"))
+}
+
+// authenticate identifies the client by the API key or by the value of the authorization header (in that order),
+// the bool value indicates if the client sent credentials
+func (a *Authenticator) authenticate(apiKey, authorization string) (model.Caller, bool, error) {
+	if apiKey != "" {
+		caller, err := a.AuthenticateAPIKey(apiKey)
+		return caller, true, err
+	}
+
+	if authorization == "" {
+		return model.Caller{}, false, nil
+	}
+
+	scheme, token, _ := strings.Cut(authorization, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return model.Caller{}, true, error2.Unauthorized(fmt.Sprintf(`authorization scheme '%s' is not supported`, scheme))
+	}
+
+	caller, err := a.AuthenticateBearer(strings.TrimSpace(token))
+	return caller, true, err
+}
+
+func (a *Authenticator) keyFunc(token *jwt.Token) (any, error) {
+	if token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		if len(a.secrets) == 0 {
+			return nil, errors.New("HS256 is not supported")
+		}
+
+		return jwt.VerificationKeySet{Keys: a.secrets}, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+
+	keys := a.keys.lookup(token.Method.Alg(), kid)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf(`missing key for algorithm '%s' and key id '%s'`, token.Method.Alg(), kid)
+	}
+
+	return jwt.VerificationKeySet{Keys: keys}, nil
+}
+
+// roles obtains the roles from a claim, the claim could be a list of strings or a string with roles separated by blank spaces (like the "scope" claim)
+func roles(claim any) []string {
+	switch claim := claim.(type) {
+	case string:
+		return strings.Fields(claim)
+	case []any:
+		roles := make([]string, 0, len(claim))
+
+		for _, v := range claim {
+			if role, ok := v.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+
+		return roles
+	}
+
+	return nil
+}
+
+// Authentication middleware that identifies the clients using the *Authenticator
+//
+// Safe methods (GET, HEAD and OPTIONS) are allowed for anonymous clients, any other method requires valid credentials
+// except for the anonymous routes (e.g. /graphql, whose queries are sent with POST), in that case the handler is in charge
+// of requiring credentials. If the request contains credentials they must be valid regardless of the method
+func Authentication(a *Authenticator, anonymousRoutes ...string) gin.HandlerFunc {
+	anonymous := make(map[string]bool, len(anonymousRoutes))
+	for _, route := range anonymousRoutes {
+		anonymous[route] = true
+	}
+
+	return func(c *gin.Context) {
+		caller, ok, err := a.Authenticate(c.Request)
+		if err != nil {
+			handleError(c, err)
+			c.Abort()
+			return
+		}
+
+		if !ok {
+			switch {
+			case anonymous[c.FullPath()]:
+				c.Next()
+			case c.Request.Method == http.MethodGet, c.Request.Method == http.MethodHead, c.Request.Method == http.MethodOptions:
+				c.Next()
+			default:
+				handleError(c, error2.Unauthorized("credentials are required"))
+				c.Abort()
+			}
+
+			return
+		}
+
+		c.Request = c.Request.WithContext(business.WithCaller(c.Request.Context(), caller))
+		c.Next()
+	}
+}
+
+// requireCaller returns an error if the request was not authenticated, it is used by the handlers of anonymous routes
+// (e.g. GraphQL mutations and gRPC calls) to require credentials for the write operations
+func requireCaller(ctx context.Context) error {
+	if _, ok := business.CallerFrom(ctx); !ok {
+		return error2.Unauthorized("credentials are required")
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yael-castro/products-api/internal/business"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthentication(t *testing.T) {
+	const (
+		apiKey   = "secret-api-key"
+		secret   = "hs256-secret"
+		issuer   = "https://auth.example.com/"
+		audience = "products-api"
+	)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+
+	jwks, _ := json.Marshal(map[string]any{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+		},
+	})
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := sha256.Sum256([]byte(apiKey))
+
+	authenticator, err := NewAuthenticator(AuthConfig{
+		APIKeys: []APIKey{{Name: "erp", Hash: hex.EncodeToString(hash[:]), Roles: []string{"catalog_editor"}}},
+		JWT: JWTConfig{
+			Issuer:       issuer,
+			Audience:     audience,
+			HS256Secrets: []string{secret},
+			JWKSFile:     jwksFile,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(modify func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub":   "user",
+			"iss":   issuer,
+			"aud":   audience,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"roles": []string{"admin"},
+		}
+
+		if modify != nil {
+			modify(c)
+		}
+
+		return c
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return signed
+	}
+
+	tdt := []struct {
+		method          string
+		path            string
+		headers         map[string]string
+		expectedCode    int
+		expectedSubject string
+	}{
+		{
+			method:       http.MethodGet,
+			expectedCode: http.StatusOK,
+		},
+		{
+			method:       http.MethodDelete,
+			expectedCode: http.StatusUnauthorized,
+		},
+		// Anonymous route
+		{
+			method:       http.MethodPost,
+			path:         "/graphql",
+			expectedCode: http.StatusOK,
+		},
+		{
+			method:          http.MethodDelete,
+			headers:         map[string]string{APIKeyHeader: apiKey},
+			expectedCode:    http.StatusOK,
+			expectedSubject: "erp",
+		},
+		{
+			method:       http.MethodGet,
+			headers:      map[string]string{APIKeyHeader: "invalid"},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			method:          http.MethodPost,
+			headers:         map[string]string{"Authorization": "Bearer " + sign(jwt.SigningMethodHS256, "", []byte(secret), claims(nil))},
+			expectedCode:    http.StatusOK,
+			expectedSubject: "user",
+		},
+		{
+			method:          http.MethodPut,
+			headers:         map[string]string{"Authorization": "Bearer " + sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(nil))},
+			expectedCode:    http.StatusOK,
+			expectedSubject: "user",
+		},
+		{
+			method:          http.MethodPut,
+			headers:         map[string]string{"Authorization": "Bearer " + sign(jwt.SigningMethodES256, "", ecKey, claims(nil))},
+			expectedCode:    http.StatusOK,
+			expectedSubject: "user",
+		},
+		{
+			method:       http.MethodPut,
+			headers:      map[string]string{"Authorization": "Bearer " + sign(jwt.SigningMethodHS256, "", []byte("invalid"), claims(nil))},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			method: http.MethodPut,
+			headers: map[string]string{"Authorization": "Bearer " + sign(jwt.SigningMethodHS256, "", []byte(secret), claims(func(c jwt.MapClaims) {
+				c["exp"] = time.Now().Add(-time.Minute).Unix()
+			}))},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			method: http.MethodPut,
+			headers: map[string]string{"Authorization": "Bearer " + sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) {
+				c["aud"] = "another-api"
+			}))},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			method: http.MethodPut,
+			headers: map[string]string{"Authorization": "Bearer " + sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) {
+				c["iss"] = "https://evil.example.com/"
+			}))},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			method: http.MethodPut,
+			headers: map[string]string{"Authorization": "Bearer " + sign(jwt.SigningMethodHS256, "", []byte(secret), claims(func(c jwt.MapClaims) {
+				delete(c, "exp")
+			}))},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			method:       http.MethodPut,
+			headers:      map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	var subject string
+
+	engine := gin.New()
+	engine.Use(Authentication(authenticator, "/graphql"))
+
+	handler := func(c *gin.Context) {
+		caller, _ := business.CallerFrom(c.Request.Context())
+		subject = caller.Subject
+	}
+
+	engine.Any("/", handler)
+	engine.Any("/graphql", handler)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			subject = ""
+
+			path := v.path
+			if path == "" {
+				path = "/"
+			}
+
+			request, _ := http.NewRequest(v.method, path, nil)
+			for key, value := range v.headers {
+				request.Header.Set(key, value)
+			}
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, request)
+
+			if w.Code != v.expectedCode {
+				t.Fatalf(`expected code '%d' unexpected code '%d' (%s)`, v.expectedCode, w.Code, w.Body.String())
+			}
+
+			if subject != v.expectedSubject {
+				t.Fatalf(`expected subject '%s' unexpected subject '%s'`, v.expectedSubject, subject)
+			}
+
+			if w.Code == http.StatusUnauthorized && !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Bearer") {
+				t.Fatal("missing WWW-Authenticate header")
+			}
+
+			t.Log(w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"strings"
+)
+
+// MIMEYAML Content-Type MIME for the OpenAPI document in YAML format
+const MIMEYAML = "application/yaml"
+
+// explorerCSP Content-Security-Policy of the API explorer, every asset is inlined so nothing is loaded from other origins
+const explorerCSP = "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; img-src 'self' data:"
+
+// explorer self-contained HTML page of the API explorer
+//
+//go:embed assets/explorer.html
+var explorer []byte
+
+// _ "implements" constraint for Docs
+var _ Documentation = Docs{}
+
+// Docs contains the group of gin.HandlerFunc for handle requests related to the API documentation
+type Docs struct {
+	doc *openapi3.T
+}
+
+// NewDocs builds the Docs that serve the OpenAPI document
+func NewDocs(doc *openapi3.T) Docs {
+	return Docs{doc: doc}
+}
+
+// OpenAPIYAML gin.HandlerFunc to handle http requests made to obtain the OpenAPI document in YAML format
+func (d Docs) OpenAPIYAML(c *gin.Context) {
+	data, err := yaml.Marshal(d.document(c.Request))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, MIMEYAML+"; charset=utf-8", data)
+}
+
+// OpenAPIJSON gin.HandlerFunc to handle http requests made to obtain the OpenAPI document in JSON format
+func (d Docs) OpenAPIJSON(c *gin.Context) {
+	data, err := json.Marshal(d.document(c.Request))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, gin.MIMEJSON+"; charset=utf-8", data)
+}
+
+// Explorer gin.HandlerFunc to handle http requests made to the interactive API explorer
+func (d Docs) Explorer(c *gin.Context) {
+	c.Header("Content-Security-Policy", explorerCSP)
+	c.Data(http.StatusOK, gin.MIMEHTML+"; charset=utf-8", explorer)
+}
+
+// ProblemType gin.HandlerFunc to handle http requests made to obtain the description of a problem type (/problems/:code),
+// the type of every Problem is the URI of its description
+func (d Docs) ProblemType(c *gin.Context) {
+	code := c.Param("code")
+
+	for _, t := range problemTypes {
+		if t.Code == code {
+			respond(c, http.StatusOK, t)
+			return
+		}
+	}
+
+	handleError(c, error2.NotFound(fmt.Sprintf(`problem type '%s' does not exist`, code)))
+}
+
+// document returns a copy of the OpenAPI document whose servers URL is the URL used by the client to reach the server
+func (d Docs) document(r *http.Request) *openapi3.T {
+	doc := *d.doc
+	doc.Servers = openapi3.Servers{{URL: serverURL(r)}}
+
+	return &doc
+}
+
+// serverURL returns the URL used by the client to reach the server, considering the headers set by the reverse proxies
+func serverURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	if proto := firstValue(r.Header.Get("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+
+	host := r.Host
+	if forwardedHost := firstValue(r.Header.Get("X-Forwarded-Host")); forwardedHost != "" {
+		host = forwardedHost
+	}
+
+	return scheme + "://" + host
+}
+
+// firstValue returns the first value of a comma separated header value
+func firstValue(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	productsapi "github.com/yael-castro/products-api"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDocs(t *testing.T) {
+	tdt := []struct {
+		path              string
+		headers           map[string]string
+		expectedServerURL string
+	}{
+		{
+			path:              "/openapi.json",
+			expectedServerURL: "http://products.example.com",
+		},
+		{
+			path:              "/openapi.yaml",
+			expectedServerURL: "http://products.example.com",
+		},
+		// Behind a reverse proxy
+		{
+			path: "/openapi.json",
+			headers: map[string]string{
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "api.example.com, proxy.internal",
+			},
+			expectedServerURL: "https://api.example.com",
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	doc, err := ParseOpenAPI(productsapi.OpenAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs := NewDocs(doc)
+
+	engine := gin.New()
+	engine.GET("/openapi.yaml", docs.OpenAPIYAML)
+	engine.GET("/openapi.json", docs.OpenAPIJSON)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			request, _ := http.NewRequest(http.MethodGet, v.path, nil)
+			request.Host = "products.example.com"
+
+			for key, value := range v.headers {
+				request.Header.Set(key, value)
+			}
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, request)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf(`expected code '%d' unexpected code '%d'`, http.StatusOK, w.Code)
+			}
+
+			document := struct {
+				Servers []struct {
+					URL string `json:"url" yaml:"url"`
+				} `json:"servers" yaml:"servers"`
+			}{}
+
+			if strings.HasSuffix(v.path, ".json") {
+				err = json.Unmarshal(w.Body.Bytes(), &document)
+			} else {
+				err = yaml.Unmarshal(w.Body.Bytes(), &document)
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(document.Servers) != 1 || document.Servers[0].URL != v.expectedServerURL {
+				t.Fatalf(`expected server URL '%s' got %+v`, v.expectedServerURL, document.Servers)
+			}
+
+			// The original document is not modified
+			if doc.Servers[0].URL != "http://localhost:8080" {
+				t.Fatalf(`unexpected modification of the OpenAPI document: %s`, doc.Servers[0].URL)
+			}
+		})
+	}
+}
+
+func TestDocs_Explorer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.GET("/docs", Docs{}.Explorer)
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/docs", nil)
+	engine.ServeHTTP(w, request)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf(`expected code '%d' unexpected code '%d'`, http.StatusOK, w.Code)
+	}
+
+	// The explorer must not load anything from other origins (e.g. a CDN)
+	for _, attribute := range []string{`src="http`, `href="http`, `src="//`, `href="//`} {
+		if strings.Contains(w.Body.String(), attribute) {
+			t.Fatalf(`the explorer loads an external asset: %s`, attribute)
+		}
+	}
+}
@@ -0,0 +1,888 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/yael-castro/products-api/internal/business"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default limits of the GraphQL queries
+const (
+	defaultGraphQLMaxDepth      = 8
+	defaultGraphQLMaxComplexity = 1000
+	defaultGraphQLPageSize      = 20
+	defaultGraphQLMaxPageSize   = 100
+)
+
+// Codes of the GraphQL errors, they are returned in the "code" extension of each error
+const (
+	GraphQLCodeBadRequest       = "BAD_REQUEST"
+	GraphQLCodeParseFailed      = "GRAPHQL_PARSE_FAILED"
+	GraphQLCodeValidationFailed = "GRAPHQL_VALIDATION_FAILED"
+	GraphQLCodeTooDeep          = "QUERY_TOO_DEEP"
+	GraphQLCodeTooComplex       = "QUERY_TOO_COMPLEX"
+	GraphQLCodeBadUserInput     = "BAD_USER_INPUT"
+	GraphQLCodeUnauthenticated  = "UNAUTHENTICATED"
+	GraphQLCodeForbidden        = "FORBIDDEN"
+	GraphQLCodeNotFound         = "NOT_FOUND"
+	GraphQLCodeConflict         = "CONFLICT"
+	GraphQLCodePayloadTooLarge  = "PAYLOAD_TOO_LARGE"
+	GraphQLCodeRateLimited      = "RATE_LIMITED"
+	GraphQLCodeNotImplemented   = "NOT_IMPLEMENTED"
+	GraphQLCodeInternal         = "INTERNAL_SERVER_ERROR"
+)
+
+// GraphQLConfig limits of the GraphQL queries, the zero values take the default values
+type GraphQLConfig struct {
+	// MaxDepth maximum depth of the selection sets, default value 8
+	MaxDepth int
+	// MaxComplexity maximum complexity of a query, every field costs 1 and the fields selected from
+	// a page of products cost as many times as the page size, default value 1000
+	MaxComplexity int
+	// MaxPageSize maximum number of products by page, default value 100
+	MaxPageSize int
+}
+
+// withDefaults returns the config with the default values for the missing limits
+func (g GraphQLConfig) withDefaults() GraphQLConfig {
+	if g.MaxDepth <= 0 {
+		g.MaxDepth = defaultGraphQLMaxDepth
+	}
+
+	if g.MaxComplexity <= 0 {
+		g.MaxComplexity = defaultGraphQLMaxComplexity
+	}
+
+	if g.MaxPageSize <= 0 {
+		g.MaxPageSize = defaultGraphQLMaxPageSize
+	}
+
+	return g
+}
+
+// graphQLError error with the code returned in the GraphQL error extensions
+type graphQLError struct {
+	code    string
+	message string
+	// extensions extensions returned besides the code
+	extensions map[string]any
+}
+
+// Error returns the error message
+func (g graphQLError) Error() string {
+	return g.message
+}
+
+// Extensions returns the extensions of the GraphQL error
+func (g graphQLError) Extensions() map[string]any {
+	extensions := map[string]any{"code": g.code}
+
+	for key, value := range g.extensions {
+		extensions[key] = value
+	}
+
+	return extensions
+}
+
+// newGraphQLError relates the domain errors to GraphQL error codes, the unexpected errors are logged and hidden from the clients.
+// The domain errors are found even if they are wrapped, the candidates of error2.Duplicates are returned in the "candidates" extension
+func newGraphQLError(ctx context.Context, err error) error {
+	var duplicates error2.Duplicates
+	if errors.As(err, &duplicates) {
+		return graphQLError{code: GraphQLCodeConflict, message: err.Error(), extensions: map[string]any{"candidates": duplicates.Candidates}}
+	}
+
+	switch {
+	case errorAs[error2.Violations](err), errorAs[error2.Validation](err), errorAs[error2.Unprocessable](err):
+		return graphQLError{code: GraphQLCodeBadUserInput, message: err.Error()}
+	case errorAs[error2.Unauthorized](err):
+		return graphQLError{code: GraphQLCodeUnauthenticated, message: err.Error()}
+	case errorAs[error2.Forbidden](err):
+		return graphQLError{code: GraphQLCodeForbidden, message: err.Error()}
+	case errorAs[error2.NotFound](err):
+		return graphQLError{code: GraphQLCodeNotFound, message: err.Error()}
+	case errorAs[error2.Conflict](err):
+		return graphQLError{code: GraphQLCodeConflict, message: err.Error()}
+	case errorAs[error2.TooLarge](err):
+		return graphQLError{code: GraphQLCodePayloadTooLarge, message: err.Error()}
+	case errorAs[error2.TooManyRequests](err):
+		return graphQLError{code: GraphQLCodeRateLimited, message: err.Error()}
+	case errorAs[error2.NotImplemented](err):
+		return graphQLError{code: GraphQLCodeNotImplemented, message: err.Error()}
+	}
+
+	pgErr := &error2.PG{}
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return graphQLError{code: GraphQLCodeConflict, message: "duplicated record"}
+	}
+
+	slog.ErrorContext(ctx, "graphql resolver failed", "error", err)
+
+	return graphQLError{code: GraphQLCodeInternal, message: "an unexpected error occurred"}
+}
+
+// graphQLRequest body of the GraphQL requests
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// graphQLResponse body of the GraphQL responses
+type graphQLResponse struct {
+	Data   any                        `json:"data,omitempty"`
+	Errors []gqlerrors.FormattedError `json:"errors,omitempty"`
+}
+
+// _ "implements" constraint for *GraphQLSchema
+var _ GraphQLExecutor = (*GraphQLSchema)(nil)
+
+// GraphQLSchema GraphQL schema of the products, the queries and mutations are resolved by the business.ProductManager
+type GraphQLSchema struct {
+	manager business.ProductManager
+	config  GraphQLConfig
+	schema  graphql.Schema
+}
+
+// NewGraphQLSchema builds the *GraphQLSchema
+func NewGraphQLSchema(manager business.ProductManager, config GraphQLConfig) (*GraphQLSchema, error) {
+	g := &GraphQLSchema{manager: manager, config: config.withDefaults()}
+
+	schema, err := g.buildSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	g.schema = schema
+	return g, nil
+}
+
+// GraphQL gin.HandlerFunc to handle http requests made to execute GraphQL operations
+//
+// The operations are received as JSON body (POST) or as query parameters (GET), only queries are allowed with GET.
+// The documents that can not be executed (invalid or over the limits) are responded with 400 status code,
+// otherwise the response has 200 status code even if some field failed
+func (g *GraphQLSchema) GraphQL(c *gin.Context) {
+	request, err := graphQLRequestFrom(c)
+	if err != nil {
+		g.respondErrors(c, http.StatusBadRequest, graphQLError{code: GraphQLCodeBadRequest, message: err.Error()})
+		return
+	}
+
+	document, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(request.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		g.respondErrors(c, http.StatusBadRequest, graphQLError{code: GraphQLCodeParseFailed, message: err.Error()})
+		return
+	}
+
+	validation := graphql.ValidateDocument(&g.schema, document, nil)
+	if !validation.IsValid {
+		for i := range validation.Errors {
+			validation.Errors[i].Extensions = graphQLError{code: GraphQLCodeValidationFailed}.Extensions()
+		}
+
+		c.JSON(http.StatusBadRequest, graphQLResponse{Errors: validation.Errors})
+		return
+	}
+
+	operation, err := graphQLOperation(document, request.OperationName)
+	if err != nil {
+		g.respondErrors(c, http.StatusBadRequest, graphQLError{code: GraphQLCodeBadRequest, message: err.Error()})
+		return
+	}
+
+	if c.Request.Method == http.MethodGet && operation.Operation != ast.OperationTypeQuery {
+		c.Header("Allow", http.MethodPost)
+		g.respondErrors(c, http.StatusMethodNotAllowed, graphQLError{
+			code:    GraphQLCodeBadRequest,
+			message: fmt.Sprintf(`%s operations are only allowed with POST method`, operation.Operation),
+		})
+		return
+	}
+
+	if err = g.checkLimits(document, operation, request.Variables); err != nil {
+		g.respondErrors(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        g.schema,
+		AST:           document,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       c.Request.Context(),
+	})
+
+	c.JSON(http.StatusOK, graphQLResponse{Data: result.Data, Errors: result.Errors})
+}
+
+// respondErrors writes the errors of a GraphQL document that was not executed
+func (g *GraphQLSchema) respondErrors(c *gin.Context, code int, errs ...error) {
+	formatted := make([]gqlerrors.FormattedError, 0, len(errs))
+
+	for _, err := range errs {
+		formattedErr := gqlerrors.FormatError(err)
+
+		if extended, ok := err.(gqlerrors.ExtendedError); ok {
+			formattedErr.Extensions = extended.Extensions()
+		}
+
+		formatted = append(formatted, formattedErr)
+	}
+
+	c.JSON(code, graphQLResponse{Errors: formatted})
+}
+
+// graphQLRequestFrom reads the GraphQL request from the query parameters (GET) or from the JSON body (POST)
+func graphQLRequestFrom(c *gin.Context) (graphQLRequest, error) {
+	request := graphQLRequest{}
+
+	if c.Request.Method == http.MethodGet {
+		request.Query = c.Query("query")
+		request.OperationName = c.Query("operationName")
+
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				return request, fmt.Errorf("invalid variables: %w", err)
+			}
+		}
+	} else if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil {
+		return request, fmt.Errorf("invalid request body: %w", err)
+	}
+
+	if strings.TrimSpace(request.Query) == "" {
+		return request, errors.New("missing query")
+	}
+
+	return request, nil
+}
+
+// graphQLOperation returns the operation of the document that will be executed
+func graphQLOperation(document *ast.Document, name string) (*ast.OperationDefinition, error) {
+	var operation *ast.OperationDefinition
+
+	for _, definition := range document.Definitions {
+		op, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		if name == "" {
+			if operation != nil {
+				return nil, errors.New("operationName is required when the document contains multiple operations")
+			}
+
+			operation = op
+			continue
+		}
+
+		if op.Name != nil && op.Name.Value == name {
+			return op, nil
+		}
+	}
+
+	if operation == nil {
+		return nil, fmt.Errorf(`operation '%s' does not exist`, name)
+	}
+
+	return operation, nil
+}
+
+// checkLimits checks the depth and the complexity of the operation, the introspection fields are not considered
+func (g *GraphQLSchema) checkLimits(document *ast.Document, operation *ast.OperationDefinition, variables map[string]any) error {
+	analyzer := graphQLAnalyzer{
+		fragments:   make(map[string]*ast.FragmentDefinition),
+		variables:   variables,
+		maxPageSize: g.config.MaxPageSize,
+	}
+
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			analyzer.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	if depth := analyzer.depth(operation.SelectionSet); depth > g.config.MaxDepth {
+		return graphQLError{
+			code:    GraphQLCodeTooDeep,
+			message: fmt.Sprintf("query depth %d exceeds the maximum depth %d", depth, g.config.MaxDepth),
+		}
+	}
+
+	if complexity := analyzer.complexity(operation.SelectionSet); complexity > g.config.MaxComplexity {
+		return graphQLError{
+			code:    GraphQLCodeTooComplex,
+			message: fmt.Sprintf("query complexity %d exceeds the maximum complexity %d", complexity, g.config.MaxComplexity),
+		}
+	}
+
+	return nil
+}
+
+// graphQLAnalyzer computes the depth and the complexity of the selection sets, the fragments are expanded
+//
+// The documents are validated before the analysis, so there are no fragment cycles
+type graphQLAnalyzer struct {
+	fragments   map[string]*ast.FragmentDefinition
+	variables   map[string]any
+	maxPageSize int
+}
+
+// fields returns the fields of the selection set expanding the fragments
+func (a graphQLAnalyzer) fields(set *ast.SelectionSet) []*ast.Field {
+	if set == nil {
+		return nil
+	}
+
+	fields := make([]*ast.Field, 0, len(set.Selections))
+
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if !strings.HasPrefix(selection.Name.Value, "__") {
+				fields = append(fields, selection)
+			}
+		case *ast.InlineFragment:
+			fields = append(fields, a.fields(selection.SelectionSet)...)
+		case *ast.FragmentSpread:
+			if fragment, ok := a.fragments[selection.Name.Value]; ok {
+				fields = append(fields, a.fields(fragment.SelectionSet)...)
+			}
+		}
+	}
+
+	return fields
+}
+
+// depth returns the depth of the selection set
+func (a graphQLAnalyzer) depth(set *ast.SelectionSet) int {
+	depth := 0
+
+	for _, field := range a.fields(set) {
+		depth = max(depth, 1+a.depth(field.SelectionSet))
+	}
+
+	return depth
+}
+
+// complexity returns the complexity of the selection set, the fields selected from a page cost as many times as the page size
+func (a graphQLAnalyzer) complexity(set *ast.SelectionSet) int {
+	complexity := 0
+
+	for _, field := range a.fields(set) {
+		multiplier := 1
+		if field.Name.Value == "products" {
+			multiplier = a.pageSize(field)
+		}
+
+		complexity += 1 + multiplier*a.complexity(field.SelectionSet)
+	}
+
+	return complexity
+}
+
+// pageSize returns the page size requested by the field, limited by the maximum page size
+func (a graphQLAnalyzer) pageSize(field *ast.Field) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "limit" {
+			continue
+		}
+
+		var value any
+
+		switch v := argument.Value.(type) {
+		case *ast.IntValue:
+			value = v.Value
+		case *ast.Variable:
+			value = a.variables[v.Name.Value]
+		}
+
+		if limit, ok := toInt(value); ok {
+			return min(max(limit, 0), a.maxPageSize)
+		}
+	}
+
+	return defaultGraphQLPageSize
+}
+
+// toInt converts the literal or variable value into int
+func toInt(value any) (int, bool) {
+	switch v := value.(type) {
+	case string:
+		i, err := strconv.Atoi(v)
+		return i, err == nil
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	}
+
+	return 0, false
+}
+
+// buildSchema builds the GraphQL schema of the products
+func (g *GraphQLSchema) buildSchema() (graphql.Schema, error) {
+	imageType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Image",
+		Description: "Image of a product",
+		Fields: graphql.Fields{
+			"url": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source.(model.URL).String(), nil
+				},
+			},
+		},
+	})
+
+	statusValues := graphql.EnumValueConfigMap{}
+	for _, status := range model.Statuses {
+		statusValues[strings.ToUpper(string(status))] = &graphql.EnumValueConfig{Value: status}
+	}
+
+	statusType := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "ProductStatus",
+		Description: "Stage of the lifecycle of a product",
+		Values:      statusValues,
+	})
+
+	productType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Product",
+		Fields: graphql.Fields{
+			"sku": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "Internal stock-keeping unit",
+				Resolve: productField(func(p model.Product) any {
+					return string(p.SKU)
+				}),
+			},
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: productField(func(p model.Product) any {
+					return p.Name
+				}),
+			},
+			"brand": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: productField(func(p model.Product) any {
+					return p.Brand
+				}),
+			},
+			"size": &graphql.Field{
+				Type: graphql.String,
+				Resolve: productField(func(p model.Product) any {
+					if p.Size == nil {
+						return nil
+					}
+
+					return *p.Size
+				}),
+			},
+			"price": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Float),
+				Resolve: productField(func(p model.Product) any {
+					return p.Price
+				}),
+			},
+			"principalImage": &graphql.Field{
+				Type: imageType,
+				Resolve: productField(func(p model.Product) any {
+					if p.PrincipalImage == nil || p.PrincipalImage.URL == nil {
+						return nil
+					}
+
+					return *p.PrincipalImage
+				}),
+			},
+			"otherImages": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(imageType))),
+				Resolve: productField(func(p model.Product) any {
+					images := make([]any, 0, len(p.OtherImages))
+					for _, image := range p.OtherImages {
+						images = append(images, image)
+					}
+
+					return images
+				}),
+			},
+			"status": &graphql.Field{
+				Type: graphql.NewNonNull(statusType),
+				Resolve: productField(func(p model.Product) any {
+					return p.Status
+				}),
+			},
+		},
+	})
+
+	changeStatusValues := graphql.EnumValueConfigMap{}
+	for _, status := range model.ChangeStatuses {
+		changeStatusValues[strings.ToUpper(string(status))] = &graphql.EnumValueConfig{Value: status}
+	}
+
+	priceChangeType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "PriceChange",
+		Description: "Change of the price of a product held until it is approved (see /v1/price-changes)",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+			"sku": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+			"currentPrice": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Price of the product when the change was requested",
+			},
+			"price": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Float),
+				Description: "Requested price of the product",
+			},
+			"status": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewEnum(graphql.EnumConfig{
+					Name:        "PriceChangeStatus",
+					Description: "Stage of the review of a price change",
+					Values:      changeStatusValues,
+				})),
+			},
+			"requestedBy": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"requestedAt": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Time of the request (RFC 3339)",
+			},
+		},
+	})
+
+	updateProductPayloadType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "UpdateProductPayload",
+		Description: "Result of updating a product",
+		Fields: graphql.Fields{
+			"product": &graphql.Field{
+				Type:        graphql.NewNonNull(productType),
+				Description: "Updated product, its price is the current price if the price change is held",
+			},
+			"priceChange": &graphql.Field{
+				Type:        priceChangeType,
+				Description: "Price change held until it is approved, it is null if the price was changed",
+			},
+		},
+	})
+
+	productPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ProductPage",
+		Description: "Page of products",
+		Fields: graphql.Fields{
+			"items": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(productType))),
+			},
+			"totalCount": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Number of products that match the filter",
+			},
+			"offset": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"limit": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"hasNextPage": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+			},
+		},
+	})
+
+	productFilterType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "ProductFilter",
+		Description: "Filter of products, every condition must be met",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"brand": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "Brand name (case insensitive)",
+			},
+			"nameContains": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "Text contained in the name (case insensitive)",
+			},
+			"size": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"minPrice": &graphql.InputObjectFieldConfig{
+				Type: graphql.Float,
+			},
+			"maxPrice": &graphql.InputObjectFieldConfig{
+				Type: graphql.Float,
+			},
+			"statuses": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewList(graphql.NewNonNull(statusType)),
+				Description: "Statuses of the products, by default only the published products are listed",
+			},
+		},
+	})
+
+	productInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ProductInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"sku": &graphql.InputObjectFieldConfig{
+				Type:        graphql.ID,
+				Description: "SKU of the product, if it is missing the next free SKU is allocated when the product is created",
+			},
+			"name":           &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"brand":          &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"size":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"price":          &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+			"principalImage": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"otherImages":    &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"product": &graphql.Field{
+				Type:        productType,
+				Description: "Product identified by its SKU",
+				Args: graphql.FieldConfigArgument{
+					"sku": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: g.resolveProduct,
+			},
+			"products": &graphql.Field{
+				Type:        graphql.NewNonNull(productPageType),
+				Description: "Page of the products that match the filter",
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: productFilterType},
+					"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+					"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultGraphQLPageSize},
+				},
+				Resolve: g.resolveProducts,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createProduct": &graphql.Field{
+				Type: graphql.NewNonNull(productType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(productInputType)},
+				},
+				Resolve: g.resolveCreateProduct,
+			},
+			"updateProduct": &graphql.Field{
+				Type: graphql.NewNonNull(updateProductPayloadType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(productInputType)},
+				},
+				Resolve: g.resolveUpdateProduct,
+			},
+			"deleteProduct": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"sku": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: g.resolveDeleteProduct,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// productField builds the resolver of a field of model.Product
+func productField(f func(model.Product) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		return f(p.Source.(model.Product)), nil
+	}
+}
+
+// resolveProduct resolves the "product" query
+func (g *GraphQLSchema) resolveProduct(p graphql.ResolveParams) (any, error) {
+	product, err := g.manager.ObtainProduct(p.Context, model.SKU(p.Args["sku"].(string)))
+	if err != nil {
+		return nil, newGraphQLError(p.Context, err)
+	}
+
+	return product, nil
+}
+
+// resolveProducts resolves the "products" query, the products are filtered and paginated by the storage.
+// The pages are stable since ListProducts returns the products ordered by SKU
+func (g *GraphQLSchema) resolveProducts(p graphql.ResolveParams) (any, error) {
+	offset, _ := p.Args["offset"].(int)
+	limit, _ := p.Args["limit"].(int)
+
+	if offset < 0 || limit < 0 || limit > g.config.MaxPageSize {
+		return nil, graphQLError{
+			code:    GraphQLCodeBadUserInput,
+			message: fmt.Sprintf("offset must be positive and limit must be between 0 and %d", g.config.MaxPageSize),
+		}
+	}
+
+	// The limit 0 of model.ProductFilter lists every product, so the empty pages read a single product to obtain the total count
+	filter := productFilterFromInput(p.Args["filter"])
+	filter.Offset, filter.Limit = offset, max(limit, 1)
+
+	page, err := g.manager.ListProducts(p.Context, filter)
+	if err != nil {
+		return nil, newGraphQLError(p.Context, err)
+	}
+
+	items := make([]any, 0, limit)
+	for _, product := range page.Items[:min(limit, len(page.Items))] {
+		items = append(items, product)
+	}
+
+	return map[string]any{
+		"items":       items,
+		"totalCount":  page.TotalCount,
+		"offset":      offset,
+		"limit":       limit,
+		"hasNextPage": offset+len(items) < page.TotalCount,
+	}, nil
+}
+
+// productFilterFromInput builds the model.ProductFilter from the ProductFilter input, without offset nor limit
+func productFilterFromInput(input any) model.ProductFilter {
+	fields, _ := input.(map[string]any)
+	filter := model.ProductFilter{}
+
+	statuses, _ := fields["statuses"].([]any)
+	for _, status := range statuses {
+		filter.Statuses = append(filter.Statuses, status.(model.Status))
+	}
+
+	filter.Brand, _ = fields["brand"].(string)
+	filter.NameContains, _ = fields["nameContains"].(string)
+	filter.Size, _ = fields["size"].(string)
+
+	if minPrice, ok := fields["minPrice"].(float64); ok {
+		filter.MinPrice = &minPrice
+	}
+
+	if maxPrice, ok := fields["maxPrice"].(float64); ok {
+		filter.MaxPrice = &maxPrice
+	}
+
+	return filter
+}
+
+// resolveCreateProduct resolves the "createProduct" mutation
+func (g *GraphQLSchema) resolveCreateProduct(p graphql.ResolveParams) (any, error) {
+	product, err := productFromInput(p.Context, p.Args["input"].(map[string]any))
+	if err != nil {
+		return nil, newGraphQLError(p.Context, err)
+	}
+
+	if err = g.manager.CreateProduct(p.Context, &product); err != nil {
+		return nil, newGraphQLError(p.Context, err)
+	}
+
+	return product, nil
+}
+
+// resolveUpdateProduct resolves the "updateProduct" mutation
+func (g *GraphQLSchema) resolveUpdateProduct(p graphql.ResolveParams) (any, error) {
+	product, err := productFromInput(p.Context, p.Args["input"].(map[string]any))
+	if err != nil {
+		return nil, newGraphQLError(p.Context, err)
+	}
+
+	change, err := g.manager.UpdateProduct(p.Context, product)
+	if err != nil {
+		return nil, newGraphQLError(p.Context, err)
+	}
+
+	if change == nil {
+		return map[string]any{"product": product}, nil
+	}
+
+	product.Price = change.CurrentPrice
+
+	return map[string]any{
+		"product": product,
+		"priceChange": map[string]any{
+			"id":           strconv.FormatUint(uint64(change.ID), 10),
+			"sku":          string(change.SKU),
+			"currentPrice": change.CurrentPrice,
+			"price":        change.Price,
+			"status":       change.Status,
+			"requestedBy":  change.RequestedBy,
+			"requestedAt":  change.RequestedAt.Format(time.RFC3339),
+		},
+	}, nil
+}
+
+// resolveDeleteProduct resolves the "deleteProduct" mutation
+func (g *GraphQLSchema) resolveDeleteProduct(p graphql.ResolveParams) (any, error) {
+	if err := requireCaller(p.Context); err != nil {
+		return nil, newGraphQLError(p.Context, err)
+	}
+
+	if err := g.manager.DeleteProduct(p.Context, model.SKU(p.Args["sku"].(string))); err != nil {
+		return nil, newGraphQLError(p.Context, err)
+	}
+
+	return true, nil
+}
+
+// productFromInput builds the model.Product from the ProductInput, it requires the request to be authenticated
+func productFromInput(ctx context.Context, input map[string]any) (model.Product, error) {
+	if err := requireCaller(ctx); err != nil {
+		return model.Product{}, err
+	}
+
+	product := model.Product{
+		Name:  input["name"].(string),
+		Brand: input["brand"].(string),
+		Price: input["price"].(float64),
+	}
+
+	if sku, ok := input["sku"].(string); ok {
+		product.SKU = model.SKU(sku)
+	}
+
+	if size, ok := input["size"].(string); ok {
+		product.Size = &size
+	}
+
+	if principalImage, ok := input["principalImage"].(string); ok {
+		product.PrincipalImage = &model.URL{}
+
+		if err := product.PrincipalImage.UnmarshalText([]byte(principalImage)); err != nil {
+			return product, error2.Validation(fmt.Sprintf(`invalid principalImage: %s`, err))
+		}
+	}
+
+	otherImages, _ := input["otherImages"].([]any)
+	for _, rawURL := range otherImages {
+		image := model.URL{}
+
+		if err := image.UnmarshalText([]byte(rawURL.(string))); err != nil {
+			return product, error2.Validation(fmt.Sprintf(`invalid otherImages: %s`, err))
+		}
+
+		product.OtherImages = append(product.OtherImages, image)
+	}
+
+	return product, nil
+}
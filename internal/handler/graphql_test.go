@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/yael-castro/products-api/internal/business"
+	"github.com/yael-castro/products-api/internal/model"
+	"github.com/yael-castro/products-api/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGraphQLSchema_GraphQL(t *testing.T) {
+	tdt := []struct {
+		method       string
+		query        string
+		variables    map[string]any
+		caller       *model.Caller
+		expectedCode int
+		// expectedData substring of the JSON encoded data
+		expectedData string
+		// expectedErrorCode code extension of the first error
+		expectedErrorCode string
+		// expectedExtensions substring of the JSON encoded extensions of the first error
+		expectedExtensions string
+	}{
+		{
+			method:       http.MethodPost,
+			query:        `{ product(sku: "FAL-1000001") { sku name principalImage { url } otherImages { url } } }`,
+			expectedCode: http.StatusOK,
+			expectedData: `{"product":{"name":"Shoes","otherImages":[{"url":"https://a.example.com"}],"principalImage":{"url":"https://example.com"},"sku":"FAL-1000001"}}`,
+		},
+		{
+			method:       http.MethodGet,
+			query:        `query ($limit: Int) { products(filter: {brand: "nike"}, limit: $limit) { items { sku } totalCount hasNextPage } }`,
+			variables:    map[string]any{"limit": 1},
+			expectedCode: http.StatusOK,
+			expectedData: `{"products":{"hasNextPage":true,"items":[{"sku":"FAL-1000001"}],"totalCount":2}}`,
+		},
+		{
+			method:       http.MethodGet,
+			query:        `query ($offset: Int) { products(filter: {brand: "nike"}, offset: $offset, limit: 1) { items { sku } totalCount hasNextPage } }`,
+			variables:    map[string]any{"offset": 1},
+			expectedCode: http.StatusOK,
+			expectedData: `{"products":{"hasNextPage":false,"items":[{"sku":"FAL-1000002"}],"totalCount":2}}`,
+		},
+		{
+			method:            http.MethodPost,
+			query:             `{ product(sku: "FAL-9999999") { sku } }`,
+			expectedCode:      http.StatusOK,
+			expectedErrorCode: GraphQLCodeNotFound,
+		},
+		{
+			method:            http.MethodPost,
+			query:             `{ product(sku: "1234") { sku } }`,
+			expectedCode:      http.StatusOK,
+			expectedErrorCode: GraphQLCodeBadUserInput,
+		},
+		// Mutations require credentials
+		{
+			method:            http.MethodPost,
+			query:             `mutation { deleteProduct(sku: "FAL-1000001") }`,
+			expectedCode:      http.StatusOK,
+			expectedErrorCode: GraphQLCodeUnauthenticated,
+		},
+		{
+			method:       http.MethodPost,
+			query:        `mutation { createProduct(input: {sku: "FAL-1000003", name: "Boots", brand: "Puma", price: 20, principalImage: "https://example.com"}) { sku price } }`,
+			caller:       &model.Caller{Subject: "test"},
+			expectedCode: http.StatusOK,
+			expectedData: `{"createProduct":{"price":20,"sku":"FAL-1000003"}}`,
+		},
+		// The price change is held until it is approved, the rest of the product is updated
+		{
+			method:       http.MethodPost,
+			query:        `mutation { updateProduct(input: {sku: "FAL-1000002", name: "Shirt", brand: "Nike", price: 1500, principalImage: "https://example.com"}) { product { sku price } priceChange { id currentPrice price status } } }`,
+			caller:       &model.Caller{Subject: "test"},
+			expectedCode: http.StatusOK,
+			expectedData: `{"updateProduct":{"priceChange":{"currentPrice":15,"id":"1","price":1500,"status":"PENDING"},"product":{"price":15,"sku":"FAL-1000002"}}}`,
+		},
+		// The SKU is allocated if it is missing
+		{
+			method:       http.MethodPost,
+			query:        `mutation { createProduct(input: {name: "Sandals", brand: "Puma", price: 20, principalImage: "https://example.com"}) { sku } }`,
+			caller:       &model.Caller{Subject: "test"},
+			expectedCode: http.StatusOK,
+			expectedData: `{"createProduct":{"sku":"FAL-1000000"}}`,
+		},
+		{
+			method:             http.MethodPost,
+			query:              `mutation { createProduct(input: {name: "SHOES", brand: "nike", price: 20, principalImage: "https://example.com"}) { sku } }`,
+			caller:             &model.Caller{Subject: "test"},
+			expectedCode:       http.StatusOK,
+			expectedErrorCode:  GraphQLCodeConflict,
+			expectedExtensions: `"candidates":[{"brand":"Nike","name":"Shoes","score":1,"sku":"FAL-1000001"}]`,
+		},
+		// Mutations are not allowed with GET
+		{
+			method:            http.MethodGet,
+			query:             `mutation { deleteProduct(sku: "FAL-1000001") }`,
+			caller:            &model.Caller{Subject: "test"},
+			expectedCode:      http.StatusMethodNotAllowed,
+			expectedErrorCode: GraphQLCodeBadRequest,
+		},
+		{
+			method:            http.MethodPost,
+			query:             `{ product(sku: "FAL-1000001") { unknown } }`,
+			expectedCode:      http.StatusBadRequest,
+			expectedErrorCode: GraphQLCodeValidationFailed,
+		},
+		{
+			method:            http.MethodPost,
+			query:             `{ product(sku: `,
+			expectedCode:      http.StatusBadRequest,
+			expectedErrorCode: GraphQLCodeParseFailed,
+		},
+		// Depth 4 (products > items > principalImage > url) exceeds the limit
+		{
+			method:            http.MethodPost,
+			query:             `fragment images on Product { principalImage { url } } { products { items { ...images } } }`,
+			expectedCode:      http.StatusBadRequest,
+			expectedErrorCode: GraphQLCodeTooDeep,
+		},
+		// Complexity 1 + 50 * (1 + 2) exceeds the limit
+		{
+			method:            http.MethodPost,
+			query:             `{ products(limit: 50) { items { sku name } } }`,
+			expectedCode:      http.StatusBadRequest,
+			expectedErrorCode: GraphQLCodeTooComplex,
+		},
+		// The introspection fields are not considered by the limits
+		{
+			method:       http.MethodPost,
+			query:        `{ __schema { types { name fields { name type { name ofType { name ofType { name } } } } } } }`,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	principalImage := model.URL{}
+	otherImage := model.URL{}
+
+	if err := principalImage.UnmarshalText([]byte("https://example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := otherImage.UnmarshalText([]byte("https://a.example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	storage := &repository.MockStorage[model.SKU, model.Product]{
+		"FAL-1000001": model.Product{SKU: "FAL-1000001", Name: "Shoes", Brand: "Nike", Price: 10, PrincipalImage: &principalImage, OtherImages: model.URLs{otherImage}, Status: model.StatusPublished},
+		"FAL-1000002": model.Product{SKU: "FAL-1000002", Name: "Shirt", Brand: "Nike", Price: 15, PrincipalImage: &principalImage, Status: model.StatusPublished},
+	}
+
+	detector, err := business.NewDuplicateDetector(0, storage, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := NewGraphQLSchema(business.ProductStore{
+		StorageManager: storage,
+		Sequences:      &repository.MockSequences{},
+		Duplicates:     detector,
+		Approvals:      &repository.MockApprovals{Storage: storage},
+		PriceApproval:  business.PriceApproval{Percentage: 50},
+	}, GraphQLConfig{MaxDepth: 3, MaxComplexity: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			engine := gin.New()
+			engine.Any("/graphql", func(c *gin.Context) {
+				if v.caller != nil {
+					c.Request = c.Request.WithContext(business.WithCaller(c.Request.Context(), *v.caller))
+				}
+			}, schema.GraphQL)
+
+			var request *http.Request
+
+			if v.method == http.MethodGet {
+				variables, _ := json.Marshal(v.variables)
+				query := url.Values{"query": {v.query}, "variables": {string(variables)}}
+				request, _ = http.NewRequest(http.MethodGet, "/graphql?"+query.Encode(), nil)
+			} else {
+				body, _ := json.Marshal(graphQLRequest{Query: v.query, Variables: v.variables})
+				request, _ = http.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+			}
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, request)
+
+			if w.Code != v.expectedCode {
+				t.Fatalf(`expected code '%d' unexpected code '%d': %s`, v.expectedCode, w.Code, w.Body.String())
+			}
+
+			response := struct {
+				Data   json.RawMessage `json:"data"`
+				Errors []struct {
+					Message    string         `json:"message"`
+					Extensions map[string]any `json:"extensions"`
+				} `json:"errors"`
+			}{}
+
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+
+			if v.expectedData != "" && !strings.Contains(string(response.Data), v.expectedData) {
+				t.Fatalf(`expected data '%s' got '%s'`, v.expectedData, response.Data)
+			}
+
+			if v.expectedErrorCode == "" {
+				if len(response.Errors) > 0 {
+					t.Fatalf(`unexpected errors: %s`, w.Body.String())
+				}
+
+				return
+			}
+
+			if len(response.Errors) == 0 || response.Errors[0].Extensions["code"] != v.expectedErrorCode {
+				t.Fatalf(`expected error code '%s' got: %s`, v.expectedErrorCode, w.Body.String())
+			}
+
+			if extensions, _ := json.Marshal(response.Errors[0].Extensions); !strings.Contains(string(extensions), v.expectedExtensions) {
+				t.Fatalf(`expected extensions '%s' got '%s'`, v.expectedExtensions, extensions)
+			}
+		})
+	}
+}
@@ -0,0 +1,536 @@
+package handler
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=github.com/yael-castro/products-api --go-grpc_out=../.. --go-grpc_opt=module=github.com/yael-castro/products-api products/v1/products.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/yael-castro/products-api/internal/business"
+	"github.com/yael-castro/products-api/internal/handler/productpb"
+	"github.com/yael-castro/products-api/internal/logging"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// _ "implements" constraint for ProductService
+var _ productpb.ProductServiceServer = ProductService{}
+
+// ProductService gRPC server of products.v1.ProductService, the operations are performed by the business.ProductManager
+//
+// The write operations require credentials, which are verified by the interceptor built by GRPCAuthentication
+type ProductService struct {
+	productpb.UnimplementedProductServiceServer
+	business.ProductManager
+}
+
+// CreateProduct adds a new product to the storage
+func (p ProductService) CreateProduct(ctx context.Context, request *productpb.CreateProductRequest) (*productpb.Product, error) {
+	if err := requireCaller(ctx); err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	product, err := productFromProto(request.GetProduct())
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	if err = p.ProductManager.CreateProduct(ctx, &product); err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	return productToProto(product), nil
+}
+
+// GetProduct obtains a product by its SKU
+func (p ProductService) GetProduct(ctx context.Context, request *productpb.GetProductRequest) (*productpb.Product, error) {
+	product, err := p.ProductManager.ObtainProduct(ctx, model.SKU(request.GetSku()))
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	return productToProto(product), nil
+}
+
+// Metadata keys of the price change held by UpdateProduct, they are sent as response headers
+const (
+	PriceChangeIDKey       = "price-change-id"
+	PriceChangeLocationKey = "price-change-location"
+)
+
+// UpdateProduct updates an existing product
+//
+// If the price change is held until it is approved, the response is the product with its current price
+// and the ID and the location of the pending price change are sent as response headers
+func (p ProductService) UpdateProduct(ctx context.Context, request *productpb.UpdateProductRequest) (*productpb.Product, error) {
+	if err := requireCaller(ctx); err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	product, err := productFromProto(request.GetProduct())
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	change, err := p.ProductManager.UpdateProduct(ctx, product)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	if change != nil {
+		product.Price = change.CurrentPrice
+
+		header := metadata.Pairs(
+			PriceChangeIDKey, strconv.FormatUint(uint64(change.ID), 10),
+			PriceChangeLocationKey, fmt.Sprintf("%s/%d", PriceChangesPath, change.ID),
+		)
+
+		if err = grpc.SetHeader(ctx, header); err != nil {
+			return nil, grpcError(ctx, err)
+		}
+	}
+
+	return productToProto(product), nil
+}
+
+// DeleteProduct removes a product from the storage
+func (p ProductService) DeleteProduct(ctx context.Context, request *productpb.DeleteProductRequest) (*productpb.DeleteProductResponse, error) {
+	if err := requireCaller(ctx); err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	if err := p.ProductManager.DeleteProduct(ctx, model.SKU(request.GetSku())); err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	return &productpb.DeleteProductResponse{}, nil
+}
+
+// listProductsBatch number of products read from the storage at once by ListProducts
+const listProductsBatch = 100
+
+// ListProducts streams every published product of the storage
+//
+// The products are read in batches of listProductsBatch, so the whole catalog is never held in memory
+func (p ProductService) ListProducts(_ *productpb.ListProductsRequest, stream grpc.ServerStreamingServer[productpb.Product]) error {
+	ctx := stream.Context()
+	filter := model.ProductFilter{Limit: listProductsBatch}
+
+	for {
+		page, err := p.ProductManager.ListProducts(ctx, filter)
+		if err != nil {
+			return grpcError(ctx, err)
+		}
+
+		for _, product := range page.Items {
+			if err = stream.Send(productToProto(product)); err != nil {
+				return err
+			}
+		}
+
+		if len(page.Items) == 0 || !page.HasNextPage(filter) {
+			return nil
+		}
+
+		filter.Offset += len(page.Items)
+	}
+}
+
+// productFromProto converts the product message into model.Product
+func productFromProto(message *productpb.Product) (model.Product, error) {
+	if message == nil {
+		return model.Product{}, error2.Validation("missing product")
+	}
+
+	product := model.Product{
+		SKU:   model.SKU(message.GetSku()),
+		Name:  message.GetName(),
+		Brand: message.GetBrand(),
+		Size:  message.Size,
+		Price: message.GetPrice(),
+	}
+
+	if message.GetPrincipalImage() != "" {
+		product.PrincipalImage = &model.URL{}
+
+		if err := product.PrincipalImage.UnmarshalText([]byte(message.GetPrincipalImage())); err != nil {
+			return product, error2.Validation(fmt.Sprintf(`invalid principal_image: %s`, err))
+		}
+	}
+
+	for _, rawURL := range message.GetOtherImages() {
+		image := model.URL{}
+
+		if err := image.UnmarshalText([]byte(rawURL)); err != nil {
+			return product, error2.Validation(fmt.Sprintf(`invalid other_images: %s`, err))
+		}
+
+		product.OtherImages = append(product.OtherImages, image)
+	}
+
+	return product, nil
+}
+
+// productToProto converts the model.Product into product message
+func productToProto(product model.Product) *productpb.Product {
+	message := &productpb.Product{
+		Sku:         string(product.SKU),
+		Name:        product.Name,
+		Brand:       product.Brand,
+		Size:        product.Size,
+		Price:       product.Price,
+		OtherImages: make([]string, 0, len(product.OtherImages)),
+	}
+
+	if product.PrincipalImage != nil {
+		message.PrincipalImage = product.PrincipalImage.String()
+	}
+
+	for _, image := range product.OtherImages {
+		message.OtherImages = append(message.OtherImages, image.String())
+	}
+
+	return message
+}
+
+// grpcError relates the domain errors to gRPC status codes, the unexpected errors are logged and hidden from the clients
+//
+// The errors are searched in the whole chain of err, so the wrapped domain errors keep their status codes
+func grpcError(ctx context.Context, err error) error {
+	switch {
+	case errorAs[error2.Validation](err), errorAs[error2.Violations](err), errorAs[error2.Unprocessable](err):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errorAs[error2.Unauthorized](err):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errorAs[error2.Forbidden](err):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errorAs[error2.NotFound](err):
+		return status.Error(codes.NotFound, err.Error())
+	case errorAs[error2.Duplicates](err):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errorAs[error2.Conflict](err):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errorAs[error2.TooLarge](err), errorAs[error2.TooManyRequests](err):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errorAs[error2.NotImplemented](err):
+		return status.Error(codes.Unimplemented, err.Error())
+	}
+
+	pgErr := &error2.PG{}
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return status.Error(codes.AlreadyExists, "duplicated record")
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, err.Error())
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	slog.ErrorContext(ctx, "grpc call failed", "error", err)
+	return status.Error(codes.Internal, "an unexpected error occurred")
+}
+
+// contextStream grpc.ServerStream whose context.Context was replaced by the interceptors
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the replaced context.Context
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// GRPCAuthentication interceptors that identify the clients using the *Authenticator, the credentials are sent as metadata
+// ("x-api-key" or "authorization" with Bearer token)
+//
+// Anonymous calls are allowed, each service is in charge of requiring credentials.
+// If the call contains credentials they must be valid
+func GRPCAuthentication(a *Authenticator) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	authenticate := func(ctx context.Context) (context.Context, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		caller, ok, err := a.authenticate(firstMetadata(md, strings.ToLower(APIKeyHeader)), firstMetadata(md, "authorization"))
+		if err != nil {
+			return ctx, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		if !ok {
+			return ctx, nil
+		}
+
+		return business.WithCaller(ctx, caller), nil
+	}
+
+	unary := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+
+	stream := func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, contextStream{ServerStream: ss, ctx: ctx})
+	}
+
+	return unary, stream
+}
+
+// GRPCLogger interceptors that log every call and recover from panics, the request ID is taken from the
+// "x-request-id" metadata, if it is missing or invalid a new one is generated
+func GRPCLogger(logger *slog.Logger) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	start := func(ctx context.Context) context.Context {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		id := firstMetadata(md, strings.ToLower(RequestIDHeader))
+		if !isValidRequestID(id) {
+			id = newRequestID()
+		}
+
+		return logging.WithRequestID(ctx, id)
+	}
+
+	finish := func(ctx context.Context, method string, started time.Time, err *error) {
+		if r := recover(); r != nil {
+			logger.ErrorContext(ctx, "panic recovered", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+			*err = status.Error(codes.Internal, "an unexpected error occurred")
+		}
+
+		code := status.Code(*err)
+
+		level := slog.LevelInfo
+		switch code {
+		case codes.OK, codes.NotFound, codes.InvalidArgument, codes.AlreadyExists, codes.Canceled:
+		case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+			level = slog.LevelError
+		default:
+			level = slog.LevelWarn
+		}
+
+		logger.LogAttrs(ctx, level, "grpc call",
+			slog.String("method", method),
+			slog.String("code", code.String()),
+			slog.Duration("latency", time.Since(started)),
+		)
+	}
+
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		ctx = start(ctx)
+		defer finish(ctx, info.FullMethod, time.Now(), &err)
+
+		return handler(ctx, req)
+	}
+
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx := start(ss.Context())
+		defer finish(ctx, info.FullMethod, time.Now(), &err)
+
+		return handler(srv, contextStream{ServerStream: ss, ctx: ctx})
+	}
+
+	return unary, stream
+}
+
+// firstMetadata returns the first value of the metadata key
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// grpcWriteMethods methods of the products.v1.ProductService limited by the write limits of the RateLimitConfig,
+// any other method is limited by the read limits
+var grpcWriteMethods = map[string]bool{
+	productpb.ProductService_CreateProduct_FullMethodName: true,
+	productpb.ProductService_UpdateProduct_FullMethodName: true,
+	productpb.ProductService_DeleteProduct_FullMethodName: true,
+}
+
+// GRPCRateLimit interceptors that limit the calls of each client using the same token buckets as the RateLimit middleware
+//
+// Clients are identified by the caller subject or by the peer IP address for anonymous clients,
+// so the interceptors must be used after the interceptors built by GRPCAuthentication.
+// The calls that exceed the limit are rejected with RESOURCE_EXHAUSTED code and the "retry-after" header
+func GRPCRateLimit(store RateLimitStore, config RateLimitConfig) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	limitCall := func(ctx context.Context, method string) error {
+		client := "ip:" + peerIP(ctx)
+		limits := config.RouteLimits
+
+		if caller, ok := business.CallerFrom(ctx); ok {
+			client = "caller:" + caller.Subject
+
+			if override, ok := config.Keys[caller.Subject]; ok {
+				limits = limits.override(override)
+			}
+		}
+
+		route, limit := "read", limits.Read
+		if grpcWriteMethods[method] {
+			route, limit = "write", limits.Write
+		}
+
+		if limit.IsZero() {
+			return nil
+		}
+
+		return takeGRPC(ctx, store, route+":"+client, limit)
+	}
+
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := limitCall(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := limitCall(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+
+	return unary, stream
+}
+
+// GRPCAuthenticationRateLimit interceptors that limit the calls with credentials of each peer IP address,
+// the calls without credentials are not limited
+//
+// The interceptors must be used before the interceptors built by GRPCAuthentication (see AuthenticationRateLimit)
+func GRPCAuthenticationRateLimit(store RateLimitStore, limit Limit) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	limitCall := func(ctx context.Context) error {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		if limit.IsZero() || (firstMetadata(md, strings.ToLower(APIKeyHeader)) == "" && firstMetadata(md, "authorization") == "") {
+			return nil
+		}
+
+		return takeGRPC(ctx, store, "authentication:ip:"+peerIP(ctx), limit)
+	}
+
+	unary := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := limitCall(ctx); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+
+	stream := func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := limitCall(ss.Context()); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+
+	return unary, stream
+}
+
+// takeGRPC takes a token from the bucket identified by the key, if the limit is exceeded it sends the "retry-after" header
+// and returns an error with RESOURCE_EXHAUSTED code
+func takeGRPC(ctx context.Context, store RateLimitStore, key string, limit Limit) error {
+	reservation, err := store.Take(ctx, key, limit)
+	if err != nil {
+		// The calls are allowed when the store is not available to avoid an outage caused by the rate limiter
+		slog.WarnContext(ctx, "rate limit store failed", "error", err)
+		return nil
+	}
+
+	if reservation.Allowed {
+		return nil
+	}
+
+	retryAfter := ceilSeconds(reservation.RetryAfter)
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter)))
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %d seconds", retryAfter)
+}
+
+// peerIP returns the IP address of the peer that made the call
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+// GRPCMetrics interceptors that record the number of calls and their latency by method and status code
+func GRPCMetrics(registerer prometheus.Registerer) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor, error) {
+	calls := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Number of gRPC calls handled by method and status code.",
+	}, []string{"method", "code"})
+
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Latency of the gRPC calls by method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	for _, collector := range []prometheus.Collector{calls, latency} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	record := func(method string, started time.Time, err error) {
+		code := status.Code(err).String()
+
+		calls.WithLabelValues(method, code).Inc()
+		latency.WithLabelValues(method, code).Observe(time.Since(started).Seconds())
+	}
+
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		started := time.Now()
+
+		resp, err := handler(ctx, req)
+		record(info.FullMethod, started, err)
+
+		return resp, err
+	}
+
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		started := time.Now()
+
+		err := handler(srv, ss)
+		record(info.FullMethod, started, err)
+
+		return err
+	}
+
+	return unary, stream, nil
+}
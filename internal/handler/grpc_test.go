@@ -0,0 +1,445 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/business"
+	"github.com/yael-castro/products-api/internal/handler/productpb"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"github.com/yael-castro/products-api/internal/repository"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestProductService(t *testing.T) {
+	const apiKey = "secret-api-key"
+
+	product := &productpb.Product{
+		Sku:            "FAL-1000002",
+		Name:           "Boots",
+		Brand:          "Puma",
+		Price:          20,
+		PrincipalImage: "https://example.com",
+	}
+
+	tdt := []struct {
+		call         func(context.Context, productpb.ProductServiceClient) error
+		apiKey       string
+		expectedCode codes.Code
+	}{
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				_, err := client.GetProduct(ctx, &productpb.GetProductRequest{Sku: "FAL-1000001"})
+				return err
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				_, err := client.GetProduct(ctx, &productpb.GetProductRequest{Sku: "FAL-9999999"})
+				return err
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				_, err := client.GetProduct(ctx, &productpb.GetProductRequest{Sku: "1234"})
+				return err
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		// Write operations require credentials
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				_, err := client.CreateProduct(ctx, &productpb.CreateProductRequest{Product: product})
+				return err
+			},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				_, err := client.DeleteProduct(ctx, &productpb.DeleteProductRequest{Sku: "FAL-1000001"})
+				return err
+			},
+			apiKey:       "invalid",
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				_, err := client.CreateProduct(ctx, &productpb.CreateProductRequest{Product: product})
+				return err
+			},
+			apiKey:       apiKey,
+			expectedCode: codes.OK,
+		},
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				_, err := client.CreateProduct(ctx, &productpb.CreateProductRequest{})
+				return err
+			},
+			apiKey:       apiKey,
+			expectedCode: codes.InvalidArgument,
+		},
+		// Every product is streamed
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				stream, err := client.ListProducts(ctx, &productpb.ListProductsRequest{})
+				if err != nil {
+					return err
+				}
+
+				count := 0
+
+				for {
+					_, err = stream.Recv()
+					if errors.Is(err, io.EOF) {
+						break
+					}
+
+					if err != nil {
+						return err
+					}
+
+					count++
+				}
+
+				if count != 2 {
+					return status.Errorf(codes.DataLoss, "expected 2 products got %d", count)
+				}
+
+				return nil
+			},
+			expectedCode: codes.OK,
+		},
+		// The price change is held until it is approved, the rest of the product is updated
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				var header metadata.MD
+
+				updated, err := client.UpdateProduct(ctx, &productpb.UpdateProductRequest{
+					Product: &productpb.Product{Sku: "FAL-1000003", Name: "Shirt", Brand: "Nike", Price: 1500, PrincipalImage: "https://example.com"},
+				}, grpc.Header(&header))
+				if err != nil {
+					return err
+				}
+
+				if updated.GetPrice() != 15 {
+					return status.Errorf(codes.DataLoss, "expected price 15 got %v", updated.GetPrice())
+				}
+
+				if location := header.Get(PriceChangeLocationKey); len(location) != 1 || location[0] != PriceChangesPath+"/1" {
+					return status.Errorf(codes.DataLoss, "unexpected price change location %v", location)
+				}
+
+				return nil
+			},
+			apiKey:       apiKey,
+			expectedCode: codes.OK,
+		},
+	}
+
+	hash := sha256.Sum256([]byte(apiKey))
+
+	authenticator, err := NewAuthenticator(AuthConfig{
+		APIKeys: []APIKey{{Name: "erp", Hash: hex.EncodeToString(hash[:])}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	image := &model.URL{}
+	if err = image.UnmarshalText([]byte("https://example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	unaryLogger, streamLogger := GRPCLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	unaryAuthentication, streamAuthentication := GRPCAuthentication(authenticator)
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryLogger, unaryAuthentication),
+		grpc.ChainStreamInterceptor(streamLogger, streamAuthentication),
+	)
+
+	storage := &repository.MockStorage[model.SKU, model.Product]{
+		"FAL-1000001": model.Product{SKU: "FAL-1000001", Name: "Shoes", Brand: "Nike", Price: 10, PrincipalImage: image, Status: model.StatusPublished},
+		"FAL-1000003": model.Product{SKU: "FAL-1000003", Name: "Shirt", Brand: "Nike", Price: 15, PrincipalImage: image, Status: model.StatusPublished},
+	}
+
+	productpb.RegisterProductServiceServer(server, ProductService{
+		ProductManager: business.ProductStore{
+			StorageManager: storage,
+			Approvals:      &repository.MockApprovals{Storage: storage},
+			PriceApproval:  business.PriceApproval{Percentage: 50},
+		},
+	})
+
+	listener := bufconn.Listen(1 << 20)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	client := productpb.NewProductServiceClient(conn)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := context.Background()
+			if v.apiKey != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", v.apiKey)
+			}
+
+			err := v.call(ctx, client)
+
+			if code := status.Code(err); code != v.expectedCode {
+				t.Fatalf(`expected code '%s' unexpected code '%s' (%v)`, v.expectedCode, code, err)
+			}
+		})
+	}
+}
+
+func TestGRPCRateLimit(t *testing.T) {
+	const apiKey = "secret-api-key"
+
+	tdt := []struct {
+		call         func(context.Context, productpb.ProductServiceClient) error
+		apiKey       string
+		expectedCode codes.Code
+	}{
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				_, err := client.GetProduct(ctx, &productpb.GetProductRequest{Sku: "FAL-1000001"})
+				return err
+			},
+			expectedCode: codes.OK,
+		},
+		// The anonymous read limit is exceeded
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				_, err := client.GetProduct(ctx, &productpb.GetProductRequest{Sku: "FAL-1000001"})
+				return err
+			},
+			expectedCode: codes.ResourceExhausted,
+		},
+		// The callers have their own buckets
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				_, err := client.GetProduct(ctx, &productpb.GetProductRequest{Sku: "FAL-1000001"})
+				return err
+			},
+			apiKey:       apiKey,
+			expectedCode: codes.OK,
+		},
+		// The streams are limited too
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				stream, err := client.ListProducts(ctx, &productpb.ListProductsRequest{})
+				if err != nil {
+					return err
+				}
+
+				_, err = stream.Recv()
+				return err
+			},
+			apiKey:       apiKey,
+			expectedCode: codes.ResourceExhausted,
+		},
+		// The attempts with credentials of the IP address are limited before authenticating them
+		{
+			call: func(ctx context.Context, client productpb.ProductServiceClient) error {
+				_, err := client.GetProduct(ctx, &productpb.GetProductRequest{Sku: "FAL-1000001"})
+				return err
+			},
+			apiKey:       "invalid",
+			expectedCode: codes.ResourceExhausted,
+		},
+	}
+
+	hash := sha256.Sum256([]byte(apiKey))
+
+	authenticator, err := NewAuthenticator(AuthConfig{
+		APIKeys: []APIKey{{Name: "erp", Hash: hex.EncodeToString(hash[:])}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemoryRateLimitStore()
+	config := RateLimitConfig{
+		RouteLimits:    RouteLimits{Read: Limit{Rate: 0.001, Burst: 1}},
+		Authentication: Limit{Rate: 0.001, Burst: 2},
+	}
+
+	unaryAuthenticationRateLimit, streamAuthenticationRateLimit := GRPCAuthenticationRateLimit(store, config.Authentication)
+	unaryAuthentication, streamAuthentication := GRPCAuthentication(authenticator)
+	unaryRateLimit, streamRateLimit := GRPCRateLimit(store, config)
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryAuthenticationRateLimit, unaryAuthentication, unaryRateLimit),
+		grpc.ChainStreamInterceptor(streamAuthenticationRateLimit, streamAuthentication, streamRateLimit),
+	)
+
+	storage := &repository.MockStorage[model.SKU, model.Product]{
+		"FAL-1000001": model.Product{SKU: "FAL-1000001", Name: "Shoes", Brand: "Nike", Price: 10, Status: model.StatusPublished},
+	}
+
+	productpb.RegisterProductServiceServer(server, ProductService{
+		ProductManager: business.ProductStore{StorageManager: storage},
+	})
+
+	listener := bufconn.Listen(1 << 20)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	client := productpb.NewProductServiceClient(conn)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			ctx := context.Background()
+			if v.apiKey != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", v.apiKey)
+			}
+
+			err := v.call(ctx, client)
+
+			if code := status.Code(err); code != v.expectedCode {
+				t.Fatalf(`expected code '%s' unexpected code '%s' (%v)`, v.expectedCode, code, err)
+			}
+		})
+	}
+}
+
+func TestGRPCError(t *testing.T) {
+	tdt := []struct {
+		err          error
+		expectedCode codes.Code
+	}{
+		{err: fmt.Errorf("obtaining product: %w", error2.NotFound("product not found")), expectedCode: codes.NotFound},
+		{err: fmt.Errorf("creating product: %w", error2.Invalid("sku", "invalid sku")), expectedCode: codes.InvalidArgument},
+		{err: error2.Duplicates{}, expectedCode: codes.AlreadyExists},
+		{err: error2.TooLarge("the image is too large"), expectedCode: codes.ResourceExhausted},
+		{err: error2.NotImplemented("not implemented"), expectedCode: codes.Unimplemented},
+		{err: errors.New("unexpected"), expectedCode: codes.Internal},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := grpcError(context.Background(), v.err)
+
+			if code := status.Code(err); code != v.expectedCode {
+				t.Fatalf(`expected code '%s' unexpected code '%s' (%v)`, v.expectedCode, code, err)
+			}
+		})
+	}
+}
+
+func TestProductService_ListProducts(t *testing.T) {
+	// The products are more than a batch, so they are read from the storage in several batches
+	storage := repository.MockStorage[model.SKU, model.Product]{}
+
+	for i := range 2*listProductsBatch + 1 {
+		sku := model.SKU(fmt.Sprintf("FAL-%07d", 1000000+i))
+		storage[sku] = model.Product{SKU: sku, Name: "Shoes", Brand: "Nike", Price: 10, Status: model.StatusPublished}
+	}
+
+	server := grpc.NewServer()
+
+	productpb.RegisterProductServiceServer(server, ProductService{
+		ProductManager: business.ProductStore{StorageManager: &storage},
+	})
+
+	listener := bufconn.Listen(1 << 20)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	stream, err := productpb.NewProductServiceClient(conn).ListProducts(context.Background(), &productpb.ListProductsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	skus := make(map[string]bool)
+
+	for {
+		product, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		skus[product.GetSku()] = true
+	}
+
+	if len(skus) != len(storage) {
+		t.Fatalf("expected %d products unexpected %d products", len(storage), len(skus))
+	}
+}
@@ -2,17 +2,20 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/cors"
-	error2 "github.com/yael-castro/products-api/internal/model/error"
 	"net/http"
 )
 
 // Handler defines the main handler that contains all *gin.HandlerFunc
 type Handler interface {
 	ProductManager
+	MediaServer
+	Monitor
+	Documentation
+	GraphQLExecutor
 }
 
 // ProductManager defines the *gin.HandlerFunc group to manage the http requests related to product management
@@ -27,6 +30,70 @@ type ProductManager interface {
 	DeleteProduct(*gin.Context)
 	// ObtainProducts handle http requests to list products
 	ObtainProducts(*gin.Context)
+	// BatchGetProducts handle http requests to obtain several products at once
+	BatchGetProducts(*gin.Context)
+	// ReserveSKUs handle http requests to reserve blocks of SKUs for offline use
+	ReserveSKUs(*gin.Context)
+	// UploadImage handle http requests to upload an image of a product
+	UploadImage(*gin.Context)
+	// SubmitProduct handle http requests to submit a draft product to review
+	SubmitProduct(*gin.Context)
+	// RejectProduct handle http requests to return a product in review to draft
+	RejectProduct(*gin.Context)
+	// PublishProduct handle http requests to publish a product
+	PublishProduct(*gin.Context)
+	// DiscontinueProduct handle http requests to discontinue a product
+	DiscontinueProduct(*gin.Context)
+	// ArchiveProduct handle http requests to archive a product
+	ArchiveProduct(*gin.Context)
+	// ObtainTransitions handle http requests to obtain the status history of a product
+	ObtainTransitions(*gin.Context)
+	// ListPriceChanges handle http requests to list the price changes held until they are approved
+	ListPriceChanges(*gin.Context)
+	// ObtainPriceChange handle http requests to obtain a price change
+	ObtainPriceChange(*gin.Context)
+	// ApprovePriceChange handle http requests to approve a pending price change
+	ApprovePriceChange(*gin.Context)
+	// RejectPriceChange handle http requests to reject a pending price change
+	RejectPriceChange(*gin.Context)
+	// StartDuplicateReport handle http requests to start the report of the suspected duplicate products
+	StartDuplicateReport(*gin.Context)
+	// ObtainDuplicateReport handle http requests to obtain the report of the suspected duplicate products
+	ObtainDuplicateReport(*gin.Context)
+}
+
+// MediaServer defines the *gin.HandlerFunc to handle the http requests made to obtain the media served by the API
+type MediaServer interface {
+	// ObtainMedia handle http requests to obtain a media (e.g. a product image or its thumbnails)
+	ObtainMedia(*gin.Context)
+}
+
+// Monitor defines the *gin.HandlerFunc group to handle the http requests related to server monitoring
+type Monitor interface {
+	// Healthz handle http requests made by the liveness probe
+	Healthz(*gin.Context)
+	// Readyz handle http requests made by the readiness probe
+	Readyz(*gin.Context)
+	// Metrics handle http requests to expose the server metrics
+	Metrics(*gin.Context)
+}
+
+// Documentation defines the *gin.HandlerFunc group to handle the http requests related to the API documentation
+type Documentation interface {
+	// OpenAPIYAML handle http requests to obtain the OpenAPI document in YAML format
+	OpenAPIYAML(*gin.Context)
+	// OpenAPIJSON handle http requests to obtain the OpenAPI document in JSON format
+	OpenAPIJSON(*gin.Context)
+	// Explorer handle http requests to the interactive API explorer
+	Explorer(*gin.Context)
+	// ProblemType handle http requests to obtain the description of a problem type
+	ProblemType(*gin.Context)
+}
+
+// GraphQLExecutor defines the *gin.HandlerFunc to handle the http requests made to the GraphQL endpoint
+type GraphQLExecutor interface {
+	// GraphQL handle http requests to execute GraphQL operations
+	GraphQL(*gin.Context)
 }
 
 // _ "implements" constraint for Groups
@@ -35,62 +102,106 @@ var _ Handler = Groups{}
 // Groups is the collection of all *gin.HandlerFunc used to initialize the *gin.Engine
 type Groups struct {
 	ProductManager
+	MediaServer
+	Monitor
+	Documentation
+	GraphQLExecutor
 }
 
 // NewHttpHandler using an instance of Handler initializes the *gin.Engine
-func NewHttpHandler(h Handler) http.Handler {
-	engine := gin.Default()
+//
+// The middlewares are used by every route in the same order they are received
+func NewHttpHandler(h Handler, middlewares ...gin.HandlerFunc) http.Handler {
+	return cors.AllowAll().Handler(newEngine(h, middlewares...))
+}
+
+// newEngine initializes the *gin.Engine with the routes described by the OpenAPI document (swagger.yaml)
+//
+// The requests made to the paths with trailing slash (e.g. /v1/products/) are redirected to the paths without it
+func newEngine(h Handler, middlewares ...gin.HandlerFunc) *gin.Engine {
+	engine := gin.New()
+	engine.Use(middlewares...)
 
 	// Default handlers
 	engine.NoRoute(NotFound)
-	engine.GET("/", HealthCheck)
+	engine.GET("/", h.Healthz)
+	engine.GET("/healthz", h.Healthz)
+	engine.GET("/readyz", h.Readyz)
+
+	engine.GET("/openapi.yaml", h.OpenAPIYAML)
+	engine.GET("/openapi.json", h.OpenAPIJSON)
+	engine.GET("/docs", h.Explorer)
+	engine.GET("/problems/:code", h.ProblemType)
+	engine.GET("/metrics", h.Metrics)
 
-	engine.POST("/v1/products/", h.CreateProduct)
+	engine.POST("/v1/products", h.CreateProduct)
 
-	engine.GET("/v1/products/", h.ObtainProducts)
+	engine.GET("/v1/products", h.ObtainProducts)
 	engine.GET("/v1/products/:id", h.ObtainProduct)
+	engine.POST("/v1/products:batchGet", customMethod("batchGet"), h.BatchGetProducts)
 
-	engine.PUT("/v1/products/", h.UpdateProduct)
+	engine.PUT("/v1/products", h.UpdateProduct)
 
 	engine.DELETE("/v1/products/:id", h.DeleteProduct)
 
-	return cors.AllowAll().Handler(engine)
+	engine.POST("/v1/products/:id/images/upload", h.UploadImage)
+
+	engine.POST("/v1/products/:id/submit", h.SubmitProduct)
+	engine.POST("/v1/products/:id/reject", h.RejectProduct)
+	engine.POST("/v1/products/:id/publish", h.PublishProduct)
+	engine.POST("/v1/products/:id/discontinue", h.DiscontinueProduct)
+	engine.POST("/v1/products/:id/archive", h.ArchiveProduct)
+	engine.GET("/v1/products/:id/transitions", h.ObtainTransitions)
+
+	engine.GET(PriceChangesPath, h.ListPriceChanges)
+	engine.GET(PriceChangesPath+"/:id", h.ObtainPriceChange)
+	engine.POST("/v1/price-changes/:id/approve", h.ApprovePriceChange)
+	engine.POST("/v1/price-changes/:id/reject", h.RejectPriceChange)
+
+	engine.POST("/v1/reports/duplicates", h.StartDuplicateReport)
+	engine.GET("/v1/reports/duplicates", h.ObtainDuplicateReport)
+
+	engine.POST("/v1/skus:reserve", customMethod("reserve"), h.ReserveSKUs)
+
+	engine.GET("/media/*key", h.ObtainMedia)
+
+	engine.GET("/graphql", h.GraphQL)
+	engine.POST("/graphql", h.GraphQL)
+
+	return engine
 }
 
-// HealthCheck is the default *gin.HandlerFunc used to know the health server and monitoring the server status
-func HealthCheck(c *gin.Context) {
-	_, _ = c.Writer.Write(nil)
+// customMethod checks the custom method of routes like /v1/products:batchGet
+//
+// gin does not support literal colons into the routes, so the custom method is captured as a parameter
+// named like the method (e.g. "batchGet" captures ":batchGet"). The requests made to other paths are handled by NotFound
+func customMethod(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Param(name) != ":"+name {
+			NotFound(c)
+			c.Abort()
+		}
+	}
 }
 
 // NotFound is the default *gin.HandlerFunc used to handle http requests made to non exist paths
 func NotFound(c *gin.Context) {
-	c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf(`path '%s' does not exist`, c.Request.URL.Path)})
+	respondProblem(c, newProblem(c, CodeRouteNotFound, fmt.Sprintf(`path '%s' does not exist`, c.Request.URL.Path)))
 }
 
-// handleError handles errors and related it to http response codes
-func handleError(c *gin.Context, err error) {
-	switch err.(type) {
-	case error2.Validation:
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
-
-	case error2.NotFound:
-		c.JSON(http.StatusNotFound, gin.H{"error": err})
-
-	case *json.MarshalerError:
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-
-	case *json.SyntaxError:
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+// errorAs indicates if some error of the chain of err has the type T (see errors.As)
+func errorAs[T error](err error) bool {
+	var target T
+	return errors.As(err, &target)
+}
 
-	case *error2.PG:
-		switch err.(*error2.PG).Code {
-		case "23505":
-			c.JSON(http.StatusConflict, gin.H{"error": "duplicated record"})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "an unexpected error related to storage occurred"})
-		}
+// handleError relates the error to a Problem and writes it (see problemFrom)
+func handleError(c *gin.Context, err error) {
+	problem := problemFrom(c, err)
 
-	default:
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if problem.Status == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", `Bearer realm="products-api"`)
 	}
+
+	respondProblem(c, problem)
 }
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultCheckTimeout timeout used by Health when it does not define one
+const defaultCheckTimeout = 2 * time.Second
+
+// Statuses reported by the probes
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+	StatusDraining = "draining"
+)
+
+// Check verifies the status of a dependency, it returns an error if the dependency is not available
+type Check func(context.Context) error
+
+// CheckResult status of a dependency reported by the readiness probe, the errors are only logged
+// because the probe is public and they could reveal details of the infrastructure (e.g. the database host)
+type CheckResult struct {
+	Status string `json:"status"`
+}
+
+// Readiness body of the readiness probe responses
+type Readiness struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Health liveness and readiness probes
+//
+// The readiness probe runs every Check concurrently, each one limited by Timeout,
+// the server is ready only if every dependency is available and the server is not draining
+type Health struct {
+	// Checks checks of the dependencies indexed by name
+	Checks map[string]Check
+	// Timeout maximum duration of each Check, default value 2s
+	Timeout time.Duration
+	// Logger logs the errors of the checks, if it is nil slog.Default is used
+	Logger *slog.Logger
+	// draining indicates that the server is shutting down
+	draining atomic.Bool
+}
+
+// Drain marks the server as draining, from now on the readiness probe fails so that no more traffic is routed to the server
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Healthz gin.HandlerFunc for the liveness probe, it responds 200 status code while the process is able to serve requests
+func (h *Health) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": StatusUp})
+}
+
+// Readyz gin.HandlerFunc for the readiness probe, it responds 200 status code if the server is ready to receive traffic
+// otherwise 503 status code, in both cases the body contains the status of each dependency
+func (h *Health) Readyz(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, Readiness{Status: StatusDraining})
+		return
+	}
+
+	readiness := h.check(c.Request.Context())
+	if readiness.Status != StatusReady {
+		c.JSON(http.StatusServiceUnavailable, readiness)
+		return
+	}
+
+	c.JSON(http.StatusOK, readiness)
+}
+
+// logger returns the Logger or slog.Default if it is nil
+func (h *Health) logger() *slog.Logger {
+	if h.Logger == nil {
+		return slog.Default()
+	}
+
+	return h.Logger
+}
+
+// check runs the checks concurrently and gathers its results
+func (h *Health) check(ctx context.Context) Readiness {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+
+	readiness := Readiness{Status: StatusReady, Checks: make(map[string]CheckResult, len(h.Checks))}
+
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for name, check := range h.Checks {
+		wg.Add(1)
+
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			result := CheckResult{Status: StatusUp}
+
+			if err := check(ctx); err != nil {
+				result = CheckResult{Status: StatusDown}
+				h.logger().ErrorContext(ctx, "dependency check failed", "check", name, "error", err)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			readiness.Checks[name] = result
+			if result.Status != StatusUp {
+				readiness.Status = StatusNotReady
+			}
+		}(name, check)
+	}
+
+	wg.Wait()
+	return readiness
+}
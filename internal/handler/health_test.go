@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealth_Readyz(t *testing.T) {
+	tdt := []struct {
+		checks            map[string]Check
+		draining          bool
+		expectedCode      int
+		expectedReadiness Readiness
+	}{
+		{
+			checks: map[string]Check{
+				"database": func(context.Context) error { return nil },
+			},
+			expectedCode: http.StatusOK,
+			expectedReadiness: Readiness{
+				Status: StatusReady,
+				Checks: map[string]CheckResult{"database": {Status: StatusUp}},
+			},
+		},
+		{
+			checks: map[string]Check{
+				"database":   func(context.Context) error { return nil },
+				"migrations": func(context.Context) error { return errors.New("schema is outdated") },
+			},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedReadiness: Readiness{
+				Status: StatusNotReady,
+				Checks: map[string]CheckResult{
+					"database":   {Status: StatusUp},
+					"migrations": {Status: StatusDown},
+				},
+			},
+		},
+		// The check takes longer than the timeout
+		{
+			checks: map[string]Check{
+				"database": func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedReadiness: Readiness{
+				Status: StatusNotReady,
+				Checks: map[string]CheckResult{"database": {Status: StatusDown}},
+			},
+		},
+		{
+			checks: map[string]Check{
+				"database": func(context.Context) error { return nil },
+			},
+			draining:          true,
+			expectedCode:      http.StatusServiceUnavailable,
+			expectedReadiness: Readiness{Status: StatusDraining},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			health := &Health{Checks: v.checks, Timeout: 10 * time.Millisecond}
+			if v.draining {
+				health.Drain()
+			}
+
+			engine := gin.New()
+			engine.GET("/readyz", health.Readyz)
+
+			w := httptest.NewRecorder()
+			request, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+			engine.ServeHTTP(w, request)
+
+			if w.Code != v.expectedCode {
+				t.Fatalf(`expected status code %d got %d`, v.expectedCode, w.Code)
+			}
+
+			// The errors of the checks are only logged
+			if strings.Contains(w.Body.String(), "error") {
+				t.Fatalf(`unexpected error in the body: %s`, w.Body.String())
+			}
+
+			readiness := Readiness{}
+
+			if err := json.Unmarshal(w.Body.Bytes(), &readiness); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(readiness, v.expectedReadiness) {
+				t.Fatalf(`expected readiness %+v got %+v`, v.expectedReadiness, readiness)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+)
+
+// jsonWebKey public key from a JSON Web Key Set (RFC 7517)
+type jsonWebKey struct {
+	// id value of the "kid" parameter
+	id string
+	// algorithm JWT algorithm that the key is able to verify
+	algorithm string
+	// key *rsa.PublicKey or *ecdsa.PublicKey
+	key jwt.VerificationKey
+}
+
+// jsonWebKeys list of jsonWebKey
+type jsonWebKeys []jsonWebKey
+
+// lookup returns the keys for the algorithm, if the key ID is not blank only the keys with the same ID are returned
+func (k jsonWebKeys) lookup(algorithm, id string) []jwt.VerificationKey {
+	keys := make([]jwt.VerificationKey, 0, 1)
+
+	for _, key := range k {
+		if key.algorithm != algorithm || (id != "" && key.id != id) {
+			continue
+		}
+
+		keys = append(keys, key.key)
+	}
+
+	return keys
+}
+
+// parseJWKS parses the RSA keys (used to verify RS256) and the P-256 EC keys (used to verify ES256) from the JSON Web Key Set
+//
+// The keys whose "use" is not "sig" are ignored
+func parseJWKS(data []byte) (jsonWebKeys, error) {
+	set := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Alg string `json:"alg"`
+			// RSA parameters
+			N string `json:"n"`
+			E string `json:"e"`
+			// EC parameters
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}{}
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(jsonWebKeys, 0, len(set.Keys))
+
+	for _, v := range set.Keys {
+		if v.Use != "" && v.Use != "sig" {
+			continue
+		}
+
+		switch v.Kty {
+		case "RSA":
+			n, err := decodeBigInt(v.N)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid RSA key '%s': %w`, v.Kid, err)
+			}
+
+			e, err := decodeBigInt(v.E)
+			if err != nil || !e.IsInt64() {
+				return nil, fmt.Errorf(`invalid RSA key '%s': invalid exponent`, v.Kid)
+			}
+
+			keys = append(keys, jsonWebKey{
+				id:        v.Kid,
+				algorithm: jwt.SigningMethodRS256.Alg(),
+				key:       &rsa.PublicKey{N: n, E: int(e.Int64())},
+			})
+
+		case "EC":
+			if v.Crv != "P-256" {
+				return nil, fmt.Errorf(`invalid EC key '%s': curve '%s' is not supported`, v.Kid, v.Crv)
+			}
+
+			x, err := decodeBigInt(v.X)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid EC key '%s': %w`, v.Kid, err)
+			}
+
+			y, err := decodeBigInt(v.Y)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid EC key '%s': %w`, v.Kid, err)
+			}
+
+			if !elliptic.P256().IsOnCurve(x, y) {
+				return nil, fmt.Errorf(`invalid EC key '%s': the point is not on the curve`, v.Kid)
+			}
+
+			keys = append(keys, jsonWebKey{
+				id:        v.Kid,
+				algorithm: jwt.SigningMethodES256.Alg(),
+				key:       &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y},
+			})
+
+		default:
+			return nil, fmt.Errorf(`invalid key '%s': key type '%s' is not supported`, v.Kid, v.Kty)
+		}
+	}
+
+	return keys, nil
+}
+
+// decodeBigInt decodes the base64url encoded big-endian integers used by JSON Web Keys
+func decodeBigInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing parameter")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
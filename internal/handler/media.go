@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yael-castro/products-api/internal/business"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// _ "implements" constraint for MediaStore
+var _ MediaServer = MediaStore{}
+
+// MediaStore contains the gin.HandlerFunc to serve the media of the API (e.g. the product images and their thumbnails)
+type MediaStore struct {
+	business.MediaManager
+}
+
+// ObtainMedia gin.HandlerFunc to handle http requests made to obtain a media, e.g. "/media/<sha256>/original.png"
+//
+// The media are identified by their content, so they are cached by the clients without revalidation.
+// The conditional and range requests are supported
+func (m MediaStore) ObtainMedia(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	blob, err := m.MediaManager.ObtainMedia(c.Request.Context(), key)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	defer blob.Content.Close()
+
+	if blob.ContentType != "" {
+		c.Header("Content-Type", blob.ContentType)
+	}
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	http.ServeContent(c.Writer, c.Request, path.Base(key), blob.ModTime, blob.Content)
+}
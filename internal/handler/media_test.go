@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/yael-castro/products-api/internal/business"
+	"github.com/yael-castro/products-api/internal/model"
+	"github.com/yael-castro/products-api/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMediaStore_ObtainMedia(t *testing.T) {
+	tdt := []struct {
+		path                string
+		headers             map[string]string
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			path:                "/media/abc/original.png",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "image/png",
+			expectedBody:        "png image",
+		},
+		{
+			path:                "/media/abc/original.png",
+			headers:             map[string]string{"Range": "bytes=0-2"},
+			expectedCode:        http.StatusPartialContent,
+			expectedContentType: "image/png",
+			expectedBody:        "png",
+		},
+		{
+			path:                "/media/abc/160w.png",
+			expectedCode:        http.StatusNotFound,
+			expectedContentType: "application/problem+json",
+		},
+		{
+			path:                "/media/../original.png",
+			expectedCode:        http.StatusNotFound,
+			expectedContentType: "application/problem+json",
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	if *verbose {
+		gin.SetMode(gin.DebugMode)
+	}
+
+	blobs := &repository.MockBlobs{}
+	if err := blobs.Put(context.Background(), "abc/original.png", strings.NewReader("png image")); err != nil {
+		t.Fatal(err)
+	}
+
+	storage := &repository.MockStorage[model.SKU, model.Product]{}
+
+	ingester, err := business.NewImageIngester(business.MediaConfig{BaseURL: "https://api.example.com"}, storage, &repository.MockImages{Storage: storage}, blobs, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ingester.Close(context.Background())
+
+	engine := gin.New()
+	engine.GET("/media/*key", MediaStore{MediaManager: ingester}.ObtainMedia)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, v.path, nil)
+			for key, value := range v.headers {
+				request.Header.Set(key, value)
+			}
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, request)
+
+			if w.Code != v.expectedCode {
+				t.Fatalf(`expected code '%d' unexpected code '%d'`, v.expectedCode, w.Code)
+			}
+
+			if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, v.expectedContentType) {
+				t.Fatalf(`expected content type '%s' unexpected content type '%s'`, v.expectedContentType, contentType)
+			}
+
+			if v.expectedBody != "" && w.Body.String() != v.expectedBody {
+				t.Fatalf(`expected body '%s' unexpected body '%s'`, v.expectedBody, w.Body.String())
+			}
+
+			t.Log(w.Body.String())
+		})
+	}
+}
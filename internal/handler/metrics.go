@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"strconv"
+	"time"
+)
+
+// unmatchedRoute route label used for the requests that do not match any route, it keeps bounded the number of label values
+const unmatchedRoute = "unmatched"
+
+// Metrics middleware that records the number of requests and their latency by method, route and status code
+//
+// The route label is the route matched by gin (e.g. "/v1/products/:id") instead of the request path
+func Metrics(registerer prometheus.Registerer) (gin.HandlerFunc, error) {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests handled by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of the HTTP requests by method, route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	for _, collector := range []prometheus.Collector{requests, latency} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		status := strconv.Itoa(c.Writer.Status())
+
+		requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		latency.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}, nil
+}
+
+// _ "implements" constraint for Monitoring
+var _ Monitor = Monitoring{}
+
+// Monitoring contains the group of gin.HandlerFunc for handle requests related to server monitoring
+type Monitoring struct {
+	prometheus.Gatherer
+	*Health
+}
+
+// Metrics gin.HandlerFunc to handle http requests made to obtain the metrics in Prometheus text format
+func (m Monitoring) Metrics(c *gin.Context) {
+	promhttp.HandlerFor(m.Gatherer, promhttp.HandlerOpts{}).ServeHTTP(c.Writer, c.Request)
+}
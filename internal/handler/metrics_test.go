@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	tdt := []struct {
+		path           string
+		expectedMetric string
+	}{
+		{
+			path:           "/v1/products/FAL-12345678",
+			expectedMetric: `http_requests_total{method="GET",route="/v1/products/:id",status="200"} 2`,
+		},
+		{
+			path:           "/v1/products/FAL-87654321",
+			expectedMetric: `http_requests_total{method="GET",route="/v1/products/:id",status="200"} 2`,
+		},
+		{
+			path:           "/unknown/path",
+			expectedMetric: `http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	registry := prometheus.NewRegistry()
+
+	metrics, err := Metrics(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := gin.New()
+	engine.Use(metrics)
+	engine.GET("/v1/products/:id", func(c *gin.Context) {})
+	engine.GET("/metrics", Monitoring{Gatherer: registry}.Metrics)
+
+	for _, v := range tdt {
+		request, _ := http.NewRequest(http.MethodGet, v.path, nil)
+		engine.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	engine.ServeHTTP(w, request)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if !strings.Contains(w.Body.String(), v.expectedMetric) {
+				t.Fatalf(`missing metric '%s'`, v.expectedMetric)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"github.com/yael-castro/products-api/internal/logging"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader header used to receive and propagate the request ID
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength maximum length allowed for the request IDs received from clients
+const maxRequestIDLength = 128
+
+// DefaultRedactedHeaders headers whose values are never written into the logs because they contain credentials
+var DefaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+}
+
+// RequestID middleware that identifies each request, the request ID is taken from the X-Request-ID header
+// or generated if the header is missing or invalid
+//
+// The request ID is written into the X-Request-ID response header and carried by the request context.Context,
+// so every log record related to the request includes it
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !isValidRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// isValidRequestID indicates if the request ID received from the client is not blank, is not too large and only contains visible ASCII characters
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Logger middleware that writes a log record for each request once it was handled
+//
+// The values of the DefaultRedactedHeaders and the redactedHeaders are replaced before writing the request headers
+func Logger(logger *slog.Logger, redactedHeaders ...string) gin.HandlerFunc {
+	redacted := make(map[string]struct{})
+
+	for _, header := range DefaultRedactedHeaders {
+		redacted[http.CanonicalHeaderKey(header)] = struct{}{}
+	}
+
+	for _, header := range redactedHeaders {
+		redacted[http.CanonicalHeaderKey(header)] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		ctx := c.Request.Context()
+		if !logger.Enabled(ctx, level) {
+			return
+		}
+
+		headers := make([]any, 0, len(c.Request.Header))
+		for key, values := range c.Request.Header {
+			if _, ok := redacted[key]; ok {
+				headers = append(headers, slog.String(key, "[REDACTED]"))
+				continue
+			}
+
+			headers = append(headers, slog.Any(key, values))
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.String("query", c.Request.URL.RawQuery),
+			slog.Int("status", status),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Group("headers", headers...),
+		}
+
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+
+		logger.LogAttrs(ctx, level, "http request handled", attrs...)
+	}
+}
+
+// Recovery middleware that recovers from any panic, logs it and responds with 500 status code.
+// If the response was not written yet, the body is the Problem of an internal error
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.ErrorContext(c.Request.Context(), "panic recovered", "panic", r)
+
+				if !c.Writer.Written() {
+					respondProblem(c, newProblem(c, CodeInternalError, "an unexpected error occurred"))
+				}
+
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}
+		}()
+
+		c.Next()
+	}
+}
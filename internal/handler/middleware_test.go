@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/yael-castro/products-api/internal/logging"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	tdt := []struct {
+		requestID   string
+		expectReuse bool
+	}{
+		{
+			requestID:   "6f1d8b0c-4a5e-4c1b-9d3a-1f2e3d4c5b6a",
+			expectReuse: true,
+		},
+		{
+			requestID: "",
+		},
+		{
+			requestID: "invalid request id",
+		},
+		{
+			requestID: strings.Repeat("a", maxRequestIDLength+1),
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var ctxRequestID string
+
+			engine := gin.New()
+			engine.Use(RequestID())
+			engine.GET("/", func(c *gin.Context) {
+				ctxRequestID = logging.RequestID(c.Request.Context())
+			})
+
+			request, _ := http.NewRequest(http.MethodGet, "/", nil)
+			request.Header.Set(RequestIDHeader, v.requestID)
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, request)
+
+			requestID := w.Header().Get(RequestIDHeader)
+			if requestID == "" {
+				t.Fatal("missing request id")
+			}
+
+			if requestID != ctxRequestID {
+				t.Fatalf(`expected request id '%s' unexpected request id '%s'`, requestID, ctxRequestID)
+			}
+
+			if (requestID == v.requestID) != v.expectReuse {
+				t.Fatalf(`unexpected request id '%s' for the received request id '%s'`, requestID, v.requestID)
+			}
+
+			t.Log(requestID)
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	tdt := []struct {
+		headers         http.Header
+		redactedHeaders []string
+		expectedHeaders map[string]any
+	}{
+		{
+			headers: http.Header{
+				"Authorization": {"Bearer secret"},
+				"Accept":        {"application/json"},
+			},
+			expectedHeaders: map[string]any{
+				"Authorization": "[REDACTED]",
+				"Accept":        []any{"application/json"},
+			},
+		},
+		{
+			headers: http.Header{
+				"X-Tenant-Secret": {"secret"},
+			},
+			redactedHeaders: []string{"x-tenant-secret"},
+			expectedHeaders: map[string]any{
+				"X-Tenant-Secret": "[REDACTED]",
+			},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			engine := gin.New()
+			engine.Use(RequestID(), Logger(logging.NewLogger(buf, slog.LevelInfo), v.redactedHeaders...))
+			engine.GET("/v1/products/:id", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			request, _ := http.NewRequest(http.MethodGet, "/v1/products/FAL-12345678", nil)
+			request.Header = v.headers
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, request)
+
+			record := struct {
+				RequestID string         `json:"request_id"`
+				Route     string         `json:"route"`
+				Status    int            `json:"status"`
+				Headers   map[string]any `json:"headers"`
+			}{}
+
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatal(err)
+			}
+
+			if record.RequestID != w.Header().Get(RequestIDHeader) {
+				t.Fatalf(`expected request id '%s' unexpected request id '%s'`, w.Header().Get(RequestIDHeader), record.RequestID)
+			}
+
+			if record.Route != "/v1/products/:id" {
+				t.Fatalf(`unexpected route '%s'`, record.Route)
+			}
+
+			for key, expected := range v.expectedHeaders {
+				got, _ := json.Marshal(record.Headers[key])
+				want, _ := json.Marshal(expected)
+
+				if !bytes.Equal(got, want) {
+					t.Fatalf(`expected header '%s' with value '%s' unexpected value '%s'`, key, want, got)
+				}
+			}
+
+			t.Log(buf.String())
+		})
+	}
+}
@@ -0,0 +1,436 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MIMECSV Content-Type MIME for CSV, which is not defined by the binding package
+const MIMECSV = "text/csv"
+
+// encoder encodes response bodies using a specific media type
+type encoder struct {
+	// mediaType MIME type written in the Content-Type header
+	mediaType string
+	// supports indicates if the encoder is able to encode the value
+	supports func(any) bool
+	// renderer builds the render.Render used to write the value
+	renderer func(mediaType string, v any) render.Render
+}
+
+// encoders supported encoders for responses, the first one is used when the client accepts any media type
+var encoders = [...]encoder{
+	{
+		mediaType: binding.MIMEJSON,
+		supports:  anyValue,
+		renderer: func(_ string, v any) render.Render {
+			return render.JSON{Data: unwrapSparse(v)}
+		},
+	},
+	{
+		mediaType: binding.MIMEXML,
+		supports:  anyValue,
+		renderer: func(mediaType string, v any) render.Render {
+			return xmlRender{mediaType: mediaType, data: v}
+		},
+	},
+	{
+		mediaType: binding.MIMEXML2,
+		supports:  anyValue,
+		renderer: func(mediaType string, v any) render.Render {
+			return xmlRender{mediaType: mediaType, data: v}
+		},
+	},
+	{
+		mediaType: MIMECSV,
+		supports: func(v any) bool {
+			switch v.(type) {
+			case model.Products, sparseProducts:
+				return true
+			}
+
+			return false
+		},
+		renderer: func(_ string, v any) render.Render {
+			if s, ok := v.(sparseProducts); ok {
+				return csvRender{products: s.products, fields: s.fields}
+			}
+
+			return csvRender{products: v.(model.Products)}
+		},
+	},
+	{
+		mediaType: binding.MIMEMSGPACK2,
+		supports:  anyValue,
+		renderer: func(_ string, v any) render.Render {
+			return render.MsgPack{Data: unwrapSparse(v)}
+		},
+	},
+	{
+		mediaType: binding.MIMEMSGPACK,
+		supports:  anyValue,
+		renderer: func(_ string, v any) render.Render {
+			return render.MsgPack{Data: unwrapSparse(v)}
+		},
+	},
+}
+
+// decoders supported bindings for request bodies indexed by Content-Type
+var decoders = map[string]binding.Binding{
+	binding.MIMEJSON:     binding.JSON,
+	binding.MIMEXML:      binding.XML,
+	binding.MIMEXML2:     binding.XML,
+	binding.MIMEMSGPACK:  binding.MsgPack,
+	binding.MIMEMSGPACK2: binding.MsgPack,
+}
+
+// anyValue indicates that an encoder supports any value
+func anyValue(any) bool {
+	return true
+}
+
+// bind decodes the request body into v using the binding related to the Content-Type header
+//
+// If the Content-Type header is missing, the request body is decoded as JSON
+func bind(c *gin.Context, v any) error {
+	contentType := c.ContentType()
+	if contentType == "" {
+		contentType = binding.MIMEJSON
+	}
+
+	b, ok := decoders[contentType]
+	if !ok {
+		return error2.UnsupportedMediaType(fmt.Sprintf(`media type '%s' is not supported`, contentType))
+	}
+
+	_, span := startSpan(c.Request.Context(), "decode request body")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("content_type", contentType))
+
+	if err := c.ShouldBindWith(v, b); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return error2.Unprocessable(err.Error())
+	}
+
+	return nil
+}
+
+// respond writes v using the encoder negotiated from the Accept header
+//
+// If none of the media types accepted by the client are able to encode v, it responds with 406 status code
+func respond(c *gin.Context, code int, v any) {
+	e, ok := negotiate(c.GetHeader("Accept"), v)
+	if !ok {
+		handleError(c, notAcceptable(c))
+		return
+	}
+
+	c.Render(code, e.renderer(e.mediaType, v))
+}
+
+// accepts checks if the client accepts a media type able to encode v, a value of the type written by the handler,
+// otherwise it responds with 406 status code and returns false
+//
+// The handlers that change the storage call it before doing any work, so a write is never committed
+// if the client is not able to receive its result
+func accepts(c *gin.Context, v any) bool {
+	if _, ok := negotiate(c.GetHeader("Accept"), v); ok {
+		return true
+	}
+
+	handleError(c, notAcceptable(c))
+	return false
+}
+
+// notAcceptable returns error2.NotAcceptable for the Accept header of the request
+func notAcceptable(c *gin.Context) error {
+	return error2.NotAcceptable(fmt.Sprintf(`none of the media types '%s' are supported`, c.GetHeader("Accept")))
+}
+
+// negotiate chooses the encoder for v with the best match for the Accept header (RFC 7231 section 5.3.2)
+func negotiate(accept string, v any) (encoder, bool) {
+	return negotiateWith(encoders[:], accept, v)
+}
+
+// negotiateWith chooses the encoder for v from the candidates, if the client accepts any media type the first candidate is chosen
+func negotiateWith(candidates []encoder, accept string, v any) (encoder, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return candidates[0], true
+	}
+
+	for _, r := range parseAccept(accept) {
+		if r.quality <= 0 {
+			break
+		}
+
+		for _, e := range candidates {
+			if e.supports(v) && r.matches(e.mediaType) {
+				return e, true
+			}
+		}
+	}
+
+	return encoder{}, false
+}
+
+// mediaRange media range from the Accept header
+type mediaRange struct {
+	mainType, subType string
+	quality           float64
+}
+
+// matches indicates if the media type is included in the media range
+func (r mediaRange) matches(mediaType string) bool {
+	mainType, subType, _ := strings.Cut(mediaType, "/")
+
+	return (r.mainType == "*" || r.mainType == mainType) && (r.subType == "*" || r.subType == subType)
+}
+
+// specificity returns the precedence of the media range, more specific media ranges have greater precedence
+func (r mediaRange) specificity() int {
+	switch {
+	case r.mainType == "*":
+		return 0
+	case r.subType == "*":
+		return 1
+	}
+
+	return 2
+}
+
+// parseAccept parses the Accept header value and returns the media ranges sorted by quality and specificity
+func parseAccept(accept string) []mediaRange {
+	ranges := make([]mediaRange, 0)
+
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+
+		mainType, subType, ok := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !ok || mainType == "" || subType == "" {
+			continue
+		}
+
+		r := mediaRange{mainType: mainType, subType: subType, quality: 1}
+
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key != "q" {
+				continue
+			}
+
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				q = 0
+			}
+
+			r.quality = q
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].quality != ranges[j].quality {
+			return ranges[i].quality > ranges[j].quality
+		}
+
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+
+	return ranges
+}
+
+// _ "implements" constraint for xmlRender
+var _ render.Render = xmlRender{}
+
+// xmlRender writes XML documents, unlike render.XML names the root element of model.Product, model.Products, etc.
+type xmlRender struct {
+	mediaType string
+	data      any
+}
+
+// Render writes the XML document
+func (x xmlRender) Render(w http.ResponseWriter) error {
+	x.WriteContentType(w)
+
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+
+	switch data := x.data.(type) {
+	case model.Product:
+		return encoder.Encode(xmlProduct{Product: data})
+	case model.Products:
+		return encoder.Encode(xmlProducts{Products: data})
+	case sparseProduct:
+		return encoder.EncodeElement(data.value, xml.StartElement{Name: xml.Name{Local: "product"}})
+	case sparseProducts:
+		return encoder.Encode(xmlSparseProducts{Products: data.values})
+	case model.ProductBatch, sparseBatch:
+		return encoder.EncodeElement(data, xml.StartElement{Name: xml.Name{Local: "batch"}})
+	case model.SKUBlock:
+		return encoder.EncodeElement(data, xml.StartElement{Name: xml.Name{Local: "skuBlock"}})
+	case []model.StatusTransition:
+		return encoder.Encode(xmlTransitions{Transitions: data})
+	case []model.PriceChange:
+		return encoder.Encode(xmlPriceChanges{PriceChanges: data})
+	case model.PriceChange:
+		return encoder.EncodeElement(data, xml.StartElement{Name: xml.Name{Local: "priceChange"}})
+	case model.DuplicateReport:
+		return encoder.EncodeElement(data, xml.StartElement{Name: xml.Name{Local: "duplicateReport"}})
+	}
+
+	return encoder.Encode(x.data)
+}
+
+// WriteContentType writes the negotiated media type
+func (x xmlRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", x.mediaType+"; charset=utf-8")
+}
+
+// xmlProduct root element for a single model.Product
+type xmlProduct struct {
+	XMLName xml.Name `xml:"product"`
+	model.Product
+}
+
+// xmlProducts root element for a list of model.Product
+type xmlProducts struct {
+	XMLName  xml.Name        `xml:"products"`
+	Products []model.Product `xml:"product"`
+}
+
+// xmlTransitions root element for a list of model.StatusTransition
+type xmlTransitions struct {
+	XMLName     xml.Name                 `xml:"transitions"`
+	Transitions []model.StatusTransition `xml:"transition"`
+}
+
+// xmlPriceChanges root element for a list of model.PriceChange
+type xmlPriceChanges struct {
+	XMLName      xml.Name            `xml:"priceChanges"`
+	PriceChanges []model.PriceChange `xml:"priceChange"`
+}
+
+// xmlSparseProducts root element for a list of sparse model.Product
+type xmlSparseProducts struct {
+	XMLName  xml.Name `xml:"products"`
+	Products []any    `xml:"product"`
+}
+
+// _ "implements" constraint for csvRender
+var _ render.Render = csvRender{}
+
+// csvHeader column names for the CSV documents of model.Products
+var csvHeader = []string{"sku", "name", "brand", "size", "price", "principalImage", "otherImages", "status"}
+
+// csvRender writes a list of model.Product as CSV document, one product per record
+type csvRender struct {
+	products model.Products
+	// fields sparse fieldset, if it is empty every column is written
+	fields model.Fields
+}
+
+// columns returns the indexes of the csvHeader columns that are written
+func (r csvRender) columns() []int {
+	columns := make([]int, 0, len(csvHeader))
+
+	if len(r.fields) == 0 {
+		for i := range csvHeader {
+			columns = append(columns, i)
+		}
+
+		return columns
+	}
+
+	for _, field := range r.fields {
+		for i, name := range csvHeader {
+			if name == field {
+				columns = append(columns, i)
+			}
+		}
+	}
+
+	return columns
+}
+
+// Render writes the CSV document including the header record
+func (r csvRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+
+	writer := csv.NewWriter(w)
+	columns := r.columns()
+
+	if err := writer.Write(pick(csvHeader, columns)); err != nil {
+		return err
+	}
+
+	for _, product := range r.products {
+		record := []string{
+			csvText(string(product.SKU)),
+			csvText(product.Name),
+			csvText(product.Brand),
+			"",
+			strconv.FormatFloat(product.Price, 'f', -1, 64),
+			"",
+			csvText(product.OtherImages.String()),
+			csvText(string(product.Status)),
+		}
+
+		if product.Size != nil {
+			record[3] = csvText(*product.Size)
+		}
+
+		if product.PrincipalImage != nil {
+			record[5] = csvText(product.PrincipalImage.String())
+		}
+
+		if err := writer.Write(pick(record, columns)); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvText escapes a text cell that the spreadsheets would evaluate as a formula (CSV injection),
+// the cells that start with '=', '+', '-', '@', tab or carriage return are prefixed with a single quote
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+
+	return s
+}
+
+// pick returns the values of the record located at the indexes
+func pick(record []string, indexes []int) []string {
+	picked := make([]string, 0, len(indexes))
+
+	for _, i := range indexes {
+		picked = append(picked, record[i])
+	}
+
+	return picked
+}
+
+// WriteContentType writes the CSV media type
+func (r csvRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", MIMECSV+"; charset=utf-8")
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+	"github.com/yael-castro/products-api/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRespond(t *testing.T) {
+	principalImage, _ := url.Parse("https://example.com/a.png")
+	otherImage, _ := url.Parse("https://example.com/b.png")
+
+	product := model.Product{
+		SKU:            "FAL-12345678",
+		Name:           "Shoes",
+		Brand:          "Nike",
+		Price:          10.5,
+		PrincipalImage: &model.URL{URL: principalImage},
+		OtherImages:    model.URLs{{URL: principalImage}, {URL: otherImage}},
+	}
+
+	tdt := []struct {
+		accept              string
+		data                any
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			data:                product,
+			expectedCode:        http.StatusOK,
+			expectedContentType: binding.MIMEJSON,
+			expectedBody:        `"principalImage":"https://example.com/a.png"`,
+		},
+		{
+			accept:              "*/*",
+			data:                product,
+			expectedCode:        http.StatusOK,
+			expectedContentType: binding.MIMEJSON,
+		},
+		{
+			accept:              "application/xml",
+			data:                product,
+			expectedCode:        http.StatusOK,
+			expectedContentType: binding.MIMEXML,
+			expectedBody:        `<product><sku>FAL-12345678</sku>`,
+		},
+		{
+			accept:              "text/xml;q=0.5, application/json;q=0.4",
+			data:                model.Products{product},
+			expectedCode:        http.StatusOK,
+			expectedContentType: binding.MIMEXML2,
+			expectedBody:        `<otherImages><image>https://example.com/a.png</image><image>https://example.com/b.png</image></otherImages>`,
+		},
+		{
+			accept:              "text/csv",
+			data:                model.Products{product},
+			expectedCode:        http.StatusOK,
+			expectedContentType: MIMECSV,
+			expectedBody:        "FAL-12345678,Shoes,Nike,,10.5,https://example.com/a.png,https://example.com/a.png https://example.com/b.png",
+		},
+		// The text cells that would be evaluated as formulas are escaped
+		{
+			accept: "text/csv",
+			data: model.Products{
+				{SKU: "FAL-12345679", Name: `=HYPERLINK("https://evil.example.com","Shoes")`, Brand: "@cmd", Size: &[]string{"-M"}[0], Price: -1},
+			},
+			expectedCode:        http.StatusOK,
+			expectedContentType: MIMECSV,
+			expectedBody:        `FAL-12345679,"'=HYPERLINK(""https://evil.example.com"",""Shoes"")",'@cmd,'-M,-1,,,`,
+		},
+		{
+			accept:       "text/csv",
+			data:         product,
+			expectedCode: http.StatusNotAcceptable,
+		},
+		{
+			accept:              "text/csv, application/msgpack;q=0.1",
+			data:                product,
+			expectedCode:        http.StatusOK,
+			expectedContentType: binding.MIMEMSGPACK2,
+		},
+		{
+			accept:       "text/html, application/json;q=0",
+			data:         product,
+			expectedCode: http.StatusNotAcceptable,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	if *verbose {
+		gin.SetMode(gin.DebugMode)
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+			c.Request.Header.Set("Accept", v.accept)
+
+			respond(c, http.StatusOK, v.data)
+
+			if w.Code != v.expectedCode {
+				t.Fatalf(`expected code '%d' unexpected code '%d'`, v.expectedCode, w.Code)
+			}
+
+			if w.Code != http.StatusOK {
+				t.Skip(w.Body.String())
+			}
+
+			if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, v.expectedContentType) {
+				t.Fatalf(`expected content type '%s' unexpected content type '%s'`, v.expectedContentType, contentType)
+			}
+
+			if !strings.Contains(w.Body.String(), v.expectedBody) {
+				t.Fatalf(`expected body containing '%s' unexpected body '%s'`, v.expectedBody, w.Body.String())
+			}
+
+			t.Log(w.Body.String())
+		})
+	}
+}
+
+func TestBind(t *testing.T) {
+	principalImage, _ := url.Parse("https://example.com/a.png")
+
+	expectedProduct := model.Product{
+		SKU:            "FAL-12345678",
+		Name:           "Shoes",
+		Brand:          "Nike",
+		Price:          10.5,
+		PrincipalImage: &model.URL{URL: principalImage},
+		OtherImages:    model.URLs{{URL: principalImage}},
+	}
+
+	msgpack := &bytes.Buffer{}
+	if err := codec.NewEncoder(msgpack, &codec.MsgpackHandle{}).Encode(expectedProduct); err != nil {
+		t.Fatal(err)
+	}
+
+	tdt := []struct {
+		contentType string
+		body        []byte
+		expectedErr bool
+	}{
+		{
+			body: []byte(`{"sku":"FAL-12345678","name":"Shoes","brand":"Nike","price":10.5,"principalImage":"https://example.com/a.png","otherImages":["https://example.com/a.png"]}`),
+		},
+		{
+			contentType: "application/json; charset=utf-8",
+			body:        []byte(`{"sku":"FAL-12345678","name":"Shoes","brand":"Nike","price":10.5,"principalImage":"https://example.com/a.png","otherImages":["https://example.com/a.png"]}`),
+		},
+		{
+			contentType: "application/xml",
+			body:        []byte(`<product><sku>FAL-12345678</sku><name>Shoes</name><brand>Nike</brand><price>10.5</price><principalImage>https://example.com/a.png</principalImage><otherImages><image>https://example.com/a.png</image></otherImages></product>`),
+		},
+		{
+			contentType: "application/msgpack",
+			body:        msgpack.Bytes(),
+		},
+		{
+			contentType: "application/xml",
+			body:        []byte(`<product>`),
+			expectedErr: true,
+		},
+		{
+			contentType: "text/plain",
+			body:        []byte(`FAL-12345678`),
+			expectedErr: true,
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request, _ = http.NewRequest(http.MethodPost, "/", bytes.NewReader(v.body))
+			c.Request.Header.Set("Content-Type", v.contentType)
+
+			product := model.Product{}
+
+			err := bind(c, &product)
+			if (err != nil) != v.expectedErr {
+				t.Fatalf(`unexpected error '%v'`, err)
+			}
+
+			if err != nil {
+				t.Skip(err)
+			}
+
+			if !reflect.DeepEqual(expectedProduct, product) {
+				t.Fatalf("expected product '%+v' unexpected product '%+v'", expectedProduct, product)
+			}
+
+			t.Log(product)
+		})
+	}
+}
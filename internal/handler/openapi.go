@@ -0,0 +1,396 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ParseOpenAPI parses and validates the OpenAPI document
+func ParseOpenAPI(data []byte) (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	if err = doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	return doc, nil
+}
+
+// OpenAPIOptions options of the OpenAPI validation middleware
+type OpenAPIOptions struct {
+	// ValidateResponses indicates if the responses are checked against the OpenAPI document,
+	// the responses are buffered until they are validated, so it is intended for testing
+	ValidateResponses bool
+}
+
+// OpenAPIValidator middleware that rejects the requests that do not match the path, parameter or body schemas
+// of the operation described by the OpenAPI document, before they reach the handlers
+//
+// The operation is found by the route matched by the *gin.Engine (e.g. /v1/products/:id is /v1/products/{id}).
+// Only JSON bodies are checked against the schemas, the other media types are checked by the business rules after decoding.
+// The security requirements are not checked, the Authentication middleware is in charge of them
+func OpenAPIValidator(doc *openapi3.T, options OpenAPIOptions) gin.HandlerFunc {
+	filterOptions := &openapi3filter.Options{
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	filterOptions.WithCustomSchemaErrorFunc(func(err *openapi3.SchemaError) string {
+		return err.Reason
+	})
+
+	// every broken rule of the request is reported, the responses only report the first mismatch
+	requestOptions := *filterOptions
+	requestOptions.MultiError = true
+
+	return func(c *gin.Context) {
+		route, ok := openAPIRoute(doc, c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		input, err := requestValidationInput(c, route, requestOptions)
+		if err != nil {
+			handleError(c, err)
+			c.Abort()
+			return
+		}
+
+		if err = openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			handleError(c, openAPIViolations(err))
+			c.Abort()
+			return
+		}
+
+		if !options.ValidateResponses {
+			c.Next()
+			return
+		}
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+
+		responseOptions := *filterOptions
+		responseOptions.ExcludeResponseBody = !isJSON(writer.Header().Get("Content-Type"))
+
+		err = openapi3filter.ValidateResponse(c.Request.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 writer.status,
+			Header:                 writer.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(writer.body.Bytes())),
+			Options:                &responseOptions,
+		})
+		if err != nil {
+			err = fmt.Errorf("response does not match the OpenAPI document: %s", openAPIErrorMessage(err))
+			_ = c.Error(err)
+
+			c.Writer.Header().Del("Content-Length")
+			respondProblem(c, newProblem(c, CodeResponseMismatch, err.Error()))
+			return
+		}
+
+		c.Writer.WriteHeader(writer.status)
+		_, _ = c.Writer.Write(writer.body.Bytes())
+	}
+}
+
+// openAPIRoute finds the operation of the OpenAPI document related to the route matched by the *gin.Engine
+func openAPIRoute(doc *openapi3.T, c *gin.Context) (*routers.Route, bool) {
+	path := OpenAPIPath(c.FullPath())
+	if path == "" {
+		return nil, false
+	}
+
+	pathItem := doc.Paths.Value(path)
+	if pathItem == nil {
+		return nil, false
+	}
+
+	operation := pathItem.GetOperation(c.Request.Method)
+	if operation == nil {
+		return nil, false
+	}
+
+	return &routers.Route{
+		Spec:      doc,
+		Path:      path,
+		PathItem:  pathItem,
+		Method:    c.Request.Method,
+		Operation: operation,
+	}, true
+}
+
+// requestValidationInput builds the input to validate the request
+//
+// The request body is read and restored so the handlers are able to read it again.
+// The requests without Content-Type header are validated as JSON, the same way they are decoded by bind
+func requestValidationInput(c *gin.Context, route *routers.Route, options openapi3filter.Options) (*openapi3filter.RequestValidationInput, error) {
+	params := make(map[string]string, len(c.Params))
+	for _, param := range c.Params {
+		params[param.Key] = param.Value
+	}
+
+	request := c.Request.Clone(c.Request.Context())
+
+	if c.Request.Body != nil && c.Request.Body != http.NoBody {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return nil, error2.Unprocessable(err.Error())
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		request.Body = io.NopCloser(bytes.NewReader(body))
+
+		contentType := c.ContentType()
+		if contentType == "" {
+			contentType = binding.MIMEJSON
+			request.Header.Set("Content-Type", contentType)
+		}
+
+		if requestBody := route.Operation.RequestBody; requestBody != nil && requestBody.Value != nil {
+			if requestBody.Value.Content.Get(contentType) == nil {
+				return nil, error2.UnsupportedMediaType(fmt.Sprintf(`media type '%s' is not supported`, contentType))
+			}
+
+			options.ExcludeRequestBody = !isJSON(contentType)
+		}
+	}
+
+	return &openapi3filter.RequestValidationInput{
+		Request:    request,
+		PathParams: params,
+		Route:      route,
+		Options:    &options,
+	}, nil
+}
+
+// OpenAPIPath converts the path of a gin route into an OpenAPI path (e.g. /v1/products/:id is /v1/products/{id})
+func OpenAPIPath(route string) string {
+	segments := strings.Split(route, "/")
+
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// isJSON indicates if the media type is JSON, including the structured syntax suffix "+json" (e.g. application/problem+json)
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == binding.MIMEJSON || strings.HasSuffix(mediaType, "+json"))
+}
+
+// openAPIErrorMessage returns a short message for the errors of the openapi3filter package, without the schema dump
+func openAPIErrorMessage(err error) string {
+	requestErr := &openapi3filter.RequestError{}
+	if errors.As(err, &requestErr) {
+		switch {
+		case requestErr.Parameter != nil:
+			return fmt.Sprintf(`parameter '%s' in %s: %s`, requestErr.Parameter.Name, requestErr.Parameter.In, causeMessage(requestErr.Err, requestErr.Reason))
+		case requestErr.RequestBody != nil:
+			return "request body: " + causeMessage(requestErr.Err, requestErr.Reason)
+		}
+
+		return requestErr.Error()
+	}
+
+	responseErr := &openapi3filter.ResponseError{}
+	if errors.As(err, &responseErr) {
+		return causeMessage(responseErr.Err, responseErr.Reason)
+	}
+
+	return err.Error()
+}
+
+// openAPIViolations converts the errors of the openapi3filter package into error2.Violations,
+// each schema error is a violation with the JSON pointer of the body field (e.g. "/otherImages/0")
+// or the name of the parameter, the schema keyword as rule and the keyword value as parameter
+func openAPIViolations(err error) error2.Violations {
+	switch err := err.(type) {
+	case openapi3.MultiError:
+		violations := make(error2.Violations, 0, len(err))
+		for _, e := range err {
+			violations = append(violations, openAPIViolations(e)...)
+		}
+
+		return violations
+	case *openapi3filter.RequestError:
+		violations := openAPIViolations(err.Err)
+
+		if err.Parameter != nil {
+			for i := range violations {
+				violations[i].Field, violations[i].Pointer = err.Parameter.Name, ""
+			}
+		}
+
+		if len(violations) > 0 {
+			return violations
+		}
+
+		return error2.Violations{{Field: openAPIErrorField(err), Reason: openAPIErrorMessage(err)}}
+	case *openapi3.SchemaError:
+		pointer := ""
+		if tokens := err.JSONPointer(); len(tokens) > 0 {
+			pointer = "/" + strings.Join(tokens, "/")
+		}
+
+		violation := error2.Violated(pointer, err.SchemaField, err.Reason, schemaParams(err.Schema, err.SchemaField))
+		if violation.Field == "" {
+			violation.Field = "body"
+		}
+
+		return error2.Violations{violation}
+	case nil:
+		return nil
+	}
+
+	return error2.Violations{{Field: openAPIErrorField(err), Reason: openAPIErrorMessage(err)}}
+}
+
+// openAPIErrorField returns the name of the parameter related to the errors of the openapi3filter package,
+// if the error is not related to a parameter returns "body"
+func openAPIErrorField(err error) string {
+	requestErr := &openapi3filter.RequestError{}
+	if errors.As(err, &requestErr) && requestErr.Parameter != nil {
+		return requestErr.Parameter.Name
+	}
+
+	return "body"
+}
+
+// schemaParams returns the value of the schema keyword broken by a value (e.g. {"min": 3} for minLength)
+func schemaParams(schema *openapi3.Schema, keyword string) error2.RuleParams {
+	if schema == nil {
+		return nil
+	}
+
+	switch keyword {
+	case "minLength":
+		return error2.RuleParams{"min": schema.MinLength}
+	case "maxLength":
+		if schema.MaxLength != nil {
+			return error2.RuleParams{"max": *schema.MaxLength}
+		}
+	case "minItems":
+		return error2.RuleParams{"min": schema.MinItems}
+	case "maxItems":
+		if schema.MaxItems != nil {
+			return error2.RuleParams{"max": *schema.MaxItems}
+		}
+	case "minimum":
+		if schema.Min != nil {
+			return error2.RuleParams{"min": *schema.Min}
+		}
+	case "maximum":
+		if schema.Max != nil {
+			return error2.RuleParams{"max": *schema.Max}
+		}
+	case "pattern":
+		return error2.RuleParams{"pattern": schema.Pattern}
+	case "enum":
+		return error2.RuleParams{"values": schema.Enum}
+	case "type":
+		if schema.Type != nil {
+			return error2.RuleParams{"types": schema.Type.Slice()}
+		}
+	}
+
+	return nil
+}
+
+// causeMessage returns the message of the schema error which caused the failure, otherwise the reason
+func causeMessage(err error, reason string) string {
+	schemaErr := &openapi3.SchemaError{}
+	if errors.As(err, &schemaErr) {
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			return fmt.Sprintf(`'/%s' %s`, strings.Join(pointer, "/"), schemaErr.Reason)
+		}
+
+		return schemaErr.Reason
+	}
+
+	if err != nil {
+		return err.Error()
+	}
+
+	return reason
+}
+
+// _ "implements" constraint for *bufferedWriter
+var _ gin.ResponseWriter = (*bufferedWriter)(nil)
+
+// bufferedWriter gin.ResponseWriter that keeps the status code and the body in memory instead of writing them
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+// WriteHeader keeps the status code
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow marks the headers as written
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+// Write keeps the data in memory
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+// WriteString keeps the string in memory
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+// Status returns the kept status code
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+// Size returns the number of bytes kept, -1 if nothing was written
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+
+	return w.body.Len()
+}
+
+// Written indicates if the response was written
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+// Flush does nothing, the response is written after its validation
+func (w *bufferedWriter) Flush() {}
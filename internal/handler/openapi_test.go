@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	productsapi "github.com/yael-castro/products-api"
+	"github.com/yael-castro/products-api/internal/business"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"github.com/yael-castro/products-api/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// TestRoutes fails when the routes of the *gin.Engine and the paths of the OpenAPI document disagree
+func TestRoutes(t *testing.T) {
+	doc, err := ParseOpenAPI(productsapi.OpenAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := newEngine(Groups{ProductManager: ProductStore{}, MediaServer: MediaStore{}, Monitor: Monitoring{Health: &Health{}}, Documentation: NewDocs(doc), GraphQLExecutor: &GraphQLSchema{}})
+
+	routes := make(map[string]bool)
+
+	for i, route := range engine.Routes() {
+		path := OpenAPIPath(route.Path)
+		routes[route.Method+" "+path] = true
+
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			pathItem := doc.Paths.Value(path)
+			if pathItem == nil || pathItem.GetOperation(route.Method) == nil {
+				t.Fatalf(`route '%s %s' is not documented`, route.Method, path)
+			}
+		})
+	}
+
+	for path, pathItem := range doc.Paths.Map() {
+		for method := range pathItem.Operations() {
+			t.Run(method+" "+path, func(t *testing.T) {
+				if !routes[method+" "+path] {
+					t.Fatalf(`operation '%s %s' is documented but it is not routed`, method, path)
+				}
+			})
+		}
+	}
+}
+
+func TestOpenAPIValidator(t *testing.T) {
+	tdt := []struct {
+		method       string
+		path         string
+		contentType  string
+		body         string
+		expectedCode int
+	}{
+		{
+			method:       http.MethodGet,
+			path:         "/v1/products/FAL-12345678",
+			expectedCode: http.StatusOK,
+		},
+		{
+			method:       http.MethodGet,
+			path:         "/v1/products",
+			expectedCode: http.StatusOK,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products",
+			contentType:  "application/json",
+			body:         `{"sku":"FAL-1000001","name":"Shoes","brand":"Nike","price":10.5,"principalImage":"https://example.com"}`,
+			expectedCode: http.StatusCreated,
+		},
+		// Missing required properties
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products",
+			contentType:  "application/json",
+			body:         `{"sku":"FAL-1000002"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		// Invalid type
+		{
+			method:       http.MethodPut,
+			path:         "/v1/products",
+			body:         `{"sku":"FAL-12345678","name":"Shoes","brand":"Nike","price":"10.5","principalImage":"https://example.com"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		// The status is read only
+		{
+			method:       http.MethodPut,
+			path:         "/v1/products",
+			body:         `{"sku":"FAL-12345678","name":"Shoes","brand":"Nike","price":10.5,"principalImage":"https://example.com","status":"published"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		// Not documented media type
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products",
+			contentType:  "text/plain",
+			body:         `FAL-1000003`,
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+		// Documented media type which is not checked against the schema
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products",
+			contentType:  "application/xml",
+			body:         `<product><sku>FAL-1000004</sku><name>Shoes</name><brand>Nike</brand><price>10.5</price><principalImage>https://example.com</principalImage></product>`,
+			expectedCode: http.StatusCreated,
+		},
+		// Sparse fieldset
+		{
+			method:       http.MethodGet,
+			path:         "/v1/products/FAL-12345678?fields=sku,price",
+			expectedCode: http.StatusOK,
+		},
+		// Batch requests
+		{
+			method:       http.MethodGet,
+			path:         "/v1/products?sku=FAL-12345678,FAL-1000009&fields=name",
+			expectedCode: http.StatusOK,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products:batchGet",
+			body:         `{"skus":["FAL-1000009","FAL-12345678"]}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products:batchGet",
+			body:         `{}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		// Custom methods only match the literal path
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products:batchDelete",
+			body:         `{"skus":["FAL-12345678"]}`,
+			expectedCode: http.StatusNotFound,
+		},
+		// Trailing slash is redirected
+		{
+			method:       http.MethodGet,
+			path:         "/v1/products/",
+			expectedCode: http.StatusMovedPermanently,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	doc, err := ParseOpenAPI(productsapi.OpenAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	image := &model.URL{}
+	if err = image.UnmarshalText([]byte("https://example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	store := ProductStore{
+		ProductManager: business.ProductStore{
+			StorageManager: &repository.MockStorage[model.SKU, model.Product]{
+				"FAL-12345678": model.Product{SKU: "FAL-12345678", Name: "Shoes", Brand: "Nike", Price: 10.5, PrincipalImage: image, Status: model.StatusPublished},
+			},
+		},
+	}
+
+	engine := newEngine(Groups{ProductManager: store, MediaServer: MediaStore{}, Monitor: Monitoring{Health: &Health{}}, Documentation: NewDocs(doc), GraphQLExecutor: &GraphQLSchema{}}, OpenAPIValidator(doc, OpenAPIOptions{ValidateResponses: true}))
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			request, _ := http.NewRequest(v.method, v.path, bytes.NewBufferString(v.body))
+			if v.contentType != "" {
+				request.Header.Set("Content-Type", v.contentType)
+			}
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, request)
+
+			if w.Code != v.expectedCode {
+				t.Fatalf(`expected code '%d' unexpected code '%d': %s`, v.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestOpenAPIValidator_Violations(t *testing.T) {
+	tdt := []struct {
+		method             string
+		target             string
+		body               string
+		expectedViolations error2.Violations
+	}{
+		{
+			method: http.MethodPost,
+			target: "/v1/products",
+			body:   `{"sku":"FAL-1000001","name":"Shoes","price":"10","otherImages":["https://example.com",3]}`,
+			expectedViolations: error2.Violations{
+				{Field: "otherImages", Pointer: "/otherImages/1", Rule: "type", Params: error2.RuleParams{"types": []any{"string"}}, Reason: "value must be a string"},
+				{Field: "price", Pointer: "/price", Rule: "type", Params: error2.RuleParams{"types": []any{"number"}}, Reason: "value must be a number"},
+				{Field: "brand", Pointer: "/brand", Rule: "required", Reason: `property "brand" is missing`},
+				{Field: "principalImage", Pointer: "/principalImage", Rule: "required", Reason: `property "principalImage" is missing`},
+			},
+		},
+		{
+			method: http.MethodGet,
+			target: "/v1/products/FAL-12345678?fields=nope",
+			expectedViolations: error2.Violations{
+				{Field: "fields", Reason: "invalid query parameter 'fields': unknown field 'nope'"},
+			},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	doc, err := ParseOpenAPI(productsapi.OpenAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := ProductStore{
+		ProductManager: business.ProductStore{
+			StorageManager: &repository.MockStorage[model.SKU, model.Product]{},
+		},
+	}
+
+	engine := newEngine(Groups{ProductManager: store, MediaServer: MediaStore{}, Monitor: Monitoring{Health: &Health{}}, Documentation: NewDocs(doc), GraphQLExecutor: &GraphQLSchema{}}, OpenAPIValidator(doc, OpenAPIOptions{}))
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest(v.method, v.target, bytes.NewBufferString(v.body)))
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf(`expected code '%d' unexpected code '%d': %s`, http.StatusBadRequest, w.Code, w.Body.String())
+			}
+
+			problem := Problem{}
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(v.expectedViolations, problem.InvalidParams) {
+				t.Fatalf("expected violations '%+v' unexpected violations '%+v'", v.expectedViolations, problem.InvalidParams)
+			}
+		})
+	}
+}
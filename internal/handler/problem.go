@@ -0,0 +1,344 @@
+package handler
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"github.com/yael-castro/products-api/internal/logging"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"net/http"
+)
+
+// Media types of the problem details (RFC 7807)
+const (
+	MIMEProblemJSON = "application/problem+json"
+	MIMEProblemXML  = "application/problem+xml"
+)
+
+// ProblemTypePath path prefix of the problem type URIs, the URI of each problem type is the path followed by its code
+const ProblemTypePath = "/problems/"
+
+// Stable codes of the problems, the clients are able to rely on them. Every code is documented in the OpenAPI document
+const (
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthenticated      = "unauthenticated"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeRouteNotFound        = "route_not_found"
+	CodeNotAcceptable        = "not_acceptable"
+	CodeDuplicateRecord      = "duplicate_record"
+	CodeConflict             = "conflict"
+	CodeDuplicateProduct     = "duplicate_product"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeUnprocessableBody    = "unprocessable_body"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeRateLimited          = "rate_limited"
+	CodeStorageError         = "storage_error"
+	CodeInternalError        = "internal_error"
+	CodeResponseMismatch     = "response_mismatch"
+	CodeNotImplemented       = "not_implemented"
+)
+
+// ProblemType kind of problem identified by a stable code
+type ProblemType struct {
+	// Code stable and machine-readable identifier of the problem type
+	Code string `json:"code" xml:"code"`
+	// Title short summary of the problem type, it does not change between occurrences
+	Title string `json:"title" xml:"title"`
+	// Status HTTP status code used by the problems of this type
+	Status int `json:"status" xml:"status"`
+	// Description explains when the problem occurs and what the client should do
+	Description string `json:"description" xml:"description"`
+}
+
+// problemTypes supported problem types
+var problemTypes = []ProblemType{
+	{
+		Code:        CodeValidationFailed,
+		Title:       "Validation failed",
+		Status:      http.StatusBadRequest,
+		Description: "The request contains invalid parameters or product data, the invalid fields are listed in invalidParams. Fix the values before retrying.",
+	},
+	{
+		Code:        CodeUnauthenticated,
+		Title:       "Authentication required",
+		Status:      http.StatusUnauthorized,
+		Description: "The operation requires credentials or the credentials are invalid. Send a valid X-API-Key header or Bearer token.",
+	},
+	{
+		Code:        CodeForbidden,
+		Title:       "Operation not allowed",
+		Status:      http.StatusForbidden,
+		Description: "The client is authenticated but none of its roles is allowed to perform the operation.",
+	},
+	{
+		Code:        CodeNotFound,
+		Title:       "Resource not found",
+		Status:      http.StatusNotFound,
+		Description: "The requested product does not exist.",
+	},
+	{
+		Code:        CodeRouteNotFound,
+		Title:       "Route not found",
+		Status:      http.StatusNotFound,
+		Description: "The requested path is not served by the API.",
+	},
+	{
+		Code:        CodeNotAcceptable,
+		Title:       "Not acceptable",
+		Status:      http.StatusNotAcceptable,
+		Description: "None of the media types of the Accept header is able to represent the response.",
+	},
+	{
+		Code:        CodeDuplicateRecord,
+		Title:       "Duplicate record",
+		Status:      http.StatusConflict,
+		Description: "A product with the same SKU already exists.",
+	},
+	{
+		Code:        CodeConflict,
+		Title:       "Conflict",
+		Status:      http.StatusConflict,
+		Description: "The request conflicts with the current state of the resource (e.g. a status transition that is not allowed).",
+	},
+	{
+		Code:        CodeDuplicateProduct,
+		Title:       "Duplicate product",
+		Status:      http.StatusConflict,
+		Description: "The product is likely the same item as the existing products listed in candidates. Send the query parameter force=true to create it anyway.",
+	},
+	{
+		Code:        CodeUnsupportedMediaType,
+		Title:       "Unsupported media type",
+		Status:      http.StatusUnsupportedMediaType,
+		Description: "The media type of the request body is not supported by the operation.",
+	},
+	{
+		Code:        CodeUnprocessableBody,
+		Title:       "Unprocessable request body",
+		Status:      http.StatusUnprocessableEntity,
+		Description: "The request body could not be decoded using its media type.",
+	},
+	{
+		Code:        CodePayloadTooLarge,
+		Title:       "Payload too large",
+		Status:      http.StatusRequestEntityTooLarge,
+		Description: "The request content exceeds the size limit of the operation.",
+	},
+	{
+		Code:        CodeRateLimited,
+		Title:       "Rate limit exceeded",
+		Status:      http.StatusTooManyRequests,
+		Description: "The client exceeded its rate limit. Retry after the seconds indicated by the Retry-After header.",
+	},
+	{
+		Code:        CodeStorageError,
+		Title:       "Storage error",
+		Status:      http.StatusInternalServerError,
+		Description: "The storage failed to perform the operation. Retry later and report the request ID if the problem persists.",
+	},
+	{
+		Code:        CodeInternalError,
+		Title:       "Internal error",
+		Status:      http.StatusInternalServerError,
+		Description: "An unexpected error occurred. Report the request ID if the problem persists.",
+	},
+	{
+		Code:        CodeResponseMismatch,
+		Title:       "Response does not match the API description",
+		Status:      http.StatusInternalServerError,
+		Description: "The response does not match the OpenAPI document, it is only reported while the responses are validated (testing).",
+	},
+	{
+		Code:        CodeNotImplemented,
+		Title:       "Not implemented",
+		Status:      http.StatusNotImplemented,
+		Description: "The operation is not enabled in the server.",
+	},
+}
+
+// problemType returns the ProblemType identified by the code, the unknown codes are treated as internal errors
+func problemType(code string) ProblemType {
+	for _, t := range problemTypes {
+		if t.Code == code {
+			return t
+		}
+	}
+
+	return problemType(CodeInternalError)
+}
+
+// Problem problem details for HTTP APIs (RFC 7807), the body of every error response
+type Problem struct {
+	XMLName xml.Name `json:"-" xml:"urn:ietf:rfc:7807 problem"`
+	// Type URI reference that identifies the problem type, it is resolved against the API base URL
+	Type string `json:"type" xml:"type"`
+	// Title short summary of the problem type
+	Title string `json:"title" xml:"title"`
+	// Status HTTP status code
+	Status int `json:"status" xml:"status"`
+	// Detail explanation specific to this occurrence of the problem
+	Detail string `json:"detail,omitempty" xml:"detail,omitempty"`
+	// Instance URI reference that identifies the specific occurrence of the problem
+	Instance string `json:"instance,omitempty" xml:"instance,omitempty"`
+	// Code stable and machine-readable identifier of the problem type
+	Code string `json:"code" xml:"code"`
+	// RequestID identifier of the request, it is included into the logs
+	RequestID string `json:"requestId,omitempty" xml:"requestId,omitempty"`
+	// InvalidParams fields that break the validation rules
+	InvalidParams error2.Violations `json:"invalidParams,omitempty" xml:"invalidParams>param,omitempty"`
+	// Candidates existing products that are likely the same item as the new product
+	Candidates []error2.Candidate `json:"candidates,omitempty" xml:"candidates>candidate,omitempty"`
+}
+
+// Error returns the detail of the Problem
+func (p Problem) Error() string {
+	return p.Detail
+}
+
+// newProblem builds the Problem of the request based on the ProblemType identified by the code
+func newProblem(c *gin.Context, code, detail string) Problem {
+	t := problemType(code)
+
+	requestID := logging.RequestID(c.Request.Context())
+	if requestID == "" {
+		requestID = c.Writer.Header().Get(RequestIDHeader)
+	}
+
+	return Problem{
+		Type:      ProblemTypePath + t.Code,
+		Title:     t.Title,
+		Status:    t.Status,
+		Detail:    detail,
+		Instance:  c.Request.URL.RequestURI(),
+		Code:      t.Code,
+		RequestID: requestID,
+	}
+}
+
+// problemFrom relates the error to the Problem written in the response
+//
+// The errors are searched in the whole chain of err, so the wrapped domain errors keep their codes.
+// The details of the unexpected errors are hidden from the clients, they are attached to the *gin.Context to be logged
+func problemFrom(c *gin.Context, err error) Problem {
+	if problem := (Problem{}); errors.As(err, &problem) {
+		return problem
+	}
+
+	if violations := (error2.Violations{}); errors.As(err, &violations) {
+		problem := newProblem(c, CodeValidationFailed, err.Error())
+		problem.InvalidParams = violations
+		return problem
+	}
+
+	if duplicates := (error2.Duplicates{}); errors.As(err, &duplicates) {
+		problem := newProblem(c, CodeDuplicateProduct, err.Error())
+		problem.Candidates = duplicates.Candidates
+		return problem
+	}
+
+	switch {
+	case errorAs[error2.Validation](err), errorAs[*json.MarshalerError](err):
+		return newProblem(c, CodeValidationFailed, err.Error())
+	case errorAs[error2.Unauthorized](err):
+		return newProblem(c, CodeUnauthenticated, err.Error())
+	case errorAs[error2.Forbidden](err):
+		return newProblem(c, CodeForbidden, err.Error())
+	case errorAs[error2.NotFound](err):
+		return newProblem(c, CodeNotFound, err.Error())
+	case errorAs[error2.NotAcceptable](err):
+		return newProblem(c, CodeNotAcceptable, err.Error())
+	case errorAs[error2.Conflict](err):
+		return newProblem(c, CodeConflict, err.Error())
+	case errorAs[error2.UnsupportedMediaType](err):
+		return newProblem(c, CodeUnsupportedMediaType, err.Error())
+	case errorAs[error2.Unprocessable](err), errorAs[*json.SyntaxError](err):
+		return newProblem(c, CodeUnprocessableBody, err.Error())
+	case errorAs[error2.TooLarge](err):
+		return newProblem(c, CodePayloadTooLarge, err.Error())
+	case errorAs[error2.TooManyRequests](err):
+		return newProblem(c, CodeRateLimited, err.Error())
+	case errorAs[error2.NotImplemented](err):
+		return newProblem(c, CodeNotImplemented, err.Error())
+	}
+
+	_ = c.Error(err)
+
+	pgErr := &error2.PG{}
+	if errors.As(err, &pgErr) {
+		if pgErr.Code == "23505" {
+			return newProblem(c, CodeDuplicateRecord, "duplicated record")
+		}
+
+		return newProblem(c, CodeStorageError, "an unexpected error related to storage occurred")
+	}
+
+	return newProblem(c, CodeInternalError, "an unexpected error occurred")
+}
+
+// problemEncoders supported encoders for the problems, JSON and XML clients receive the problem media types
+var problemEncoders = [...]encoder{
+	{mediaType: MIMEProblemJSON, supports: anyValue, renderer: problemJSON},
+	{mediaType: binding.MIMEJSON, supports: anyValue, renderer: problemJSON},
+	{mediaType: MIMEProblemXML, supports: anyValue, renderer: problemXML},
+	{mediaType: binding.MIMEXML, supports: anyValue, renderer: problemXML},
+	{mediaType: binding.MIMEXML2, supports: anyValue, renderer: problemXML},
+	{
+		mediaType: binding.MIMEMSGPACK2,
+		supports:  anyValue,
+		renderer: func(_ string, v any) render.Render {
+			return render.MsgPack{Data: v}
+		},
+	},
+	{
+		mediaType: binding.MIMEMSGPACK,
+		supports:  anyValue,
+		renderer: func(_ string, v any) render.Render {
+			return render.MsgPack{Data: v}
+		},
+	},
+}
+
+// problemJSON renders the problem as application/problem+json
+func problemJSON(_ string, v any) render.Render {
+	return problemRender{data: v}
+}
+
+// problemXML renders the problem as application/problem+xml
+func problemXML(_ string, v any) render.Render {
+	return xmlRender{mediaType: MIMEProblemXML, data: v}
+}
+
+// respondProblem writes the Problem using the media type negotiated from the Accept header.
+// If none of the accepted media types is supported, the Problem is written as application/problem+json,
+// that way the original status code is never hidden by a 406 status code
+func respondProblem(c *gin.Context, problem Problem) {
+	e, ok := negotiateWith(problemEncoders[:], c.GetHeader("Accept"), problem)
+	if !ok {
+		e = problemEncoders[0]
+	}
+
+	c.Render(problem.Status, e.renderer(e.mediaType, problem))
+}
+
+// _ "implements" constraint for problemRender
+var _ render.Render = problemRender{}
+
+// problemRender writes JSON documents using the application/problem+json media type
+type problemRender struct {
+	data any
+}
+
+// Render writes the JSON document
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.data)
+}
+
+// WriteContentType writes the application/problem+json media type
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", MIMEProblemJSON)
+}
@@ -0,0 +1,340 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	productsapi "github.com/yael-castro/products-api"
+	"github.com/yael-castro/products-api/internal/business"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"github.com/yael-castro/products-api/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestProblems(t *testing.T) {
+	skuParams := error2.RuleParams{"prefix": "FAL-", "min": 1_000_000.0, "max": 99_999_999.0}
+
+	tdt := []struct {
+		method              string
+		target              string
+		body                string
+		accept              string
+		expectedContentType string
+		expectedProblem     Problem
+	}{
+		{
+			method:              http.MethodPost,
+			target:              "/v1/products",
+			body:                `{"sku":"FAL-1000001","name":"Sh","brand":"Nike","price":10,"principalImage":"https://example.com"}`,
+			expectedContentType: MIMEProblemJSON,
+			expectedProblem: Problem{
+				Type:     "/problems/validation_failed",
+				Title:    "Validation failed",
+				Status:   http.StatusBadRequest,
+				Detail:   "product name is too short",
+				Instance: "/v1/products",
+				Code:     CodeValidationFailed,
+				InvalidParams: error2.Violations{
+					{Field: "name", Pointer: "/name", Rule: "minLength", Params: error2.RuleParams{"min": 3.0}, Reason: "product name is too short"},
+				},
+			},
+		},
+		{
+			method:              http.MethodGet,
+			target:              "/v1/products?sku=FAL-1,FAL-1000001,invalid",
+			expectedContentType: MIMEProblemJSON,
+			expectedProblem: Problem{
+				Type:     "/problems/validation_failed",
+				Title:    "Validation failed",
+				Status:   http.StatusBadRequest,
+				Detail:   "invalid sku 'FAL-1': invalid suffix '1'; invalid sku 'invalid': missing prefix 'FAL-'",
+				Instance: "/v1/products?sku=FAL-1,FAL-1000001,invalid",
+				Code:     CodeValidationFailed,
+				InvalidParams: error2.Violations{
+					{Field: "sku", Rule: "format", Params: skuParams, Reason: "invalid sku 'FAL-1': invalid suffix '1'"},
+					{Field: "sku", Rule: "format", Params: skuParams, Reason: "invalid sku 'invalid': missing prefix 'FAL-'"},
+				},
+			},
+		},
+		// The SKUs of the request body are located by pointers
+		{
+			method:              http.MethodPost,
+			target:              "/v1/products:batchGet",
+			body:                `{"skus":["FAL-1000001","invalid"]}`,
+			expectedContentType: MIMEProblemJSON,
+			expectedProblem: Problem{
+				Type:     "/problems/validation_failed",
+				Title:    "Validation failed",
+				Status:   http.StatusBadRequest,
+				Detail:   "invalid sku 'invalid': missing prefix 'FAL-'",
+				Instance: "/v1/products:batchGet",
+				Code:     CodeValidationFailed,
+				InvalidParams: error2.Violations{
+					{Field: "skus", Pointer: "/skus/1", Rule: "format", Params: skuParams, Reason: "invalid sku 'invalid': missing prefix 'FAL-'"},
+				},
+			},
+		},
+		{
+			method:              http.MethodGet,
+			target:              "/v1/products/FAL-1000009",
+			accept:              "application/json",
+			expectedContentType: MIMEProblemJSON,
+			expectedProblem: Problem{
+				Type:     "/problems/not_found",
+				Title:    "Resource not found",
+				Status:   http.StatusNotFound,
+				Detail:   "product identified by sku 'FAL-1000009' does not exist",
+				Instance: "/v1/products/FAL-1000009",
+				Code:     CodeNotFound,
+			},
+		},
+		{
+			method:              http.MethodGet,
+			target:              "/v1/products/FAL-1000009",
+			accept:              "application/problem+xml",
+			expectedContentType: MIMEProblemXML,
+		},
+		// The Accept header is checked before reading the request body
+		{
+			method:              http.MethodPost,
+			target:              "/v1/products",
+			body:                `{"sku":`,
+			accept:              "text/html",
+			expectedContentType: MIMEProblemJSON,
+			expectedProblem: Problem{
+				Type:     "/problems/not_acceptable",
+				Title:    "Not acceptable",
+				Status:   http.StatusNotAcceptable,
+				Detail:   "none of the media types 'text/html' are supported",
+				Instance: "/v1/products",
+				Code:     CodeNotAcceptable,
+			},
+		},
+		{
+			method:              http.MethodPost,
+			target:              "/v1/products",
+			body:                `{"sku":`,
+			accept:              "text/html, application/json;q=0.5",
+			expectedContentType: MIMEProblemJSON,
+			expectedProblem: Problem{
+				Type:     "/problems/unprocessable_body",
+				Title:    "Unprocessable request body",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "unexpected EOF",
+				Instance: "/v1/products",
+				Code:     CodeUnprocessableBody,
+			},
+		},
+		{
+			method:              http.MethodGet,
+			target:              "/v1/products/FAL-12345678",
+			accept:              "text/html",
+			expectedContentType: MIMEProblemJSON,
+			expectedProblem: Problem{
+				Type:     "/problems/not_acceptable",
+				Title:    "Not acceptable",
+				Status:   http.StatusNotAcceptable,
+				Detail:   "none of the media types 'text/html' are supported",
+				Instance: "/v1/products/FAL-12345678",
+				Code:     CodeNotAcceptable,
+			},
+		},
+		{
+			method:              http.MethodGet,
+			target:              "/v2/products",
+			expectedContentType: MIMEProblemJSON,
+			expectedProblem: Problem{
+				Type:     "/problems/route_not_found",
+				Title:    "Route not found",
+				Status:   http.StatusNotFound,
+				Detail:   "path '/v2/products' does not exist",
+				Instance: "/v2/products",
+				Code:     CodeRouteNotFound,
+			},
+		},
+		{
+			method:              http.MethodGet,
+			target:              "/problems/unknown",
+			expectedContentType: MIMEProblemJSON,
+			expectedProblem: Problem{
+				Type:     "/problems/not_found",
+				Title:    "Resource not found",
+				Status:   http.StatusNotFound,
+				Detail:   "problem type 'unknown' does not exist",
+				Instance: "/problems/unknown",
+				Code:     CodeNotFound,
+			},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	doc, err := ParseOpenAPI(productsapi.OpenAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := ProductStore{
+		ProductManager: business.ProductStore{
+			StorageManager: &repository.MockStorage[model.SKU, model.Product]{
+				"FAL-12345678": model.Product{SKU: "FAL-12345678"},
+			},
+		},
+	}
+
+	engine := newEngine(Groups{ProductManager: store, MediaServer: MediaStore{}, Monitor: Monitoring{Health: &Health{}}, Documentation: NewDocs(doc), GraphQLExecutor: &GraphQLSchema{}}, RequestID())
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			request := httptest.NewRequest(v.method, v.target, bytes.NewBufferString(v.body))
+			request.Header.Set(RequestIDHeader, "request-"+strconv.Itoa(i))
+			if v.accept != "" {
+				request.Header.Set("Accept", v.accept)
+			}
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, request)
+
+			if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, v.expectedContentType) {
+				t.Fatalf(`expected content type '%s' unexpected content type '%s'`, v.expectedContentType, contentType)
+			}
+
+			if v.expectedProblem.Code == "" {
+				t.Skip(w.Body.String())
+			}
+
+			problem := Problem{}
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+
+			if w.Code != problem.Status {
+				t.Fatalf(`expected code '%d' unexpected code '%d'`, problem.Status, w.Code)
+			}
+
+			v.expectedProblem.RequestID = "request-" + strconv.Itoa(i)
+
+			if !reflect.DeepEqual(v.expectedProblem, problem) {
+				t.Fatalf("expected problem '%+v' unexpected problem '%+v'", v.expectedProblem, problem)
+			}
+		})
+	}
+}
+
+func TestProblemFrom(t *testing.T) {
+	violations := error2.Invalid("sku", "invalid sku")
+
+	tdt := []struct {
+		err             error
+		expectedProblem Problem
+	}{
+		// The domain errors are found even if they are wrapped
+		{
+			err: fmt.Errorf("obtaining product: %w", error2.NotFound("product not found")),
+			expectedProblem: Problem{
+				Type:     "/problems/not_found",
+				Title:    "Resource not found",
+				Status:   http.StatusNotFound,
+				Detail:   "obtaining product: product not found",
+				Instance: "/v1/products/FAL-1000001",
+				Code:     CodeNotFound,
+			},
+		},
+		{
+			err: fmt.Errorf("updating product: %w", violations),
+			expectedProblem: Problem{
+				Type:          "/problems/validation_failed",
+				Title:         "Validation failed",
+				Status:        http.StatusBadRequest,
+				Detail:        "updating product: invalid sku",
+				Instance:      "/v1/products/FAL-1000001",
+				Code:          CodeValidationFailed,
+				InvalidParams: violations,
+			},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/v1/products/FAL-1000001", nil)
+
+			problem := problemFrom(c, v.err)
+
+			if !reflect.DeepEqual(v.expectedProblem, problem) {
+				t.Fatalf("expected problem '%+v' unexpected problem '%+v'", v.expectedProblem, problem)
+			}
+		})
+	}
+}
+
+func TestDocs_ProblemType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.GET("/problems/:code", Docs{}.ProblemType)
+
+	for i, v := range problemTypes {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, ProblemTypePath+v.Code, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf(`expected code '%d' unexpected code '%d'`, http.StatusOK, w.Code)
+			}
+
+			problemType := ProblemType{}
+			if err := json.Unmarshal(w.Body.Bytes(), &problemType); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(v, problemType) {
+				t.Fatalf("expected problem type '%+v' unexpected problem type '%+v'", v, problemType)
+			}
+		})
+	}
+}
+
+func TestProblemCodes(t *testing.T) {
+	doc, err := ParseOpenAPI(productsapi.OpenAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := doc.Components.Schemas["ProblemCode"]
+	if schema == nil || schema.Value == nil {
+		t.Fatal("schema 'ProblemCode' is not documented")
+	}
+
+	documented := make(map[string]bool)
+	for _, code := range schema.Value.Enum {
+		documented[code.(string)] = true
+	}
+
+	for i, v := range problemTypes {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if !documented[v.Code] {
+				t.Fatalf(`code '%s' is not documented`, v.Code)
+			}
+
+			if !strings.Contains(schema.Value.Description, "| "+v.Code+" | "+strconv.Itoa(v.Status)+" |") {
+				t.Fatalf(`code '%s' is not described`, v.Code)
+			}
+
+			delete(documented, v.Code)
+		})
+	}
+
+	if len(documented) > 0 {
+		t.Fatalf("documented codes are not supported: %v", documented)
+	}
+}
@@ -0,0 +1,471 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.29.3
+// source: products/v1/products.proto
+
+// products.v1 typed interface of the product storage management for internal services
+
+package productpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Product item sold in the catalog
+type Product struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// sku internal stock-keeping unit, it is the identifier of the product
+	Sku string `protobuf:"bytes,1,opt,name=sku,proto3" json:"sku,omitempty"`
+	// name short description of the product
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// brand name of the brand
+	Brand string `protobuf:"bytes,3,opt,name=brand,proto3" json:"brand,omitempty"`
+	// size product size
+	Size *string `protobuf:"bytes,4,opt,name=size,proto3,oneof" json:"size,omitempty"`
+	// price sell price
+	Price float64 `protobuf:"fixed64,5,opt,name=price,proto3" json:"price,omitempty"`
+	// principal_image URL of the principal image of the product
+	PrincipalImage string `protobuf:"bytes,6,opt,name=principal_image,json=principalImage,proto3" json:"principal_image,omitempty"`
+	// other_images URLs of the other images of the product
+	OtherImages   []string `protobuf:"bytes,7,rep,name=other_images,json=otherImages,proto3" json:"other_images,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Product) Reset() {
+	*x = Product{}
+	mi := &file_products_v1_products_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Product) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Product) ProtoMessage() {}
+
+func (x *Product) ProtoReflect() protoreflect.Message {
+	mi := &file_products_v1_products_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Product.ProtoReflect.Descriptor instead.
+func (*Product) Descriptor() ([]byte, []int) {
+	return file_products_v1_products_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Product) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+func (x *Product) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Product) GetBrand() string {
+	if x != nil {
+		return x.Brand
+	}
+	return ""
+}
+
+func (x *Product) GetSize() string {
+	if x != nil && x.Size != nil {
+		return *x.Size
+	}
+	return ""
+}
+
+func (x *Product) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Product) GetPrincipalImage() string {
+	if x != nil {
+		return x.PrincipalImage
+	}
+	return ""
+}
+
+func (x *Product) GetOtherImages() []string {
+	if x != nil {
+		return x.OtherImages
+	}
+	return nil
+}
+
+type CreateProductRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Product       *Product               `protobuf:"bytes,1,opt,name=product,proto3" json:"product,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateProductRequest) Reset() {
+	*x = CreateProductRequest{}
+	mi := &file_products_v1_products_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateProductRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateProductRequest) ProtoMessage() {}
+
+func (x *CreateProductRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_v1_products_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateProductRequest.ProtoReflect.Descriptor instead.
+func (*CreateProductRequest) Descriptor() ([]byte, []int) {
+	return file_products_v1_products_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateProductRequest) GetProduct() *Product {
+	if x != nil {
+		return x.Product
+	}
+	return nil
+}
+
+type GetProductRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sku           string                 `protobuf:"bytes,1,opt,name=sku,proto3" json:"sku,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetProductRequest) Reset() {
+	*x = GetProductRequest{}
+	mi := &file_products_v1_products_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetProductRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetProductRequest) ProtoMessage() {}
+
+func (x *GetProductRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_v1_products_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetProductRequest.ProtoReflect.Descriptor instead.
+func (*GetProductRequest) Descriptor() ([]byte, []int) {
+	return file_products_v1_products_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetProductRequest) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+type UpdateProductRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Product       *Product               `protobuf:"bytes,1,opt,name=product,proto3" json:"product,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateProductRequest) Reset() {
+	*x = UpdateProductRequest{}
+	mi := &file_products_v1_products_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateProductRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateProductRequest) ProtoMessage() {}
+
+func (x *UpdateProductRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_v1_products_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateProductRequest.ProtoReflect.Descriptor instead.
+func (*UpdateProductRequest) Descriptor() ([]byte, []int) {
+	return file_products_v1_products_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UpdateProductRequest) GetProduct() *Product {
+	if x != nil {
+		return x.Product
+	}
+	return nil
+}
+
+type DeleteProductRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sku           string                 `protobuf:"bytes,1,opt,name=sku,proto3" json:"sku,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteProductRequest) Reset() {
+	*x = DeleteProductRequest{}
+	mi := &file_products_v1_products_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteProductRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteProductRequest) ProtoMessage() {}
+
+func (x *DeleteProductRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_v1_products_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteProductRequest.ProtoReflect.Descriptor instead.
+func (*DeleteProductRequest) Descriptor() ([]byte, []int) {
+	return file_products_v1_products_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DeleteProductRequest) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+type DeleteProductResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteProductResponse) Reset() {
+	*x = DeleteProductResponse{}
+	mi := &file_products_v1_products_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteProductResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteProductResponse) ProtoMessage() {}
+
+func (x *DeleteProductResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_products_v1_products_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteProductResponse.ProtoReflect.Descriptor instead.
+func (*DeleteProductResponse) Descriptor() ([]byte, []int) {
+	return file_products_v1_products_proto_rawDescGZIP(), []int{5}
+}
+
+type ListProductsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProductsRequest) Reset() {
+	*x = ListProductsRequest{}
+	mi := &file_products_v1_products_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProductsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProductsRequest) ProtoMessage() {}
+
+func (x *ListProductsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_products_v1_products_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProductsRequest.ProtoReflect.Descriptor instead.
+func (*ListProductsRequest) Descriptor() ([]byte, []int) {
+	return file_products_v1_products_proto_rawDescGZIP(), []int{6}
+}
+
+var File_products_v1_products_proto protoreflect.FileDescriptor
+
+const file_products_v1_products_proto_rawDesc = "" +
+	"\n" +
+	"\x1aproducts/v1/products.proto\x12\vproducts.v1\"\xc9\x01\n" +
+	"\aProduct\x12\x10\n" +
+	"\x03sku\x18\x01 \x01(\tR\x03sku\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05brand\x18\x03 \x01(\tR\x05brand\x12\x17\n" +
+	"\x04size\x18\x04 \x01(\tH\x00R\x04size\x88\x01\x01\x12\x14\n" +
+	"\x05price\x18\x05 \x01(\x01R\x05price\x12'\n" +
+	"\x0fprincipal_image\x18\x06 \x01(\tR\x0eprincipalImage\x12!\n" +
+	"\fother_images\x18\a \x03(\tR\votherImagesB\a\n" +
+	"\x05_size\"F\n" +
+	"\x14CreateProductRequest\x12.\n" +
+	"\aproduct\x18\x01 \x01(\v2\x14.products.v1.ProductR\aproduct\"%\n" +
+	"\x11GetProductRequest\x12\x10\n" +
+	"\x03sku\x18\x01 \x01(\tR\x03sku\"F\n" +
+	"\x14UpdateProductRequest\x12.\n" +
+	"\aproduct\x18\x01 \x01(\v2\x14.products.v1.ProductR\aproduct\"(\n" +
+	"\x14DeleteProductRequest\x12\x10\n" +
+	"\x03sku\x18\x01 \x01(\tR\x03sku\"\x17\n" +
+	"\x15DeleteProductResponse\"\x15\n" +
+	"\x13ListProductsRequest2\x8a\x03\n" +
+	"\x0eProductService\x12H\n" +
+	"\rCreateProduct\x12!.products.v1.CreateProductRequest\x1a\x14.products.v1.Product\x12B\n" +
+	"\n" +
+	"GetProduct\x12\x1e.products.v1.GetProductRequest\x1a\x14.products.v1.Product\x12H\n" +
+	"\rUpdateProduct\x12!.products.v1.UpdateProductRequest\x1a\x14.products.v1.Product\x12V\n" +
+	"\rDeleteProduct\x12!.products.v1.DeleteProductRequest\x1a\".products.v1.DeleteProductResponse\x12H\n" +
+	"\fListProducts\x12 .products.v1.ListProductsRequest\x1a\x14.products.v1.Product0\x01BJZHgithub.com/yael-castro/products-api/internal/handler/productpb;productpbb\x06proto3"
+
+var (
+	file_products_v1_products_proto_rawDescOnce sync.Once
+	file_products_v1_products_proto_rawDescData []byte
+)
+
+func file_products_v1_products_proto_rawDescGZIP() []byte {
+	file_products_v1_products_proto_rawDescOnce.Do(func() {
+		file_products_v1_products_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_products_v1_products_proto_rawDesc), len(file_products_v1_products_proto_rawDesc)))
+	})
+	return file_products_v1_products_proto_rawDescData
+}
+
+var file_products_v1_products_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_products_v1_products_proto_goTypes = []any{
+	(*Product)(nil),               // 0: products.v1.Product
+	(*CreateProductRequest)(nil),  // 1: products.v1.CreateProductRequest
+	(*GetProductRequest)(nil),     // 2: products.v1.GetProductRequest
+	(*UpdateProductRequest)(nil),  // 3: products.v1.UpdateProductRequest
+	(*DeleteProductRequest)(nil),  // 4: products.v1.DeleteProductRequest
+	(*DeleteProductResponse)(nil), // 5: products.v1.DeleteProductResponse
+	(*ListProductsRequest)(nil),   // 6: products.v1.ListProductsRequest
+}
+var file_products_v1_products_proto_depIdxs = []int32{
+	0, // 0: products.v1.CreateProductRequest.product:type_name -> products.v1.Product
+	0, // 1: products.v1.UpdateProductRequest.product:type_name -> products.v1.Product
+	1, // 2: products.v1.ProductService.CreateProduct:input_type -> products.v1.CreateProductRequest
+	2, // 3: products.v1.ProductService.GetProduct:input_type -> products.v1.GetProductRequest
+	3, // 4: products.v1.ProductService.UpdateProduct:input_type -> products.v1.UpdateProductRequest
+	4, // 5: products.v1.ProductService.DeleteProduct:input_type -> products.v1.DeleteProductRequest
+	6, // 6: products.v1.ProductService.ListProducts:input_type -> products.v1.ListProductsRequest
+	0, // 7: products.v1.ProductService.CreateProduct:output_type -> products.v1.Product
+	0, // 8: products.v1.ProductService.GetProduct:output_type -> products.v1.Product
+	0, // 9: products.v1.ProductService.UpdateProduct:output_type -> products.v1.Product
+	5, // 10: products.v1.ProductService.DeleteProduct:output_type -> products.v1.DeleteProductResponse
+	0, // 11: products.v1.ProductService.ListProducts:output_type -> products.v1.Product
+	7, // [7:12] is the sub-list for method output_type
+	2, // [2:7] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_products_v1_products_proto_init() }
+func file_products_v1_products_proto_init() {
+	if File_products_v1_products_proto != nil {
+		return
+	}
+	file_products_v1_products_proto_msgTypes[0].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_products_v1_products_proto_rawDesc), len(file_products_v1_products_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_products_v1_products_proto_goTypes,
+		DependencyIndexes: file_products_v1_products_proto_depIdxs,
+		MessageInfos:      file_products_v1_products_proto_msgTypes,
+	}.Build()
+	File_products_v1_products_proto = out.File
+	file_products_v1_products_proto_goTypes = nil
+	file_products_v1_products_proto_depIdxs = nil
+}
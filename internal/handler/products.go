@@ -1,10 +1,16 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/yael-castro/products-api/internal/business"
 	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 // _ "implements" constraint for ProductStore
@@ -13,82 +19,511 @@ var _ ProductManager = ProductStore{}
 // ProductStore contains the group of gin.HandlerFunc for handle requests related to management of product storage
 type ProductStore struct {
 	business.ProductManager
+	// MaxUploadSize maximum number of bytes of the images uploaded by UploadImage, if it is zero business.DefaultMediaMaxSize is used.
+	// The request bodies are limited to MaxUploadSize plus multipartOverhead
+	MaxUploadSize int64
 }
 
-// CreateProduct gin.HandlerFunc to handle http requests made to add a product into the storage
+// CreateProduct gin.HandlerFunc to handle http requests made to add a product into the storage,
+// if the product does not have SKU the next free SKU is allocated
+//
+// The products that are likely duplicates of existing products are rejected, the query parameter "force"
+// creates them anyway, e.g. "?force=true"
 func (p ProductStore) CreateProduct(c *gin.Context) {
 	product := model.Product{}
+	ctx := c.Request.Context()
 
-	c.Header("Content-Type", "application/json")
-	err := c.BindJSON(&product)
+	if !accepts(c, product) {
+		return
+	}
+
+	if raw := c.Query(ForceParam); raw != "" {
+		force, err := strconv.ParseBool(raw)
+		if err != nil {
+			handleError(c, error2.Invalid(ForceParam, fmt.Sprintf(`invalid query parameter 'force': '%s' is not a boolean`, raw)))
+			return
+		}
+
+		if force {
+			ctx = business.AllowDuplicates(ctx)
+		}
+	}
+
+	err := bind(c, &product)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	err = p.ProductManager.CreateProduct(&product)
+	err = p.ProductManager.CreateProduct(ctx, &product)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, product)
+	respond(c, http.StatusCreated, product)
 }
 
 // ObtainProduct gin.HandlerFunc to handle http requests made to obtain a product from the storage
+//
+// The query parameter "fields" limits the fields of the product written in the response, e.g. "?fields=sku,name,price"
 func (p ProductStore) ObtainProduct(c *gin.Context) {
 	sku := c.Param("id")
 
-	product, err := p.ProductManager.ObtainProduct(model.SKU(sku))
+	fields, sparse, err := sparseFields(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	product, err := p.ProductManager.ObtainProduct(c.Request.Context(), model.SKU(sku))
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, product)
+	if sparse {
+		respond(c, http.StatusOK, newProjection(fields).product(product))
+		return
+	}
+
+	respond(c, http.StatusOK, product)
 }
 
 // UpdateProduct gin.HandlerFunc to handle http requests made to update existing product in the storage
+//
+// If the price change is held until it is approved, responds 202 with the pending price change and its location
 func (p ProductStore) UpdateProduct(c *gin.Context) {
 	product := model.Product{}
 
-	c.Header("Content-Type", "application/json")
-	err := c.BindJSON(&product)
+	// The pending model.PriceChange is encoded by the same media types as model.Product
+	if !accepts(c, product) {
+		return
+	}
+
+	err := bind(c, &product)
 	if err != nil {
-		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": err.Error()})
+		handleError(c, err)
 		return
 	}
 
-	err = p.ProductManager.UpdateProduct(product)
+	change, err := p.ProductManager.UpdateProduct(c.Request.Context(), product)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, product)
+	if change != nil {
+		c.Header("Location", fmt.Sprintf("%s/%d", PriceChangesPath, change.ID))
+		respond(c, http.StatusAccepted, *change)
+		return
+	}
+
+	respond(c, http.StatusOK, product)
 }
 
 // DeleteProduct gin.HandlerFunc to handle http requests made to remove a product from the storage
 func (p ProductStore) DeleteProduct(c *gin.Context) {
 	sku := c.Param("id")
 
-	err := p.ProductManager.DeleteProduct(model.SKU(sku))
+	if !accepts(c, gin.H{}) {
+		return
+	}
+
+	err := p.ProductManager.DeleteProduct(c.Request.Context(), model.SKU(sku))
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	respond(c, http.StatusOK, gin.H{"message": "ok"})
 }
 
 // ObtainProducts gin.HandlerFunc to handle http requests made to remove a product from the storage
+//
+// The query parameter "fields" limits the fields of the products written in the response, e.g. "?fields=sku,name,price".
+// The query parameter "status" lists the products with those statuses, e.g. "?status=draft,in_review" or "?status=all",
+// by default only the published products are listed.
+// If the query parameter "sku" is present only the products identified by those SKUs are obtained (see BatchGetProducts)
 // TODO: pagination
 func (p ProductStore) ObtainProducts(c *gin.Context) {
-	products, err := p.ProductManager.ListProducts()
+	if values, ok := c.GetQueryArray(SKUParam); ok {
+		skus := make([]model.SKU, 0, len(values))
+
+		for _, value := range values {
+			for _, sku := range strings.Split(value, ",") {
+				skus = append(skus, model.SKU(strings.TrimSpace(sku)))
+			}
+		}
+
+		p.batchGet(c, skus, SKUParam)
+		return
+	}
+
+	fields, sparse, err := sparseFields(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	filter := model.ProductFilter{}
+
+	if raw := c.Query(StatusParam); raw != "" {
+		if filter.Statuses, err = model.ParseStatuses(raw); err != nil {
+			handleError(c, error2.Invalid(StatusParam, "invalid query parameter 'status': "+err.Error()))
+			return
+		}
+	}
+
+	page, err := p.ProductManager.ListProducts(c.Request.Context(), filter)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	if sparse {
+		respond(c, http.StatusOK, newProjection(fields).products(page.Items))
+		return
+	}
+
+	respond(c, http.StatusOK, page.Items)
+}
+
+// SKUParam name of the query parameter that contains the SKUs of a batch request, e.g. "?sku=FAL-1000001,FAL-1000002"
+const SKUParam = "sku"
+
+// StatusParam name of the query parameter that contains the statuses of the listed products, e.g. "?status=draft,in_review"
+const StatusParam = "status"
+
+// PriceChangesPath path of the price changes, the path of each change is followed by its ID (e.g. "/v1/price-changes/1")
+const PriceChangesPath = "/v1/price-changes"
+
+// ForceParam name of the query parameter that creates a product even if it is likely a duplicate, e.g. "?force=true"
+const ForceParam = "force"
+
+// batchGetRequest request body for the batch requests
+type batchGetRequest struct {
+	SKUs []model.SKU `json:"skus" xml:"sku"`
+}
+
+// BatchGetProducts gin.HandlerFunc to handle http requests made to obtain several products at once.
+// The products keep the request order and the SKUs that do not exist are reported apart
+//
+// The query parameter "fields" limits the fields of the products written in the response, e.g. "?fields=sku,name,price"
+func (p ProductStore) BatchGetProducts(c *gin.Context) {
+	request := batchGetRequest{}
+
+	if err := bind(c, &request); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	p.batchGet(c, request.SKUs, "")
+}
+
+// batchGet obtains the products identified by the SKUs and writes the model.ProductBatch,
+// if the SKUs were received in the query parameter param the violations refer to the parameter instead of the request body
+func (p ProductStore) batchGet(c *gin.Context, skus []model.SKU, param string) {
+	fields, sparse, err := sparseFields(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	batch, err := p.ProductManager.ObtainProductBatch(c.Request.Context(), skus)
+	if err != nil {
+		handleError(c, parameterViolations(err, param))
+		return
+	}
+
+	if sparse {
+		respond(c, http.StatusOK, newProjection(fields).batch(batch))
+		return
+	}
+
+	respond(c, http.StatusOK, batch)
+}
+
+// parameterViolations moves the error2.Violations of the request body into the query parameter, the pointers are omitted
+// for the parameters. If the parameter is empty or the error is not error2.Violations the error is returned as is
+func parameterViolations(err error, param string) error {
+	var violations error2.Violations
+	if param == "" || !errors.As(err, &violations) {
+		return err
+	}
+
+	parameters := make(error2.Violations, len(violations))
+
+	for i, violation := range violations {
+		violation.Field, violation.Pointer = param, ""
+		parameters[i] = violation
+	}
+
+	return parameters
+}
+
+// ReserveSKUs gin.HandlerFunc to handle http requests made to reserve a block of SKUs for offline use
+func (p ProductStore) ReserveSKUs(c *gin.Context) {
+	reservation := model.SKUReservation{}
+
+	if !accepts(c, model.SKUBlock{}) {
+		return
+	}
+
+	if err := bind(c, &reservation); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	block, err := p.ProductManager.ReserveSKUs(c.Request.Context(), reservation)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	respond(c, http.StatusCreated, block)
+}
+
+// UploadImagePart name of the multipart/form-data part that contains the uploaded image
+const UploadImagePart = "image"
+
+// multipartOverhead number of bytes allowed in the upload request bodies besides the image (boundaries, part headers and other parts)
+const multipartOverhead = 1 << 20
+
+// UploadImage gin.HandlerFunc to handle http requests made to upload an image of a product as multipart/form-data,
+// the image is streamed from the part UploadImagePart and the query parameter "principal" indicates
+// if it is set as the principal image or appended to the other images
+func (p ProductStore) UploadImage(c *gin.Context) {
+	upload := model.ImageUpload{}
+
+	if !accepts(c, model.Product{}) {
+		return
+	}
+
+	if principal := c.Query("principal"); principal != "" {
+		var err error
+
+		if upload.Principal, err = strconv.ParseBool(principal); err != nil {
+			handleError(c, error2.Invalid("principal", fmt.Sprintf(`invalid principal '%s': it must be a boolean`, principal)))
+			return
+		}
+	}
+
+	maxSize := p.MaxUploadSize
+	if maxSize <= 0 {
+		maxSize = business.DefaultMediaMaxSize
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		handleError(c, error2.UnsupportedMediaType(fmt.Sprintf(`the image must be sent as multipart/form-data: %s`, err)))
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			handleError(c, error2.Invalid(UploadImagePart, fmt.Sprintf(`missing part '%s'`, UploadImagePart)))
+			return
+		}
+
+		if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+			handleError(c, error2.TooLarge(fmt.Sprintf("the request body exceeds the maximum of %d bytes", maxBytesErr.Limit)))
+			return
+		}
+
+		if err != nil {
+			handleError(c, error2.Unprocessable(err.Error()))
+			return
+		}
+
+		if part.FormName() != UploadImagePart {
+			_ = part.Close()
+			continue
+		}
+
+		upload.Filename = part.FileName()
+		upload.Content = part
+		break
+	}
+
+	product, err := p.ProductManager.UploadImage(c.Request.Context(), model.SKU(c.Param("id")), upload)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, product)
+}
+
+// SubmitProduct gin.HandlerFunc to handle http requests made to submit a draft product to review
+func (p ProductStore) SubmitProduct(c *gin.Context) {
+	p.transition(c, model.StatusInReview)
+}
+
+// RejectProduct gin.HandlerFunc to handle http requests made to return a product in review to draft
+func (p ProductStore) RejectProduct(c *gin.Context) {
+	p.transition(c, model.StatusDraft)
+}
+
+// PublishProduct gin.HandlerFunc to handle http requests made to publish a product, the published products are shown in the listings
+func (p ProductStore) PublishProduct(c *gin.Context) {
+	p.transition(c, model.StatusPublished)
+}
+
+// DiscontinueProduct gin.HandlerFunc to handle http requests made to discontinue a published product
+func (p ProductStore) DiscontinueProduct(c *gin.Context) {
+	p.transition(c, model.StatusDiscontinued)
+}
+
+// ArchiveProduct gin.HandlerFunc to handle http requests made to archive a draft or discontinued product
+func (p ProductStore) ArchiveProduct(c *gin.Context) {
+	p.transition(c, model.StatusArchived)
+}
+
+// transition moves the product identified by the path parameter "id" to the status and writes the updated product
+func (p ProductStore) transition(c *gin.Context, status model.Status) {
+	if !accepts(c, model.Product{}) {
+		return
+	}
+
+	product, err := p.ProductManager.TransitionProduct(c.Request.Context(), model.SKU(c.Param("id")), status)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, product)
+}
+
+// ObtainTransitions gin.HandlerFunc to handle http requests made to obtain the status history of a product
+func (p ProductStore) ObtainTransitions(c *gin.Context) {
+	transitions, err := p.ProductManager.ObtainTransitions(c.Request.Context(), model.SKU(c.Param("id")))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, transitions)
+}
+
+// ListPriceChanges gin.HandlerFunc to handle http requests made to list the price changes held until they are approved
+//
+// The query parameter "status" lists the changes with those statuses, e.g. "?status=approved,rejected" or "?status=all",
+// by default only the pending changes are listed. The query parameter "sku" lists only the changes of that product
+func (p ProductStore) ListPriceChanges(c *gin.Context) {
+	filter := model.PriceChangeFilter{SKU: model.SKU(c.Query(SKUParam))}
+
+	if raw := c.Query(StatusParam); raw != "" {
+		var err error
+
+		if filter.Statuses, err = model.ParseChangeStatuses(raw); err != nil {
+			handleError(c, error2.Invalid(StatusParam, "invalid query parameter 'status': "+err.Error()))
+			return
+		}
+	}
+
+	changes, err := p.ProductManager.ListPriceChanges(c.Request.Context(), filter)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, changes)
+}
+
+// ObtainPriceChange gin.HandlerFunc to handle http requests made to obtain the price change identified by the path parameter "id"
+func (p ProductStore) ObtainPriceChange(c *gin.Context) {
+	id, err := priceChangeID(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	change, err := p.ProductManager.ObtainPriceChange(c.Request.Context(), id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, change)
+}
+
+// ApprovePriceChange gin.HandlerFunc to handle http requests made to approve a pending price change, the price of the product is changed
+func (p ProductStore) ApprovePriceChange(c *gin.Context) {
+	p.review(c, model.ChangeApproved)
+}
+
+// RejectPriceChange gin.HandlerFunc to handle http requests made to reject a pending price change, the price of the product is kept
+func (p ProductStore) RejectPriceChange(c *gin.Context) {
+	p.review(c, model.ChangeRejected)
+}
+
+// review decides the status of the price change identified by the path parameter "id" and responds with the reviewed change
+func (p ProductStore) review(c *gin.Context, status model.ChangeStatus) {
+	if !accepts(c, model.PriceChange{}) {
+		return
+	}
+
+	id, err := priceChangeID(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	review := model.PriceReview{}
+
+	if err = bind(c, &review); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	review.ID, review.Status = id, status
+
+	change, err := p.ProductManager.ReviewPriceChange(c.Request.Context(), review)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, change)
+}
+
+// priceChangeID parses the path parameter "id" of the price change
+func priceChangeID(c *gin.Context) (uint, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		return 0, error2.Invalid("id", fmt.Sprintf("invalid price change id '%s'", c.Param("id")))
+	}
+
+	return uint(id), nil
+}
+
+// StartDuplicateReport gin.HandlerFunc to handle http requests made to start the report of the suspected duplicate products,
+// the report is generated in background so it responds with the running report
+func (p ProductStore) StartDuplicateReport(c *gin.Context) {
+	if !accepts(c, model.DuplicateReport{}) {
+		return
+	}
+
+	report, err := p.ProductManager.StartDuplicateReport(c.Request.Context())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	respond(c, http.StatusAccepted, report)
+}
+
+// ObtainDuplicateReport gin.HandlerFunc to handle http requests made to obtain the last report of the suspected duplicate products
+func (p ProductStore) ObtainDuplicateReport(c *gin.Context) {
+	report, err := p.ProductManager.ObtainDuplicateReport(c.Request.Context())
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, products)
+	respond(c, http.StatusOK, report)
 }
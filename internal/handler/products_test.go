@@ -2,16 +2,23 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"github.com/gin-gonic/gin"
+	productsapi "github.com/yael-castro/products-api"
 	"github.com/yael-castro/products-api/internal/business"
 	"github.com/yael-castro/products-api/internal/model"
 	"github.com/yael-castro/products-api/internal/repository"
+	"image"
+	"image/png"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 // verbose cli flag for "V", indicates whether to show additional logs, such as request logs
@@ -30,6 +37,17 @@ func TestProductStore_CreateProduct(t *testing.T) {
 			}(),
 			expectedCode: http.StatusBadRequest,
 		},
+		// The Accept header is checked before creating the product, so the next request is able to create it
+		{
+			request: func() *http.Request {
+				request, _ := http.NewRequest(http.MethodPost, "/v1/products", bytes.NewBuffer([]byte(`{"sku":"FAL-12345677","name":"...","brand":"...","price":1.2,"principalImage":"https://example.com"}`)))
+
+				request.Header.Set("Content-Type", "application/json")
+				request.Header.Set("Accept", "text/csv")
+				return request
+			}(),
+			expectedCode: http.StatusNotAcceptable,
+		},
 		{
 			request: func() *http.Request {
 				request, _ := http.NewRequest(http.MethodPost, "/v1/products", bytes.NewBuffer([]byte(`{
@@ -147,3 +165,528 @@ func TestProductStore_ObtainProduct(t *testing.T) {
 		})
 	}
 }
+
+func TestProductStore_ReserveSKUs(t *testing.T) {
+	tdt := []struct {
+		request      *http.Request
+		expectedCode int
+	}{
+		{
+			request: func() *http.Request {
+				request, _ := http.NewRequest(http.MethodPost, "/v1/skus:reserve", bytes.NewBuffer([]byte(`{"count": 2}`)))
+				request.Header.Set("Content-Type", "application/json")
+				return request
+			}(),
+			expectedCode: http.StatusCreated,
+		},
+		{
+			request: func() *http.Request {
+				request, _ := http.NewRequest(http.MethodPost, "/v1/skus:reserve", bytes.NewBuffer([]byte(`{"count": 0}`)))
+				request.Header.Set("Content-Type", "application/json")
+				return request
+			}(),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			request: func() *http.Request {
+				request, _ := http.NewRequest(http.MethodPost, "/v1/skus:reserve", bytes.NewBuffer([]byte(`{"scheme": "acme", "count": 1}`)))
+				request.Header.Set("Content-Type", "application/json")
+				return request
+			}(),
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	if *verbose {
+		gin.SetMode(gin.DebugMode)
+	}
+
+	store := ProductStore{
+		ProductManager: business.ProductStore{
+			StorageManager: &repository.MockStorage[model.SKU, model.Product]{},
+			Sequences:      &repository.MockSequences{},
+		},
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = v.request
+
+			store.ReserveSKUs(c)
+
+			if w.Code != v.expectedCode {
+				t.Errorf(`expected code '%d' unexpected code '%d'`, v.expectedCode, w.Code)
+			}
+
+			data, err := io.ReadAll(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Log(string(data))
+		})
+	}
+}
+
+func TestProductStore_UploadImage(t *testing.T) {
+	pngImage := bytes.Buffer{}
+	if err := png.Encode(&pngImage, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+
+	// multipartBody builds a multipart/form-data body with a single part
+	multipartBody := func(part string, content []byte) (string, *bytes.Buffer) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+
+		w, _ := writer.CreateFormFile(part, "image.png")
+		_, _ = w.Write(content)
+		_ = writer.Close()
+
+		return writer.FormDataContentType(), body
+	}
+
+	tdt := []struct {
+		path         string
+		part         string
+		content      []byte
+		contentType  string
+		expectedCode int
+	}{
+		{
+			path:         "/v1/products/FAL-12345678/images/upload",
+			part:         "image",
+			content:      pngImage.Bytes(),
+			expectedCode: http.StatusOK,
+		},
+		{
+			path:         "/v1/products/FAL-12345678/images/upload?principal=true",
+			part:         "image",
+			content:      pngImage.Bytes(),
+			expectedCode: http.StatusOK,
+		},
+		{
+			path:         "/v1/products/FAL-12345678/images/upload",
+			part:         "file",
+			content:      pngImage.Bytes(),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			path:         "/v1/products/FAL-12345678/images/upload",
+			part:         "image",
+			content:      []byte("not an image"),
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			path:         "/v1/products/FAL-12345678/images/upload",
+			part:         "image",
+			content:      append(append([]byte{}, pngImage.Bytes()...), make([]byte, 1024)...),
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		// The request body is limited even if the image part is missing
+		{
+			path:         "/v1/products/FAL-12345678/images/upload",
+			part:         "file",
+			content:      make([]byte, 2*multipartOverhead),
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			path:         "/v1/products/FAL-12345678/images/upload?principal=yes",
+			part:         "image",
+			content:      pngImage.Bytes(),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			path:         "/v1/products/FAL-12345678/images/upload",
+			contentType:  "application/json",
+			content:      []byte(`{}`),
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			path:         "/v1/products/FAL-1000001/images/upload",
+			part:         "image",
+			content:      pngImage.Bytes(),
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	if *verbose {
+		gin.SetMode(gin.DebugMode)
+	}
+
+	doc, err := ParseOpenAPI(productsapi.OpenAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principalImage := &model.URL{}
+	if err = principalImage.UnmarshalText([]byte("https://example.com/a.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	storage := &repository.MockStorage[model.SKU, model.Product]{
+		"FAL-12345678": model.Product{SKU: "FAL-12345678", Name: "Shoes", Brand: "Nike", Price: 10.5, PrincipalImage: principalImage, Status: model.StatusDraft},
+	}
+
+	ingester, err := business.NewImageIngester(business.MediaConfig{BaseURL: "https://api.example.com", MaxSize: 1024}, storage, &repository.MockImages{Storage: storage}, &repository.MockBlobs{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = ingester.Close(context.Background()) }()
+
+	store := ProductStore{
+		ProductManager: business.ProductStore{StorageManager: storage, Media: ingester},
+		MaxUploadSize:  ingester.MaxSize(),
+	}
+
+	engine := newEngine(Groups{ProductManager: store, MediaServer: MediaStore{}, Monitor: Monitoring{Health: &Health{}}, Documentation: NewDocs(doc), GraphQLExecutor: &GraphQLSchema{}}, OpenAPIValidator(doc, OpenAPIOptions{ValidateResponses: true}))
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			contentType, body := v.contentType, bytes.NewBuffer(v.content)
+			if contentType == "" {
+				contentType, body = multipartBody(v.part, v.content)
+			}
+
+			request, _ := http.NewRequest(http.MethodPost, v.path, body)
+			request.Header.Set("Content-Type", contentType)
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, request)
+
+			if w.Code != v.expectedCode {
+				t.Fatalf(`expected code '%d' unexpected code '%d': %s`, v.expectedCode, w.Code, w.Body.String())
+			}
+
+			t.Log(w.Body.String())
+		})
+	}
+}
+
+func TestProductStore_TransitionProduct(t *testing.T) {
+	tdt := []struct {
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products/FAL-12345678/submit",
+			expectedCode: http.StatusOK,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products/FAL-12345678/archive",
+			expectedCode: http.StatusConflict,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products/FAL-12345678/publish",
+			expectedCode: http.StatusOK,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products/FAL-12345678/discontinue",
+			expectedCode: http.StatusOK,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products/FAL-12345678/reject",
+			expectedCode: http.StatusConflict,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products/FAL-1000001/submit",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			method:       http.MethodGet,
+			path:         "/v1/products/FAL-12345678/transitions",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	if *verbose {
+		gin.SetMode(gin.DebugMode)
+	}
+
+	doc, err := ParseOpenAPI(productsapi.OpenAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principalImage := &model.URL{}
+	if err = principalImage.UnmarshalText([]byte("https://example.com/a.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	storage := &repository.MockStorage[model.SKU, model.Product]{
+		"FAL-12345678": model.Product{SKU: "FAL-12345678", Name: "Shoes", Brand: "Nike", Price: 10.5, PrincipalImage: principalImage, Status: model.StatusDraft},
+	}
+
+	store := ProductStore{
+		ProductManager: business.ProductStore{StorageManager: storage, Lifecycle: &repository.MockLifecycle{Storage: storage}},
+	}
+
+	engine := newEngine(Groups{ProductManager: store, MediaServer: MediaStore{}, Monitor: Monitoring{Health: &Health{}}, Documentation: NewDocs(doc), GraphQLExecutor: &GraphQLSchema{}}, OpenAPIValidator(doc, OpenAPIOptions{ValidateResponses: true}))
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			request, _ := http.NewRequest(v.method, v.path, nil)
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, request)
+
+			if w.Code != v.expectedCode {
+				t.Fatalf(`expected code '%d' unexpected code '%d': %s`, v.expectedCode, w.Code, w.Body.String())
+			}
+
+			t.Log(w.Body.String())
+		})
+	}
+}
+
+func TestProductStore_ReviewPriceChange(t *testing.T) {
+	tdt := []struct {
+		method           string
+		path             string
+		body             string
+		expectedCode     int
+		expectedLocation string
+	}{
+		{
+			method:       http.MethodPut,
+			path:         "/v1/products",
+			body:         `{"sku":"FAL-12345678","name":"Shoes","brand":"Nike","price":1050,"principalImage":"https://example.com/a.png"}`,
+			expectedCode: http.StatusAccepted,
+			// The pending price change is responded
+			expectedLocation: "/v1/price-changes/1",
+		},
+		{
+			method:       http.MethodGet,
+			path:         "/v1/price-changes/1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			method:       http.MethodGet,
+			path:         "/v1/price-changes/2",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			method:       http.MethodGet,
+			path:         "/v1/price-changes?status=pending&sku=FAL-12345678",
+			expectedCode: http.StatusOK,
+		},
+		{
+			method:       http.MethodGet,
+			path:         "/v1/price-changes?status=open",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/price-changes/1/reject",
+			body:         `{}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/price-changes/2/approve",
+			body:         `{}`,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/price-changes/1/approve",
+			body:         `{"comment":"New collection"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/price-changes/1/reject",
+			body:         `{"comment":"Too late"}`,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			method:       http.MethodGet,
+			path:         "/v1/price-changes?status=all",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	if *verbose {
+		gin.SetMode(gin.DebugMode)
+	}
+
+	doc, err := ParseOpenAPI(productsapi.OpenAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principalImage := &model.URL{}
+	if err = principalImage.UnmarshalText([]byte("https://example.com/a.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	storage := &repository.MockStorage[model.SKU, model.Product]{
+		"FAL-12345678": model.Product{SKU: "FAL-12345678", Name: "Shoes", Brand: "Nike", Price: 10.5, PrincipalImage: principalImage, Status: model.StatusPublished},
+	}
+
+	store := ProductStore{
+		ProductManager: business.ProductStore{
+			StorageManager: storage,
+			Approvals:      &repository.MockApprovals{Storage: storage},
+			PriceApproval:  business.PriceApproval{Percentage: 50},
+		},
+	}
+
+	// The price changes are requested by the editor and reviewed by the manager
+	caller := func(c *gin.Context) {
+		subject := "manager"
+		if c.Request.Method == http.MethodPut {
+			subject = "editor"
+		}
+
+		c.Request = c.Request.WithContext(business.WithCaller(c.Request.Context(), model.Caller{Subject: subject}))
+	}
+
+	engine := newEngine(Groups{ProductManager: store, MediaServer: MediaStore{}, Monitor: Monitoring{Health: &Health{}}, Documentation: NewDocs(doc), GraphQLExecutor: &GraphQLSchema{}}, caller, OpenAPIValidator(doc, OpenAPIOptions{ValidateResponses: true}))
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			request, _ := http.NewRequest(v.method, v.path, strings.NewReader(v.body))
+			if v.body != "" {
+				request.Header.Set("Content-Type", "application/json")
+			}
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, request)
+
+			if w.Code != v.expectedCode {
+				t.Fatalf(`expected code '%d' unexpected code '%d': %s`, v.expectedCode, w.Code, w.Body.String())
+			}
+
+			if location := w.Header().Get("Location"); location != v.expectedLocation {
+				t.Fatalf(`expected location '%s' unexpected location '%s'`, v.expectedLocation, location)
+			}
+
+			t.Log(w.Body.String())
+		})
+	}
+
+	if product, _ := storage.Obtain(context.Background(), "FAL-12345678"); product.Price != 1050 {
+		t.Fatalf("expected price '1050' unexpected price '%v'", product.Price)
+	}
+}
+
+func TestProductStore_Duplicates(t *testing.T) {
+	tdt := []struct {
+		method       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{
+			method:       http.MethodGet,
+			path:         "/v1/reports/duplicates",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products",
+			body:         `{"sku":"FAL-12345679","name":"NIKE air-max 90","brand":"NIKE","price":10.5,"principalImage":"https://example.com/a.png"}`,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products?force=yes",
+			body:         `{"sku":"FAL-12345679","name":"NIKE air-max 90","brand":"NIKE","price":10.5,"principalImage":"https://example.com/a.png"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/products?force=true",
+			body:         `{"sku":"FAL-12345679","name":"NIKE air-max 90","brand":"NIKE","price":10.5,"principalImage":"https://example.com/a.png"}`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			method:       http.MethodPost,
+			path:         "/v1/reports/duplicates",
+			expectedCode: http.StatusAccepted,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	if *verbose {
+		gin.SetMode(gin.DebugMode)
+	}
+
+	doc, err := ParseOpenAPI(productsapi.OpenAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principalImage := &model.URL{}
+	if err = principalImage.UnmarshalText([]byte("https://example.com/a.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	storage := &repository.MockStorage[model.SKU, model.Product]{
+		"FAL-12345678": model.Product{SKU: "FAL-12345678", Name: "Nike Air Max 90", Brand: "Nike", Price: 10.5, PrincipalImage: principalImage, Status: model.StatusPublished},
+		"FAL-12345677": model.Product{SKU: "FAL-12345677", Name: "Nike air max 90", Brand: "Nike", Price: 10.5, PrincipalImage: principalImage, Status: model.StatusDraft},
+	}
+
+	detector, err := business.NewDuplicateDetector(0, storage, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := ProductStore{
+		ProductManager: business.ProductStore{
+			StorageManager: storage,
+			Duplicates:     detector,
+		},
+	}
+
+	engine := newEngine(Groups{ProductManager: store, MediaServer: MediaStore{}, Monitor: Monitoring{Health: &Health{}}, Documentation: NewDocs(doc), GraphQLExecutor: &GraphQLSchema{}}, OpenAPIValidator(doc, OpenAPIOptions{ValidateResponses: true}))
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			request, _ := http.NewRequest(v.method, v.path, strings.NewReader(v.body))
+			if v.body != "" {
+				request.Header.Set("Content-Type", "application/json")
+			}
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, request)
+
+			if w.Code != v.expectedCode {
+				t.Fatalf(`expected code '%d' unexpected code '%d': %s`, v.expectedCode, w.Code, w.Body.String())
+			}
+
+			t.Log(w.Body.String())
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err = detector.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, "/v1/reports/duplicates", nil)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, request)
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"completed"`) {
+		t.Fatalf("unexpected report '%d': %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yael-castro/products-api/internal/business"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"reflect"
+)
+
+// FieldsParam name of the query parameter that contains the sparse fieldset, e.g. "?fields=sku,name,price"
+const FieldsParam = "fields"
+
+// sparseFields parses the sparse fieldset requested by the client, the bool value is false if every field was requested
+//
+// The sparse fieldset is stored into the request context.Context in order to push it down to the storage
+func sparseFields(c *gin.Context) (model.Fields, bool, error) {
+	raw, ok := c.GetQuery(FieldsParam)
+	if !ok || raw == "" {
+		return nil, false, nil
+	}
+
+	fields, err := model.ParseFields(raw)
+	if err != nil {
+		return nil, false, error2.Invalid(FieldsParam, "invalid query parameter 'fields': "+err.Error())
+	}
+
+	c.Request = c.Request.WithContext(business.WithFields(c.Request.Context(), fields))
+	return fields, true, nil
+}
+
+// projection builds values that contain only the fields of a sparse fieldset.
+// The struct tags of model.Product are kept, that way every encoder writes the same names that it writes for model.Product
+type projection struct {
+	fields     model.Fields
+	index      [][]int
+	structType reflect.Type
+}
+
+// newProjection builds the projection for the sparse fieldset
+func newProjection(fields model.Fields) projection {
+	structFields := fields.StructFields()
+
+	p := projection{
+		fields: fields,
+		index:  make([][]int, 0, len(structFields)),
+	}
+
+	projected := make([]reflect.StructField, 0, len(structFields))
+
+	for _, field := range structFields {
+		p.index = append(p.index, field.Index)
+		projected = append(projected, reflect.StructField{Name: field.Name, Type: field.Type, Tag: field.Tag})
+	}
+
+	p.structType = reflect.StructOf(projected)
+	return p
+}
+
+// product returns the sparse representation of the model.Product
+func (p projection) product(product model.Product) sparseProduct {
+	src := reflect.ValueOf(product)
+	dst := reflect.New(p.structType).Elem()
+
+	for i, index := range p.index {
+		dst.Field(i).Set(src.FieldByIndex(index))
+	}
+
+	return sparseProduct{fields: p.fields, value: dst.Interface()}
+}
+
+// products returns the sparse representation of the model.Products
+func (p projection) products(products model.Products) sparseProducts {
+	values := make([]any, 0, len(products))
+
+	for _, product := range products {
+		values = append(values, p.product(product).value)
+	}
+
+	return sparseProducts{fields: p.fields, products: products, values: values}
+}
+
+// batch returns the sparse representation of the model.ProductBatch
+func (p projection) batch(batch model.ProductBatch) sparseBatch {
+	return sparseBatch{
+		Products: p.products(batch.Products).values,
+		Missing:  batch.Missing,
+	}
+}
+
+// sparser values whose representation is a projection of the original value
+type sparser interface {
+	sparse() any
+}
+
+// sparseProduct model.Product that contains only the fields of a sparse fieldset
+type sparseProduct struct {
+	fields model.Fields
+	value  any
+}
+
+// sparse returns the projected value
+func (s sparseProduct) sparse() any {
+	return s.value
+}
+
+// sparseProducts model.Products that contain only the fields of a sparse fieldset
+type sparseProducts struct {
+	fields   model.Fields
+	products model.Products
+	values   []any
+}
+
+// sparse returns the projected values
+func (s sparseProducts) sparse() any {
+	return s.values
+}
+
+// sparseBatch model.ProductBatch whose products contain only the fields of a sparse fieldset
+type sparseBatch struct {
+	Products []any       `json:"products" xml:"products>product"`
+	Missing  []model.SKU `json:"missing" xml:"missing>sku"`
+}
+
+// unwrapSparse returns the projected value if v is a sparse representation, otherwise v is returned
+func unwrapSparse(v any) any {
+	if s, ok := v.(sparser); ok {
+		return s.sparse()
+	}
+
+	return v
+}
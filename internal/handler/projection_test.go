@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yael-castro/products-api/internal/business"
+	"github.com/yael-castro/products-api/internal/model"
+	"github.com/yael-castro/products-api/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestProductStore_SparseFields(t *testing.T) {
+	tdt := []struct {
+		target       string
+		accept       string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			target:       "/v1/products/FAL-12345678?fields=sku,name,price",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"sku":"FAL-12345678","name":"Shoes","price":10.5}`,
+		},
+		{
+			target:       "/v1/products/FAL-12345678?fields=price,sku,price",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"price":10.5,"sku":"FAL-12345678"}`,
+		},
+		{
+			target:       "/v1/products/FAL-12345678?fields=sku,size",
+			accept:       "application/xml",
+			expectedCode: http.StatusOK,
+			expectedBody: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<product><sku>FAL-12345678</sku></product>`,
+		},
+		{
+			target:       "/v1/products?fields=sku,otherImages",
+			accept:       "application/xml",
+			expectedCode: http.StatusOK,
+			expectedBody: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<products><product><sku>FAL-12345678</sku><otherImages><image>https://example.com</image></otherImages></product></products>`,
+		},
+		{
+			target:       "/v1/products?fields=name,sku",
+			accept:       MIMECSV,
+			expectedCode: http.StatusOK,
+			expectedBody: "name,sku\nShoes,FAL-12345678\n",
+		},
+		{
+			target:       "/v1/products?fields=",
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"sku":"FAL-12345678","name":"Shoes","brand":"Nike","size":null,"price":10.5,"principalImage":"https://example.com","otherImages":["https://example.com"],"status":"published"}]`,
+		},
+		{
+			target:       "/v1/products?fields=sku&status=draft,in_review",
+			expectedCode: http.StatusOK,
+			expectedBody: `[]`,
+		},
+		{
+			target:       "/v1/products?fields=sku,status&status=all",
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"sku":"FAL-12345678","status":"published"}]`,
+		},
+		{
+			target:       "/v1/products?status=live",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			target:       "/v1/products?fields=sku,weight",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			target:       "/v1/products/FAL-12345678?fields=sku,,name",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	image, _ := url.Parse("https://example.com")
+
+	store := ProductStore{
+		ProductManager: business.ProductStore{
+			StorageManager: &repository.MockStorage[model.SKU, model.Product]{
+				"FAL-12345678": model.Product{
+					SKU:            "FAL-12345678",
+					Name:           "Shoes",
+					Brand:          "Nike",
+					Price:          10.5,
+					PrincipalImage: &model.URL{URL: image},
+					OtherImages:    model.URLs{{URL: image}},
+					Status:         model.StatusPublished,
+				},
+			},
+		},
+	}
+
+	engine := gin.New()
+	engine.GET("/v1/products", store.ObtainProducts)
+	engine.GET("/v1/products/:id", store.ObtainProduct)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, v.target, nil)
+			if v.accept != "" {
+				request.Header.Set("Accept", v.accept)
+			}
+
+			w := httptest.NewRecorder()
+
+			engine.ServeHTTP(w, request)
+
+			if w.Code != v.expectedCode {
+				t.Fatalf(`expected code '%d' unexpected code '%d' (%s)`, v.expectedCode, w.Code, w.Body)
+			}
+
+			if body := strings.TrimSpace(w.Body.String()); v.expectedBody != "" && body != strings.TrimSpace(v.expectedBody) {
+				t.Fatalf(`expected body '%s' unexpected body '%s'`, v.expectedBody, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/yael-castro/products-api/internal/business"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limit token bucket settings, the zero value means no limit
+type Limit struct {
+	// Rate tokens added to the bucket per second
+	Rate float64 `yaml:"rate"`
+	// Burst capacity of the bucket, it is the maximum number of requests allowed at once
+	Burst int `yaml:"burst"`
+}
+
+// IsZero indicates that the Limit does not limit anything
+func (l Limit) IsZero() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+// RouteLimits limits for read routes (GET, HEAD and OPTIONS) and write routes (any other method)
+type RouteLimits struct {
+	Read  Limit `yaml:"read"`
+	Write Limit `yaml:"write"`
+}
+
+// override returns the limits replaced by the non-zero limits of the override, the routes that the override does not set keep their limits
+func (r RouteLimits) override(override RouteLimits) RouteLimits {
+	if !override.Read.IsZero() {
+		r.Read = override.Read
+	}
+
+	if !override.Write.IsZero() {
+		r.Write = override.Write
+	}
+
+	return r
+}
+
+// RateLimitConfig configuration for the RateLimit middleware
+type RateLimitConfig struct {
+	// RouteLimits default limits for every client
+	RouteLimits `yaml:",inline"`
+	// Keys limits for specific clients indexed by the API key name (or JWT subject), they override the default limits
+	// of the routes they set (e.g. a key that only sets read keeps the default write limit)
+	Keys map[string]RouteLimits `yaml:"keys"`
+	// Authentication limit for the requests with credentials of each IP address, it is applied before authenticating
+	// the requests so the credentials could not be guessed without limit (see AuthenticationRateLimit)
+	Authentication Limit `yaml:"authentication"`
+}
+
+// Reservation result of taking a token from a bucket
+type Reservation struct {
+	// Allowed indicates if the bucket had a token available
+	Allowed bool
+	// Remaining tokens available after the reservation
+	Remaining int
+	// Reset time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter time until a token is available, it is zero if the request was allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitStore stores the token buckets, it is abstracted to allow shared stores between server instances
+type RateLimitStore interface {
+	// Take takes a token from the bucket identified by the key
+	Take(ctx context.Context, key string, limit Limit) (Reservation, error)
+}
+
+// bucketTTL time that a full bucket is kept in memory after its last use
+const bucketTTL = 10 * time.Minute
+
+// _ "implements" constraint for *MemoryRateLimitStore
+var _ RateLimitStore = (*MemoryRateLimitStore)(nil)
+
+// MemoryRateLimitStore stores the token buckets in memory, it is only suitable for a single server instance
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	// now returns the current time, it is replaced in tests
+	now func() time.Time
+}
+
+// bucket state of a token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewMemoryRateLimitStore builds an empty *MemoryRateLimitStore
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Take refills the bucket based on the time elapsed since its last use and then takes a token if it is available
+func (m *MemoryRateLimitStore) Take(_ context.Context, key string, limit Limit) (Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+
+	reservation := Reservation{}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		reservation.Allowed = true
+	} else {
+		reservation.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+
+	reservation.Remaining = int(b.tokens)
+	reservation.Reset = seconds((float64(limit.Burst) - b.tokens) / limit.Rate)
+
+	return reservation, nil
+}
+
+// sweep removes the buckets that have not been used during bucketTTL, at most once per bucketTTL
+func (m *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < bucketTTL {
+		return
+	}
+
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if now.Sub(b.updated) > bucketTTL {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+// seconds converts a float number of seconds to time.Duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// RateLimit middleware that limits the requests of each client using token buckets, read and write routes have separate buckets
+//
+// Clients are identified by the caller subject (API key name or JWT subject) or by the IP address for anonymous clients,
+// so the middleware must be used after the Authentication middleware.
+// The RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers are written for every limited request,
+// and the requests that exceed the limit are rejected with 429 status code and the Retry-After header
+func RateLimit(store RateLimitStore, config RateLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := "ip:" + c.ClientIP()
+		limits := config.RouteLimits
+
+		if caller, ok := business.CallerFrom(c.Request.Context()); ok {
+			client = "caller:" + caller.Subject
+
+			if override, ok := config.Keys[caller.Subject]; ok {
+				limits = limits.override(override)
+			}
+		}
+
+		route, limit := "write", limits.Write
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			route, limit = "read", limits.Read
+		}
+
+		if limit.IsZero() {
+			c.Next()
+			return
+		}
+
+		if !take(c, store, route+":"+client, limit) {
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AuthenticationRateLimit middleware that limits the requests with credentials (API key or authorization header)
+// of each IP address using token buckets, the requests without credentials are not limited
+//
+// The middleware must be used before the Authentication middleware, that way every attempt to guess the credentials
+// takes a token even if it is rejected with 401 status code
+func AuthenticationRateLimit(store RateLimitStore, limit Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit.IsZero() || (c.GetHeader(APIKeyHeader) == "" && c.GetHeader("Authorization") == "") {
+			c.Next()
+			return
+		}
+
+		if !take(c, store, "authentication:ip:"+c.ClientIP(), limit) {
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// take takes a token from the bucket identified by the key and writes the RateLimit headers,
+// if the limit is exceeded it responds with 429 status code and returns false
+func take(c *gin.Context, store RateLimitStore, key string, limit Limit) bool {
+	reservation, err := store.Take(c.Request.Context(), key, limit)
+	if err != nil {
+		// The requests are allowed when the store is not available to avoid an outage caused by the rate limiter
+		_ = c.Error(err)
+		return true
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+	c.Header("RateLimit-Remaining", strconv.Itoa(reservation.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(reservation.Reset)))
+
+	if !reservation.Allowed {
+		retryAfter := ceilSeconds(reservation.RetryAfter)
+
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		handleError(c, error2.TooManyRequests(fmt.Sprintf("rate limit exceeded, retry after %d seconds", retryAfter)))
+		return false
+	}
+
+	return true
+}
+
+// ceilSeconds rounds up the time.Duration to seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yael-castro/products-api/internal/business"
+	"github.com/yael-castro/products-api/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	type request struct {
+		method             string
+		caller             string
+		elapsed            time.Duration
+		expectedCode       int
+		expectedRemaining  string
+		expectedRetryAfter string
+	}
+
+	tdt := []struct {
+		requests []request
+	}{
+		// Burst exhausted and then refilled
+		{
+			requests: []request{
+				{method: http.MethodGet, expectedCode: http.StatusOK, expectedRemaining: "1"},
+				{method: http.MethodGet, expectedCode: http.StatusOK, expectedRemaining: "0"},
+				{method: http.MethodGet, expectedCode: http.StatusTooManyRequests, expectedRemaining: "0", expectedRetryAfter: "1"},
+				{method: http.MethodGet, elapsed: time.Second, expectedCode: http.StatusOK, expectedRemaining: "0"},
+			},
+		},
+		// Read and write routes have separate buckets
+		{
+			requests: []request{
+				{method: http.MethodPost, expectedCode: http.StatusOK, expectedRemaining: "0"},
+				{method: http.MethodPost, expectedCode: http.StatusTooManyRequests, expectedRetryAfter: "10", expectedRemaining: "0"},
+				{method: http.MethodGet, expectedCode: http.StatusOK, expectedRemaining: "1"},
+			},
+		},
+		// Per-key overrides
+		{
+			requests: []request{
+				{method: http.MethodPost, caller: "erp", expectedCode: http.StatusOK, expectedRemaining: "2"},
+				{method: http.MethodPost, caller: "erp", expectedCode: http.StatusOK, expectedRemaining: "1"},
+				{method: http.MethodPost, caller: "other", expectedCode: http.StatusOK, expectedRemaining: "0"},
+				{method: http.MethodPost, caller: "other", expectedCode: http.StatusTooManyRequests, expectedRetryAfter: "10", expectedRemaining: "0"},
+			},
+		},
+		// The overrides that only set the read limit keep the default write limit
+		{
+			requests: []request{
+				{method: http.MethodGet, caller: "reader", expectedCode: http.StatusOK, expectedRemaining: "4"},
+				{method: http.MethodPost, caller: "reader", expectedCode: http.StatusOK, expectedRemaining: "0"},
+				{method: http.MethodPost, caller: "reader", expectedCode: http.StatusTooManyRequests, expectedRetryAfter: "10", expectedRemaining: "0"},
+			},
+		},
+	}
+
+	config := RateLimitConfig{
+		RouteLimits: RouteLimits{
+			Read:  Limit{Rate: 1, Burst: 2},
+			Write: Limit{Rate: 0.1, Burst: 1},
+		},
+		Keys: map[string]RouteLimits{
+			"erp":    {Write: Limit{Rate: 1, Burst: 3}},
+			"reader": {Read: Limit{Rate: 1, Burst: 5}},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			now := time.Now()
+
+			store := NewMemoryRateLimitStore()
+			store.now = func() time.Time {
+				return now
+			}
+
+			engine := gin.New()
+			engine.Use(func(c *gin.Context) {
+				if caller := c.GetHeader("X-Caller"); caller != "" {
+					c.Request = c.Request.WithContext(business.WithCaller(c.Request.Context(), model.Caller{Subject: caller}))
+				}
+			})
+			engine.Use(RateLimit(store, config))
+			engine.Any("/", func(c *gin.Context) {})
+
+			for j, r := range v.requests {
+				now = now.Add(r.elapsed)
+
+				request, _ := http.NewRequest(r.method, "/", nil)
+				request.Header.Set("X-Caller", r.caller)
+
+				w := httptest.NewRecorder()
+				engine.ServeHTTP(w, request)
+
+				if w.Code != r.expectedCode {
+					t.Fatalf(`request %d: expected code '%d' unexpected code '%d'`, j, r.expectedCode, w.Code)
+				}
+
+				if remaining := w.Header().Get("RateLimit-Remaining"); remaining != r.expectedRemaining {
+					t.Fatalf(`request %d: expected remaining '%s' unexpected remaining '%s'`, j, r.expectedRemaining, remaining)
+				}
+
+				if retryAfter := w.Header().Get("Retry-After"); retryAfter != r.expectedRetryAfter {
+					t.Fatalf(`request %d: expected retry after '%s' unexpected retry after '%s'`, j, r.expectedRetryAfter, retryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthenticationRateLimit(t *testing.T) {
+	tdt := []struct {
+		apiKey       string
+		expectedCode int
+	}{
+		// The requests without credentials are not limited
+		{
+			expectedCode: http.StatusOK,
+		},
+		{
+			apiKey:       "invalid",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			apiKey:       "invalid",
+			expectedCode: http.StatusUnauthorized,
+		},
+		// The invalid credentials took every token
+		{
+			apiKey:       "invalid",
+			expectedCode: http.StatusTooManyRequests,
+		},
+		{
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	authenticator, err := NewAuthenticator(AuthConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(AuthenticationRateLimit(NewMemoryRateLimitStore(), Limit{Rate: 0.1, Burst: 2}), Authentication(authenticator))
+	engine.GET("/", func(c *gin.Context) {})
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			request, _ := http.NewRequest(http.MethodGet, "/", nil)
+			if v.apiKey != "" {
+				request.Header.Set(APIKeyHeader, v.apiKey)
+			}
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, request)
+
+			if w.Code != v.expectedCode {
+				t.Fatalf(`expected code '%d' unexpected code '%d': %s`, v.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// InstrumentationName name of the instrumentation scope used by the tracers of the presentation layer
+const InstrumentationName = "github.com/yael-castro/products-api/internal/handler"
+
+// Tracing middleware that starts a server span for each request
+//
+// If the request contains the W3C traceparent header, the span is a child of the remote span.
+// The span is carried by the request context.Context, so the spans started by other layers are its children
+func Tracing(tracer trace.Tracer, propagator propagation.TextMapPropagator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		ctx, span := tracer.Start(
+			ctx,
+			c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+	}
+}
+
+// startSpan starts a child span of the span carried by ctx using the same trace.TracerProvider,
+// if ctx does not carry any span the started span is a no-op span
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(InstrumentationName).Start(ctx, name)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestTracing(t *testing.T) {
+	tdt := []struct {
+		path            string
+		traceparent     string
+		expectedName    string
+		expectedTraceID string
+		expectedParent  string
+	}{
+		{
+			path:         "/v1/products/FAL-12345678",
+			expectedName: "GET /v1/products/:id",
+		},
+		{
+			path:            "/v1/products/FAL-12345678",
+			traceparent:     "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expectedName:    "GET /v1/products/:id",
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedParent:  "00f067aa0ba902b7",
+		},
+		{
+			path:         "/unknown/path",
+			expectedName: "GET unmatched",
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			engine := gin.New()
+			engine.Use(Tracing(provider.Tracer(InstrumentationName), propagation.TraceContext{}))
+			engine.GET("/v1/products/:id", func(c *gin.Context) {
+				_, span := startSpan(c.Request.Context(), "child")
+				span.End()
+			})
+
+			request, _ := http.NewRequest(http.MethodGet, v.path, nil)
+			if v.traceparent != "" {
+				request.Header.Set("traceparent", v.traceparent)
+			}
+
+			engine.ServeHTTP(httptest.NewRecorder(), request)
+
+			var server sdktrace.ReadOnlySpan
+
+			for _, span := range recorder.Ended() {
+				if span.SpanKind() == trace.SpanKindServer {
+					server = span
+				}
+			}
+
+			if server == nil {
+				t.Fatal("missing server span")
+			}
+
+			if server.Name() != v.expectedName {
+				t.Fatalf(`expected span name '%s' got '%s'`, v.expectedName, server.Name())
+			}
+
+			if v.expectedTraceID != "" && server.SpanContext().TraceID().String() != v.expectedTraceID {
+				t.Fatalf(`expected trace id '%s' got '%s'`, v.expectedTraceID, server.SpanContext().TraceID())
+			}
+
+			if v.expectedParent != "" && server.Parent().SpanID().String() != v.expectedParent {
+				t.Fatalf(`expected parent span id '%s' got '%s'`, v.expectedParent, server.Parent().SpanID())
+			}
+
+			for _, span := range recorder.Ended() {
+				if span.Name() == "child" && span.Parent().SpanID() != server.SpanContext().SpanID() {
+					t.Fatal("child span is not a child of the server span")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Package logging contains everything related to structured logging, it is shared by every layer to correlate log records
+package logging
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log/slog"
+)
+
+// requestIDKey context key for the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx that carries the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, if ctx does not carry any request ID returns an empty string
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ParseLevel parses the names of log levels ("debug", "info", "warn" and "error"), if the name is blank returns slog.LevelInfo
+func ParseLevel(name string) (level slog.Level, err error) {
+	if name == "" {
+		return slog.LevelInfo, nil
+	}
+
+	err = level.UnmarshalText([]byte(name))
+	return
+}
+
+// NewLogger builds a *slog.Logger that writes JSON records into w
+//
+// Each record logged using a context.Context includes the request ID and the trace ID carried by the context.Context
+func NewLogger(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(contextHandler{
+		Handler: slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}),
+	})
+}
+
+// _ "implements" constraint for contextHandler
+var _ slog.Handler = contextHandler{}
+
+// contextHandler decorates a slog.Handler to add the values carried by context.Context to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle adds the request ID and the trace ID to the record (if any) and then delegates to the decorated slog.Handler
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()), slog.String("span_id", spanContext.SpanID().String()))
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs decorates the slog.Handler returned by the decorated slog.Handler
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup decorates the slog.Handler returned by the decorated slog.Handler
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithGroup(name)}
+}
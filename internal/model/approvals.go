@@ -0,0 +1,81 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// ChangeStatus stage of the review of a PriceChange
+type ChangeStatus string
+
+// Supported values of ChangeStatus
+const (
+	// ChangePending the change is waiting to be reviewed, the price of the product is not changed yet
+	ChangePending ChangeStatus = "pending"
+	// ChangeApproved the change was approved and the price of the product was changed
+	ChangeApproved ChangeStatus = "approved"
+	// ChangeRejected the change was rejected, the price of the product was kept
+	ChangeRejected ChangeStatus = "rejected"
+)
+
+// ChangeStatuses every supported ChangeStatus
+var ChangeStatuses = []ChangeStatus{ChangePending, ChangeApproved, ChangeRejected}
+
+// IsValid indicates if the ChangeStatus is one of the supported ChangeStatuses
+func (c ChangeStatus) IsValid() bool {
+	return slices.Contains(ChangeStatuses, c)
+}
+
+// ParseChangeStatuses parses a comma-separated list of change statuses, "all" means every supported ChangeStatus.
+// The duplicated statuses are ignored
+func ParseChangeStatuses(s string) ([]ChangeStatus, error) {
+	return parseEnum(s, ChangeStatuses, "status")
+}
+
+// PriceChange request to change the price of a Product that must be approved before being applied,
+// the requester and the reviewer are recorded
+type PriceChange struct {
+	// ID identifier of the change
+	ID uint `json:"id" xml:"id" gorm:"primaryKey"`
+	// SKU identifier of the product
+	SKU SKU `json:"sku" xml:"sku" gorm:"type:varchar;not null;index"`
+	// CurrentPrice price of the product when the change was requested
+	CurrentPrice float64 `json:"currentPrice" xml:"currentPrice" gorm:"type:decimal;not null"`
+	// Price requested price of the product
+	Price float64 `json:"price" xml:"price" gorm:"type:decimal;not null"`
+	// Status stage of the review of the change
+	Status ChangeStatus `json:"status" xml:"status" gorm:"type:varchar;not null;index"`
+	// RequestedBy subject of the client that requested the change, it is empty for anonymous clients
+	RequestedBy string `json:"requestedBy" xml:"requestedBy" gorm:"type:varchar;not null"`
+	// RequestedAt time of the request
+	RequestedAt time.Time `json:"requestedAt" xml:"requestedAt" gorm:"not null"`
+	// ReviewedBy subject of the client that approved or rejected the change, it is empty while the change is pending
+	ReviewedBy string `json:"reviewedBy,omitempty" xml:"reviewedBy,omitempty" gorm:"type:varchar;not null"`
+	// ReviewedAt time of the review, it is nil while the change is pending
+	ReviewedAt *time.Time `json:"reviewedAt,omitempty" xml:"reviewedAt,omitempty"`
+	// Comment explanation of the reviewer
+	Comment string `json:"comment,omitempty" xml:"comment,omitempty" gorm:"type:varchar;not null"`
+}
+
+// TableName name of the table of the PriceChange records
+func (PriceChange) TableName() string {
+	return "price_changes"
+}
+
+// PriceChangeFilter conditions of the listed price changes
+type PriceChangeFilter struct {
+	// Statuses statuses of the listed changes, if it is empty only the pending changes are listed
+	Statuses []ChangeStatus
+	// SKU identifier of the product of the listed changes, if it is empty the changes of every product are listed
+	SKU SKU
+}
+
+// PriceReview decision of a reviewer about a pending PriceChange
+type PriceReview struct {
+	// ID identifier of the reviewed change
+	ID uint `json:"-" xml:"-"`
+	// Status decision of the reviewer, it must be ChangeApproved or ChangeRejected
+	Status ChangeStatus `json:"-" xml:"-"`
+	// Comment explanation of the decision, it is required to reject a change
+	Comment string `json:"comment" xml:"comment"`
+}
@@ -0,0 +1,20 @@
+package model
+
+// Caller identity of the client that makes a request, it is obtained from the credentials sent by the client
+type Caller struct {
+	// Subject identifier of the client, for API keys it is the name of the key and for JWT it is the "sub" claim
+	Subject string
+	// Roles roles granted to the client
+	Roles []string
+}
+
+// HasRole indicates if the role was granted to the Caller
+func (c Caller) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Normalize lowers the text and replaces every sequence of characters that are not letters or digits with a single space,
+// e.g. "NIKE air-max 90" is "nike air max 90"
+func Normalize(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// ReportStatus stage of the generation of a DuplicateReport
+type ReportStatus string
+
+// Supported values of ReportStatus
+const (
+	// ReportRunning the report is being generated in background
+	ReportRunning ReportStatus = "running"
+	// ReportCompleted the report was generated, it contains the clusters found
+	ReportCompleted ReportStatus = "completed"
+	// ReportFailed the report could not be generated
+	ReportFailed ReportStatus = "failed"
+)
+
+// DuplicateReport suspected duplicate products of the catalog grouped in clusters
+type DuplicateReport struct {
+	// Status stage of the generation of the report
+	Status ReportStatus `json:"status" xml:"status"`
+	// StartedAt time when the generation started
+	StartedAt time.Time `json:"startedAt" xml:"startedAt"`
+	// CompletedAt time when the generation finished, it is nil while the report is running
+	CompletedAt *time.Time `json:"completedAt,omitempty" xml:"completedAt,omitempty"`
+	// Products number of compared products
+	Products int `json:"products" xml:"products"`
+	// Clusters groups of products that are likely the same item, they are empty while the report is running
+	Clusters []DuplicateCluster `json:"clusters" xml:"clusters>cluster"`
+}
+
+// DuplicateCluster products of the same brand whose names are similar to each other
+type DuplicateCluster struct {
+	// Brand normalized brand of the products
+	Brand string `json:"brand" xml:"brand"`
+	// Products products of the cluster sorted by SKU
+	Products []DuplicateProduct `json:"products" xml:"products>product"`
+}
+
+// DuplicateProduct product that is part of a DuplicateCluster
+type DuplicateProduct struct {
+	// SKU identifier of the product
+	SKU SKU `json:"sku" xml:"sku"`
+	// Name name of the product as it is stored
+	Name string `json:"name" xml:"name"`
+	// Brand brand of the product as it is stored
+	Brand string `json:"brand" xml:"brand"`
+}
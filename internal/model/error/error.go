@@ -2,8 +2,12 @@
 package error
 
 import (
+	"encoding/xml"
+	"fmt"
 	"github.com/jackc/pgconn"
 	"github.com/lib/pq"
+	"sort"
+	"strings"
 )
 
 // NotFound error caused by missing resource
@@ -22,6 +26,174 @@ func (v Validation) Error() string {
 	return string(v)
 }
 
+// Violation describes the field that breaks a validation rule
+type Violation struct {
+	// Field name of the field (JSON name) or parameter with the invalid value
+	Field string `json:"field" xml:"field"`
+	// Pointer JSON pointer (RFC 6901) to the invalid value into the request body (e.g. "/otherImages/2"),
+	// it is empty for the values that are not part of the request body
+	Pointer string `json:"pointer,omitempty" xml:"pointer,omitempty"`
+	// Rule identifier of the broken rule (e.g. "minLength")
+	Rule string `json:"rule,omitempty" xml:"rule,omitempty"`
+	// Params parameters of the broken rule (e.g. {"min": 3} for "minLength")
+	Params RuleParams `json:"params,omitempty" xml:"params,omitempty"`
+	// Reason why the value is invalid
+	Reason string `json:"reason" xml:"reason"`
+}
+
+// Violated builds the Violation of the value located by the JSON pointer, the field is the first reference token of the pointer
+func Violated(pointer, rule, reason string, params RuleParams) Violation {
+	field, _, _ := strings.Cut(strings.TrimPrefix(pointer, "/"), "/")
+
+	return Violation{Field: field, Pointer: pointer, Rule: rule, Params: params, Reason: reason}
+}
+
+// RuleParams parameters of a validation rule indexed by name
+type RuleParams map[string]any
+
+// MarshalXML encodes the parameters as <param name="min">3</param> elements sorted by name
+func (r RuleParams) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		param := xml.StartElement{
+			Name: xml.Name{Local: "param"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "name"}, Value: name}},
+		}
+
+		if err := e.EncodeElement(fmt.Sprint(r[name]), param); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// Violations error caused by one or more fields with invalid values, it is a kind of Validation error
+type Violations []Violation
+
+// Error returns the reasons of the Violations separated by semicolons
+func (v Violations) Error() string {
+	reasons := make([]string, 0, len(v))
+
+	for _, violation := range v {
+		reasons = append(reasons, violation.Reason)
+	}
+
+	return strings.Join(reasons, "; ")
+}
+
+// Invalid builds the Violations for a single field or parameter that is not part of the request body
+func Invalid(field, reason string) Violations {
+	return Violations{{Field: field, Reason: reason}}
+}
+
+// Unauthorized error caused by missing or invalid credentials
+type Unauthorized string
+
+// Error returns the string value of Unauthorized
+func (u Unauthorized) Error() string {
+	return string(u)
+}
+
+// Forbidden error caused by a client that is not allowed to perform an operation
+type Forbidden string
+
+// Error returns the string value of Forbidden
+func (f Forbidden) Error() string {
+	return string(f)
+}
+
+// NotAcceptable error caused by a response that can not be encoded using any media type accepted by the client
+type NotAcceptable string
+
+// Error returns the string value of NotAcceptable
+func (n NotAcceptable) Error() string {
+	return string(n)
+}
+
+// UnsupportedMediaType error caused by a request body encoded using a not supported media type
+type UnsupportedMediaType string
+
+// Error returns the string value of UnsupportedMediaType
+func (u UnsupportedMediaType) Error() string {
+	return string(u)
+}
+
+// Unprocessable error caused by a request body that could not be decoded
+type Unprocessable string
+
+// Error returns the string value of Unprocessable
+func (u Unprocessable) Error() string {
+	return string(u)
+}
+
+// TooManyRequests error caused by a client that exceeds its rate limit
+type TooManyRequests string
+
+// Error returns the string value of TooManyRequests
+func (t TooManyRequests) Error() string {
+	return string(t)
+}
+
+// Conflict error caused by a request that conflicts with the current state of a resource
+type Conflict string
+
+// Error returns the string value of Conflict
+func (c Conflict) Error() string {
+	return string(c)
+}
+
+// Candidate existing product that is likely a duplicate of a new product
+type Candidate struct {
+	// SKU identifier of the existing product
+	SKU string `json:"sku" xml:"sku"`
+	// Name name of the existing product
+	Name string `json:"name" xml:"name"`
+	// Brand brand of the existing product
+	Brand string `json:"brand" xml:"brand"`
+	// Score similarity between the names of the products, from 0 (different) to 1 (same normalized name)
+	Score float64 `json:"score" xml:"score"`
+}
+
+// Duplicates error caused by a new product that is likely the same item as existing products, it is a kind of conflict
+type Duplicates struct {
+	// Reason why the product is considered a duplicate
+	Reason string
+	// Candidates existing products sorted by descending Score
+	Candidates []Candidate
+}
+
+// Error returns the reason of Duplicates
+func (d Duplicates) Error() string {
+	return d.Reason
+}
+
+// TooLarge error caused by a request content that exceeds the size limit
+type TooLarge string
+
+// Error returns the string value of TooLarge
+func (t TooLarge) Error() string {
+	return string(t)
+}
+
+// NotImplemented error caused by an operation that is not enabled in the server
+type NotImplemented string
+
+// Error returns the string value of NotImplemented
+func (n NotImplemented) Error() string {
+	return string(n)
+}
+
 // SQL alias for pq.Error
 type SQL = pq.Error
 
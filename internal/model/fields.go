@@ -0,0 +1,81 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// productFields relates the field names of model.Product used by the clients (JSON names) to the struct fields
+var productFields = func() map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+
+	t := reflect.TypeOf(Product{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		fields[name] = field
+	}
+
+	return fields
+}()
+
+// Fields sparse fieldset, names of the model.Product fields (JSON names) requested by a client
+type Fields []string
+
+// ParseFields parses a comma-separated list of field names, every name must be a field of model.Product.
+// The duplicated names are ignored
+func ParseFields(s string) (Fields, error) {
+	fields := make(Fields, 0)
+	seen := make(map[string]bool)
+
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+
+		if name == "" {
+			return nil, fmt.Errorf("field names must not be blank")
+		}
+
+		if _, ok := productFields[name]; !ok {
+			return nil, fmt.Errorf("unknown field '%s'", name)
+		}
+
+		if seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		fields = append(fields, name)
+	}
+
+	return fields, nil
+}
+
+// StructFields returns the model.Product struct fields related to the field names
+func (f Fields) StructFields() []reflect.StructField {
+	structFields := make([]reflect.StructField, 0, len(f))
+
+	for _, name := range f {
+		if field, ok := productFields[name]; ok {
+			structFields = append(structFields, field)
+		}
+	}
+
+	return structFields
+}
+
+// Names returns the names of the model.Product struct fields related to the field names, e.g. "principalImage" is "PrincipalImage"
+func (f Fields) Names() []string {
+	names := make([]string, 0, len(f))
+
+	for _, field := range f.StructFields() {
+		names = append(names, field.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,53 @@
+package model
+
+import "io"
+
+// ImageUpload image file uploaded by a client for a product
+type ImageUpload struct {
+	// Filename name of the uploaded file, it is only used to describe the upload
+	Filename string
+	// Content bytes of the file, its real content type is detected from the content
+	Content io.Reader
+	// Principal indicates if the image is set as the principal image, otherwise it is appended to the other images
+	Principal bool
+}
+
+// Images principal image and other images of a product
+type Images struct {
+	// PrincipalImage URL of the principal image of the product
+	PrincipalImage *URL
+	// OtherImages list of images of the product
+	OtherImages URLs
+}
+
+// ImagesOf returns the Images of the product
+func ImagesOf(product Product) Images {
+	return Images{PrincipalImage: product.PrincipalImage, OtherImages: product.OtherImages}
+}
+
+// Equal indicates if both Images contain the same URLs in the same order
+func (i Images) Equal(other Images) bool {
+	var principal, otherPrincipal string
+
+	if i.PrincipalImage != nil {
+		principal = i.PrincipalImage.String()
+	}
+
+	if other.PrincipalImage != nil {
+		otherPrincipal = other.PrincipalImage.String()
+	}
+
+	return principal == otherPrincipal &&
+		len(i.OtherImages) == len(other.OtherImages) &&
+		i.OtherImages.String() == other.OtherImages.String()
+}
+
+// ImageChange replacement of the images of a product, the images are replaced only if they are still From
+type ImageChange struct {
+	// SKU identifier of the product
+	SKU SKU
+	// From images of the product before the change
+	From Images
+	// To images of the product after the change
+	To Images
+}
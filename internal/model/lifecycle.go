@@ -0,0 +1,160 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Status stage of the lifecycle of a Product
+type Status string
+
+// Supported values of Status, they are listed in lifecycle order
+const (
+	// StatusDraft the product is being prepared, every product is created as draft
+	StatusDraft Status = "draft"
+	// StatusInReview the product is waiting to be reviewed before its publication
+	StatusInReview Status = "in_review"
+	// StatusPublished the product is shown in the public listings
+	StatusPublished Status = "published"
+	// StatusDiscontinued the product is no longer sold
+	StatusDiscontinued Status = "discontinued"
+	// StatusArchived the product is kept only for historical purposes, it is the last stage of the lifecycle
+	StatusArchived Status = "archived"
+)
+
+// Statuses every supported Status in lifecycle order
+var Statuses = []Status{StatusDraft, StatusInReview, StatusPublished, StatusDiscontinued, StatusArchived}
+
+// IsValid indicates if the Status is one of the supported Statuses
+func (s Status) IsValid() bool {
+	return slices.Contains(Statuses, s)
+}
+
+// ParseStatuses parses a comma-separated list of statuses, "all" means every supported Status.
+// The duplicated statuses are ignored
+func ParseStatuses(s string) ([]Status, error) {
+	return parseEnum(s, Statuses, "status")
+}
+
+// parseEnum parses a comma-separated list of the values, "all" means every value. The duplicated values are ignored
+func parseEnum[T ~string](s string, values []T, name string) ([]T, error) {
+	if strings.TrimSpace(s) == "all" {
+		return values, nil
+	}
+
+	parsed := make([]T, 0)
+	seen := make(map[T]bool)
+
+	for _, item := range strings.Split(s, ",") {
+		value := T(strings.TrimSpace(item))
+
+		if !slices.Contains(values, value) {
+			return nil, fmt.Errorf("unknown %s '%s'", name, value)
+		}
+
+		if seen[value] {
+			continue
+		}
+
+		seen[value] = true
+		parsed = append(parsed, value)
+	}
+
+	return parsed, nil
+}
+
+// ProductFilter conditions of the products returned by the listings and the page of the listed products
+type ProductFilter struct {
+	// Statuses statuses of the listed products, if it is empty only the published products are listed
+	Statuses []Status
+	// Brand brand of the listed products (case insensitive), if it is empty every brand is listed
+	Brand string
+	// NameContains text contained in the name of the listed products (case insensitive)
+	NameContains string
+	// Size size of the listed products, if it is empty every size is listed
+	Size string
+	// MinPrice minimum price of the listed products, if it is nil there is no minimum
+	MinPrice *float64
+	// MaxPrice maximum price of the listed products, if it is nil there is no maximum
+	MaxPrice *float64
+	// Offset number of products skipped before the page
+	Offset int
+	// Limit maximum number of products of the page, if it is zero every product after the offset is listed
+	Limit int
+}
+
+// Matches indicates if the product meets every condition of the ProductFilter, the empty statuses are not checked
+func (f ProductFilter) Matches(product Product) bool {
+	switch {
+	case len(f.Statuses) > 0 && !slices.Contains(f.Statuses, product.Status):
+		return false
+	case f.Brand != "" && !strings.EqualFold(product.Brand, f.Brand):
+		return false
+	case f.NameContains != "" && !strings.Contains(strings.ToLower(product.Name), strings.ToLower(f.NameContains)):
+		return false
+	case f.Size != "" && (product.Size == nil || *product.Size != f.Size):
+		return false
+	case f.MinPrice != nil && product.Price < *f.MinPrice:
+		return false
+	case f.MaxPrice != nil && product.Price > *f.MaxPrice:
+		return false
+	}
+
+	return true
+}
+
+// Page returns the page of the products that match the ProductFilter, the products must be ordered by SKU
+func (f ProductFilter) Page(products Products) ProductPage {
+	matches := make(Products, 0, len(products))
+
+	for _, product := range products {
+		if f.Matches(product) {
+			matches = append(matches, product)
+		}
+	}
+
+	start := min(max(f.Offset, 0), len(matches))
+	end := len(matches)
+
+	if f.Limit > 0 {
+		end = min(start+f.Limit, end)
+	}
+
+	return ProductPage{Items: matches[start:end], TotalCount: len(matches)}
+}
+
+// ProductPage page of the products that match a ProductFilter
+type ProductPage struct {
+	// Items products of the page ordered by SKU
+	Items Products
+	// TotalCount number of products that match the filter, including the products out of the page
+	TotalCount int
+}
+
+// HasNextPage indicates if there are products that match the filter after the page
+func (p ProductPage) HasNextPage(filter ProductFilter) bool {
+	return max(filter.Offset, 0)+len(p.Items) < p.TotalCount
+}
+
+// StatusTransition change of the Status of a Product, it records who made the change and when
+type StatusTransition struct {
+	// ID identifier of the transition
+	ID uint `json:"-" xml:"-" gorm:"primaryKey"`
+	// SKU identifier of the product
+	SKU SKU `json:"sku" xml:"sku" gorm:"type:varchar;not null;index"`
+	// From status of the product before the transition
+	From Status `json:"from" xml:"from" gorm:"type:varchar;not null"`
+	// To status of the product after the transition
+	To Status `json:"to" xml:"to" gorm:"type:varchar;not null"`
+	// ChangedBy subject of the client that made the transition, it is empty for anonymous clients
+	ChangedBy string `json:"changedBy" xml:"changedBy" gorm:"type:varchar;not null"`
+	// ChangedAt time of the transition
+	ChangedAt time.Time `json:"changedAt" xml:"changedAt" gorm:"not null"`
+}
+
+// TableName name of the table of the StatusTransition records
+func (StatusTransition) TableName() string {
+	return "product_transitions"
+}
@@ -1,29 +1,26 @@
 package model
 
-import (
-	"errors"
-	"fmt"
-	"strconv"
-	"strings"
-)
-
 type (
 	Product struct {
 		// SKU internal stock-keeping unit. It is the candidate identifier of a product
-		SKU SKU `json:"sku" gorm:"type:varchar;primaryKey"`
+		SKU SKU `json:"sku" xml:"sku" gorm:"type:varchar;primaryKey"`
 		// Name short description of the product
-		Name string `json:"name" gorm:"type:varchar;not null"`
+		Name string `json:"name" xml:"name" gorm:"type:varchar;not null"`
 		// Brand name of the brand
-		Brand string `json:"brand" gorm:"type:varchar;not null"`
+		Brand string `json:"brand" xml:"brand" gorm:"type:varchar;not null"`
 		// Size product size
-		Size *string `json:"size" gorm:"type:varchar"`
+		Size *string `json:"size" xml:"size,omitempty" gorm:"type:varchar"`
 		// Price sell price
-		Price float64 `json:"price" gorm:"type:decimal;not null"`
+		Price float64 `json:"price" xml:"price" gorm:"type:decimal;not null"`
 		// PrincipalImage URL of the principal image of the product, which is used in catalogs
 		// and is the first image that is showed to customers when access product detail page
-		PrincipalImage *URL `json:"principalImage" gorm:"varchar;not null"`
+		PrincipalImage *URL `json:"principalImage" xml:"principalImage,omitempty" gorm:"varchar;not null"`
 		// OtherImages list of images of the product
-		OtherImages URLs `json:"otherImages" gorm:"[]varchar;not null"`
+		OtherImages URLs `json:"otherImages" xml:"otherImages>image" gorm:"[]varchar;not null"`
+		// Status stage of the lifecycle of the product, it is only changed by the status transitions
+		Status Status `json:"status" xml:"status" gorm:"type:varchar;not null;default:draft"`
+		// BrandKey Brand normalized by Normalize, it is set by the storage on write to find the products of a brand by equality
+		BrandKey string `json:"-" xml:"-" gorm:"type:varchar;not null;default:'';index"`
 	}
 
 	// Products alias for []Product
@@ -33,28 +30,15 @@ type (
 // SKU stock-keeping unit
 type SKU string
 
-// IsValid check if the SKU are valid, if it does not valid returns an error
+// IsValid check if the SKU matches the DefaultSKUScheme, if it does not valid returns an error
 func (s SKU) IsValid() error {
-	if s == "" {
-		return errors.New("SKU can not be blank")
-	}
-
-	if s == "" || !strings.HasPrefix(string(s), "FAL-") {
-		return errors.New("missing prefix 'FAL-'")
-	}
-
-	suffix, err := strconv.ParseInt(string(s[4:]), 10, 64)
-	if err != nil {
-		return errors.New("suffix is not a number")
-	}
-
-	if suffix < 1_000_000 {
-		return fmt.Errorf("invalid suffix '%d'", suffix)
-	}
-
-	if suffix > 99_999_999 {
-		return fmt.Errorf("invalid suffix '%d'", suffix)
-	}
+	return DefaultSKUScheme.Validate(s)
+}
 
-	return nil
+// ProductBatch result of obtaining several products at once
+type ProductBatch struct {
+	// Products found products in the same order they were requested
+	Products Products `json:"products" xml:"products>product"`
+	// Missing requested SKUs that do not exist
+	Missing []SKU `json:"missing" xml:"missing>sku"`
 }
@@ -0,0 +1,384 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CheckDigit algorithm used to compute the check digit appended to the SKUs
+type CheckDigit string
+
+// Supported check digit algorithms
+const (
+	// CheckDigitNone the SKUs do not have check digit
+	CheckDigitNone CheckDigit = ""
+	// CheckDigitLuhn Luhn algorithm (mod 10)
+	CheckDigitLuhn CheckDigit = "luhn"
+	// CheckDigitMod11 mod 11 algorithm with weights 2 to 7 from right to left, the remainder 10 is written as 'X'
+	CheckDigitMod11 CheckDigit = "mod11"
+)
+
+// compute returns the check digit of the digits
+func (c CheckDigit) compute(digits string) byte {
+	switch c {
+	case CheckDigitLuhn:
+		sum := 0
+
+		for i := 0; i < len(digits); i++ {
+			d := int(digits[len(digits)-1-i] - '0')
+
+			if i%2 == 0 {
+				if d *= 2; d > 9 {
+					d -= 9
+				}
+			}
+
+			sum += d
+		}
+
+		return byte('0' + (10-sum%10)%10)
+	case CheckDigitMod11:
+		sum := 0
+
+		for i := 0; i < len(digits); i++ {
+			sum += int(digits[len(digits)-1-i]-'0') * (2 + i%6)
+		}
+
+		switch check := (11 - sum%11) % 11; check {
+		case 10:
+			return 'X'
+		default:
+			return byte('0' + check)
+		}
+	}
+
+	return 0
+}
+
+// maxSKUDigits maximum number of digits of the SKU numbers, it keeps the numbers into int64
+const maxSKUDigits = 18
+
+// skuSchemeName pattern of the SKUScheme names, they are used to name the storage sequences
+var skuSchemeName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// SKUScheme format of the SKUs: a prefix followed by a number and an optional check digit (e.g. "ACM-000123-7")
+type SKUScheme struct {
+	// Name identifier of the scheme, lowercase letters, digits and underscores
+	Name string `yaml:"name" json:"name"`
+	// Prefix fixed text at the beginning of every SKU (e.g. "FAL-")
+	Prefix string `yaml:"prefix" json:"prefix"`
+	// Digits number of digits of the SKU numbers, the numbers are padded with zeros.
+	// If it is zero the numbers have variable length and they are not padded
+	Digits int `yaml:"digits" json:"digits"`
+	// Min first number of the scheme, default value 1
+	Min int64 `yaml:"min" json:"min"`
+	// Max last number of the scheme, default value the largest number of Digits digits
+	Max int64 `yaml:"max" json:"max"`
+	// CheckDigit algorithm of the check digit appended to the number, by default the SKUs have no check digit
+	CheckDigit CheckDigit `yaml:"checkDigit" json:"checkDigit"`
+	// Brands the scheme is used by the products of these brands (case-insensitive)
+	Brands []string `yaml:"brands" json:"brands"`
+	// Tenants the scheme is used by the products created by these clients (subject of the credentials)
+	Tenants []string `yaml:"tenants" json:"tenants"`
+}
+
+// DefaultSKUScheme format used when no SKUScheme is configured, "FAL-" followed by a number between 1,000,000 and 99,999,999
+var DefaultSKUScheme = SKUScheme{
+	Name:   "fal",
+	Prefix: "FAL-",
+	Min:    1_000_000,
+	Max:    99_999_999,
+}
+
+// Bounds returns the first and the last number of the SKUScheme
+func (s SKUScheme) Bounds() (min, max int64) {
+	min, max = s.Min, s.Max
+
+	if min <= 0 {
+		min = 1
+	}
+
+	if max <= 0 {
+		digits := s.Digits
+		if digits <= 0 {
+			digits = maxSKUDigits
+		}
+
+		max, _ = strconv.ParseInt(strings.Repeat("9", digits), 10, 64)
+	}
+
+	return
+}
+
+// Check validates the definition of the SKUScheme
+func (s SKUScheme) Check() error {
+	if !skuSchemeName.MatchString(s.Name) {
+		return fmt.Errorf(`invalid sku scheme name '%s'`, s.Name)
+	}
+
+	if s.Prefix == "" {
+		return fmt.Errorf(`sku scheme '%s' requires a prefix`, s.Name)
+	}
+
+	if s.Digits < 0 || s.Digits > maxSKUDigits {
+		return fmt.Errorf(`sku scheme '%s' requires between 0 and %d digits`, s.Name, maxSKUDigits)
+	}
+
+	switch s.CheckDigit {
+	case CheckDigitNone, CheckDigitLuhn, CheckDigitMod11:
+	default:
+		return fmt.Errorf(`sku scheme '%s' has an unsupported check digit '%s'`, s.Name, s.CheckDigit)
+	}
+
+	min, max := s.Bounds()
+	if min > max {
+		return fmt.Errorf(`sku scheme '%s' requires min lower than max`, s.Name)
+	}
+
+	if s.Digits > 0 && len(strconv.FormatInt(max, 10)) > s.Digits {
+		return fmt.Errorf(`sku scheme '%s' has a max with more than %d digits`, s.Name, s.Digits)
+	}
+
+	return nil
+}
+
+// Capacity number of SKUs of the SKUScheme
+func (s SKUScheme) Capacity() int64 {
+	min, max := s.Bounds()
+	return max - min + 1
+}
+
+// Format builds the SKU of the nth number of the SKUScheme (the first one is 1), if n is out of the scheme returns an error
+func (s SKUScheme) Format(n int64) (SKU, error) {
+	min, max := s.Bounds()
+
+	if n < 1 || n > max-min+1 {
+		return "", fmt.Errorf(`sku scheme '%s' is exhausted`, s.Name)
+	}
+
+	digits := strconv.FormatInt(min+n-1, 10)
+	if len(digits) < s.Digits {
+		digits = strings.Repeat("0", s.Digits-len(digits)) + digits
+	}
+
+	if check := s.CheckDigit.compute(digits); check != 0 {
+		digits += string(check)
+	}
+
+	return SKU(s.Prefix + digits), nil
+}
+
+// Number returns the position of the SKU into the SKUScheme (the first one is 1), it is the inverse of Format.
+// If the SKU does not match the SKUScheme returns an error
+func (s SKUScheme) Number(sku SKU) (int64, error) {
+	if err := s.Validate(sku); err != nil {
+		return 0, err
+	}
+
+	digits := string(sku[len(s.Prefix):])
+	if s.CheckDigit != CheckDigitNone {
+		digits = digits[:len(digits)-1]
+	}
+
+	number, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	min, _ := s.Bounds()
+	return number - min + 1, nil
+}
+
+// Validate checks that the SKU matches the SKUScheme, if it does not match returns an error
+func (s SKUScheme) Validate(sku SKU) error {
+	if sku == "" {
+		return errors.New("SKU can not be blank")
+	}
+
+	if !strings.HasPrefix(string(sku), s.Prefix) {
+		return fmt.Errorf("missing prefix '%s'", s.Prefix)
+	}
+
+	suffix := string(sku[len(s.Prefix):])
+
+	if s.CheckDigit != CheckDigitNone {
+		if len(suffix) < 2 {
+			return fmt.Errorf("invalid suffix '%s'", suffix)
+		}
+
+		var check byte
+		suffix, check = suffix[:len(suffix)-1], suffix[len(suffix)-1]
+
+		if !isDigits(suffix) {
+			return errors.New("suffix is not a number")
+		}
+
+		if s.CheckDigit.compute(suffix) != check {
+			return fmt.Errorf("invalid check digit '%c'", check)
+		}
+	}
+
+	if !isDigits(suffix) {
+		return errors.New("suffix is not a number")
+	}
+
+	if s.Digits > 0 && len(suffix) != s.Digits {
+		return fmt.Errorf("invalid suffix '%s'", suffix)
+	}
+
+	number, err := strconv.ParseInt(suffix, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid suffix '%s'", suffix)
+	}
+
+	if min, max := s.Bounds(); number < min || number > max {
+		return fmt.Errorf("invalid suffix '%d'", number)
+	}
+
+	return nil
+}
+
+// uses indicates if the SKUScheme is used by the brand or by the tenant
+func (s SKUScheme) uses(brand, tenant string) bool {
+	for _, b := range s.Brands {
+		if strings.EqualFold(b, brand) {
+			return true
+		}
+	}
+
+	for _, t := range s.Tenants {
+		if t == tenant && tenant != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isDigits indicates if s is not empty and only contains ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SKUSchemes formats of the SKUs defined per brand or per tenant, it is loaded from YAML or JSON files (see skus.example.yaml)
+type SKUSchemes struct {
+	// Default name of the scheme used by the products whose brand and tenant do not match any scheme,
+	// if it is blank the first scheme is used
+	Default string `yaml:"default" json:"default"`
+	// Schemes supported formats
+	Schemes []SKUScheme `yaml:"schemes" json:"schemes"`
+}
+
+// DefaultSKUSchemes schemes used when none is configured, it only contains the DefaultSKUScheme
+var DefaultSKUSchemes = SKUSchemes{Default: DefaultSKUScheme.Name, Schemes: []SKUScheme{DefaultSKUScheme}}
+
+// Check validates the definition of every SKUScheme, the names must be unique
+func (s SKUSchemes) Check() error {
+	if len(s.Schemes) == 0 {
+		return errors.New("at least one sku scheme is required")
+	}
+
+	names := make(map[string]bool, len(s.Schemes))
+
+	for _, scheme := range s.Schemes {
+		if err := scheme.Check(); err != nil {
+			return err
+		}
+
+		if names[scheme.Name] {
+			return fmt.Errorf(`sku scheme '%s' is duplicated`, scheme.Name)
+		}
+
+		names[scheme.Name] = true
+	}
+
+	if s.Default != "" && !names[s.Default] {
+		return fmt.Errorf(`default sku scheme '%s' does not exist`, s.Default)
+	}
+
+	return nil
+}
+
+// Find returns the SKUScheme identified by the name
+func (s SKUSchemes) Find(name string) (SKUScheme, bool) {
+	for _, scheme := range s.Schemes {
+		if scheme.Name == name {
+			return scheme, true
+		}
+	}
+
+	return SKUScheme{}, false
+}
+
+// Resolve returns the SKUScheme used by the brand, otherwise the one used by the tenant, otherwise the default one
+func (s SKUSchemes) Resolve(brand, tenant string) SKUScheme {
+	for _, scheme := range s.Schemes {
+		if scheme.uses(brand, "") {
+			return scheme
+		}
+	}
+
+	for _, scheme := range s.Schemes {
+		if scheme.uses("", tenant) {
+			return scheme
+		}
+	}
+
+	if scheme, ok := s.Find(s.Default); ok {
+		return scheme
+	}
+
+	return s.Schemes[0]
+}
+
+// Match returns the SKUScheme matched by the SKU. If the SKU does not match any scheme, the scheme with the longest
+// matching prefix is returned along with the error of its validation, if none prefix matches the default scheme is returned
+func (s SKUSchemes) Match(sku SKU) (SKUScheme, error) {
+	closest, length := s.Resolve("", ""), -1
+	if strings.HasPrefix(string(sku), closest.Prefix) {
+		length = len(closest.Prefix)
+	}
+
+	for _, scheme := range s.Schemes {
+		if scheme.Validate(sku) == nil {
+			return scheme, nil
+		}
+
+		if strings.HasPrefix(string(sku), scheme.Prefix) && len(scheme.Prefix) > length {
+			closest, length = scheme, len(scheme.Prefix)
+		}
+	}
+
+	return closest, closest.Validate(sku)
+}
+
+// SKUReservation request to reserve a block of SKUs for offline use
+type SKUReservation struct {
+	// Scheme name of the SKUScheme, if it is blank the scheme is resolved by the brand and the tenant
+	Scheme string `json:"scheme" xml:"scheme"`
+	// Brand brand of the products that will use the SKUs
+	Brand string `json:"brand" xml:"brand"`
+	// Count number of SKUs
+	Count int `json:"count" xml:"count"`
+}
+
+// SKUBlock SKUs reserved for offline use, they are never allocated again
+type SKUBlock struct {
+	// Scheme name of the SKUScheme of the SKUs
+	Scheme string `json:"scheme" xml:"scheme"`
+	// SKUs reserved SKUs
+	SKUs []SKU `json:"skus" xml:"skus>sku"`
+}
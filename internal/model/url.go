@@ -3,6 +3,7 @@ package model
 import (
 	"database/sql"
 	"database/sql/driver"
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -15,6 +16,12 @@ var _ fmt.Stringer = URL{}
 var _ json.Unmarshaler = (*URL)(nil)
 var _ json.Marshaler = (*URL)(nil)
 
+var _ encoding.TextUnmarshaler = (*URL)(nil)
+var _ encoding.TextMarshaler = URL{}
+
+var _ encoding.BinaryUnmarshaler = (*URL)(nil)
+var _ encoding.BinaryMarshaler = URL{}
+
 var _ sql.Scanner = (*URL)(nil)
 var _ driver.Valuer = (*URL)(nil)
 
@@ -57,6 +64,31 @@ func (u *URL) UnmarshalJSON(bytes []byte) (err error) {
 	return
 }
 
+// MarshalText returns the string value for URL, it is used by text based encodings like XML
+func (u URL) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText decodes the raw text value into *url.URL
+func (u *URL) UnmarshalText(text []byte) (err error) {
+	u.URL, err = url.Parse(string(text))
+	return
+}
+
+// MarshalBinary returns the string value for URL, it is used by binary encodings like MessagePack
+//
+// It shadows the method promoted from *url.URL, which panics when the URL is nil
+func (u URL) MarshalBinary() ([]byte, error) {
+	return u.MarshalText()
+}
+
+// UnmarshalBinary decodes the raw value into *url.URL
+//
+// It shadows the method promoted from *url.URL, which panics when the URL is nil
+func (u *URL) UnmarshalBinary(data []byte) error {
+	return u.UnmarshalText(data)
+}
+
 // String returns the raw string value for URL
 func (u URL) String() string {
 	if u.URL == nil {
@@ -66,11 +98,27 @@ func (u URL) String() string {
 }
 
 // "implement" constraints for URLs
+var _ fmt.Stringer = URLs{}
+
 var _ sql.Scanner = (*URLs)(nil)
 var _ driver.Valuer = (URLs)(nil)
 
+// URLs list of URL
 type URLs []URL
 
+// String returns the URLs separated by a blank space, it is used by flat encodings like CSV
+//
+// A blank space is used as separator because it is never part of a valid URL
+func (u URLs) String() string {
+	urls := make([]string, len(u))
+
+	for i, v := range u {
+		urls[i] = v.String()
+	}
+
+	return strings.Join(urls, " ")
+}
+
 func (u URLs) Value() (driver.Value, error) {
 	return json.Marshal(u)
 }
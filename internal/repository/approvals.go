@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"gorm.io/gorm"
+	"slices"
+	"sync"
+)
+
+// ApprovalManager defines the methods to request, review and obtain the changes of the product prices held until they are approved
+type ApprovalManager interface {
+	// RequestChange updates the model.Product, whose price must be kept, and records the pending model.PriceChange
+	// in a single operation. The ID of the change is set
+	RequestChange(ctx context.Context, product model.Product, change *model.PriceChange) error
+	// PriceChange returns the model.PriceChange identified by the ID, if it does not exist returns error2.NotFound
+	PriceChange(ctx context.Context, id uint) (model.PriceChange, error)
+	// PriceChanges returns the model.PriceChange records that match the model.PriceChangeFilter ordered by request time
+	PriceChanges(ctx context.Context, filter model.PriceChangeFilter) ([]model.PriceChange, error)
+	// ReviewChange records the review of a pending model.PriceChange, if it is approved the price of the product is changed
+	// in the same operation. If the change is no longer pending or the price of the product is no longer the CurrentPrice
+	// returns error2.Conflict
+	ReviewChange(ctx context.Context, change model.PriceChange) error
+}
+
+// "implement" constraint for Approvals
+var _ ApprovalManager = Approvals{}
+
+// Approvals stores the changes of the product prices into the database
+type Approvals struct {
+	*gorm.DB
+}
+
+// RequestChange updates the product and inserts the change in the same database transaction
+func (a Approvals) RequestChange(ctx context.Context, product model.Product, change *model.PriceChange) error {
+	setBrandKey(&product)
+
+	return a.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("sku = ?", product.SKU).Updates(product).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(change).Error
+	})
+}
+
+// PriceChange selects the change identified by the ID
+func (a Approvals) PriceChange(ctx context.Context, id uint) (model.PriceChange, error) {
+	changes := make([]model.PriceChange, 0, 1)
+
+	err := a.DB.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&changes).Error
+	if err != nil {
+		return model.PriceChange{}, err
+	}
+
+	if len(changes) < 1 {
+		return model.PriceChange{}, error2.NotFound(fmt.Sprintf(`price change identified by id '%d' does not exist`, id))
+	}
+
+	return changes[0], nil
+}
+
+// PriceChanges selects the changes that match the filter ordered by request time
+func (a Approvals) PriceChanges(ctx context.Context, filter model.PriceChangeFilter) ([]model.PriceChange, error) {
+	changes := make([]model.PriceChange, 0)
+
+	db := a.DB.WithContext(ctx).Where("status IN ?", filter.Statuses)
+	if filter.SKU != "" {
+		db = db.Where("sku = ?", filter.SKU)
+	}
+
+	if err := db.Order("requested_at, id").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// ReviewChange updates the change only if it is still pending and, if it is approved, updates the price of the product
+// only if it is still the CurrentPrice, both in the same database transaction
+func (a Approvals) ReviewChange(ctx context.Context, change model.PriceChange) error {
+	return a.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		db := tx.Model(&model.PriceChange{}).
+			Where("id = ? AND status = ?", change.ID, model.ChangePending).
+			Updates(map[string]any{
+				"status":      change.Status,
+				"reviewed_by": change.ReviewedBy,
+				"reviewed_at": change.ReviewedAt,
+				"comment":     change.Comment,
+			})
+
+		if db.Error != nil {
+			return db.Error
+		}
+
+		if db.RowsAffected < 1 {
+			return error2.Conflict(fmt.Sprintf(`the price change '%d' is no longer pending`, change.ID))
+		}
+
+		if change.Status != model.ChangeApproved {
+			return nil
+		}
+
+		db = tx.Model(&model.Product{}).
+			Where("sku = ? AND price = ?", change.SKU, change.CurrentPrice).
+			Update("price", change.Price)
+
+		if db.Error != nil {
+			return db.Error
+		}
+
+		if db.RowsAffected < 1 {
+			return error2.Conflict(fmt.Sprintf(`the price of the product '%s' is no longer %v`, change.SKU, change.CurrentPrice))
+		}
+
+		return nil
+	})
+}
+
+// "implement" constraint for *MockApprovals
+var _ ApprovalManager = (*MockApprovals)(nil)
+
+// MockApprovals simulates the ApprovalManager in memory, the prices of the products are changed into the Storage
+type MockApprovals struct {
+	Storage *MockStorage[model.SKU, model.Product]
+
+	mutex   sync.Mutex
+	changes []model.PriceChange
+}
+
+// RequestChange updates the product saved into the Storage and records the change in memory
+func (m *MockApprovals) RequestChange(ctx context.Context, product model.Product, change *model.PriceChange) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.Storage.Update(ctx, product.SKU, product); err != nil {
+		return err
+	}
+
+	change.ID = uint(len(m.changes) + 1)
+	m.changes = append(m.changes, *change)
+	return nil
+}
+
+// PriceChange returns the change recorded in memory
+func (m *MockApprovals) PriceChange(_ context.Context, id uint) (model.PriceChange, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if id < 1 || int(id) > len(m.changes) {
+		return model.PriceChange{}, error2.NotFound(fmt.Sprintf(`price change identified by id '%d' does not exist`, id))
+	}
+
+	return m.changes[id-1], nil
+}
+
+// PriceChanges returns the changes recorded in memory that match the filter
+func (m *MockApprovals) PriceChanges(_ context.Context, filter model.PriceChangeFilter) ([]model.PriceChange, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	changes := make([]model.PriceChange, 0)
+
+	for _, change := range m.changes {
+		if slices.Contains(filter.Statuses, change.Status) && (filter.SKU == "" || filter.SKU == change.SKU) {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+// ReviewChange updates the change recorded in memory and, if it is approved, the price of the product saved into the Storage
+func (m *MockApprovals) ReviewChange(ctx context.Context, change model.PriceChange) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if change.ID < 1 || int(change.ID) > len(m.changes) {
+		return error2.NotFound(fmt.Sprintf(`price change identified by id '%d' does not exist`, change.ID))
+	}
+
+	if m.changes[change.ID-1].Status != model.ChangePending {
+		return error2.Conflict(fmt.Sprintf(`the price change '%d' is no longer pending`, change.ID))
+	}
+
+	if change.Status == model.ChangeApproved {
+		product, err := m.Storage.Obtain(ctx, change.SKU)
+		if err != nil {
+			return err
+		}
+
+		if product.Price != change.CurrentPrice {
+			return error2.Conflict(fmt.Sprintf(`the price of the product '%s' is no longer %v`, change.SKU, change.CurrentPrice))
+		}
+
+		product.Price = change.Price
+		(*m.Storage)[change.SKU] = product
+	}
+
+	m.changes[change.ID-1] = change
+	return nil
+}
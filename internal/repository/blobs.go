@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Blob content stored by a BlobStore, the Content must be closed
+type Blob struct {
+	// Content bytes of the blob
+	Content io.ReadSeekCloser
+	// ContentType media type of the content, it is inferred from the extension of the key
+	ContentType string
+	// Size number of bytes of the content
+	Size int64
+	// ModTime last time the blob was stored
+	ModTime time.Time
+}
+
+// BlobStore defines the methods to save and obtain binary objects identified by keys like "a/b/c.png"
+type BlobStore interface {
+	// Put saves the content identified by the key, if the key already exists the content is replaced
+	Put(ctx context.Context, key string, content io.Reader) error
+	// Obtain returns the Blob identified by the key, if it does not exist returns error2.NotFound
+	Obtain(ctx context.Context, key string) (Blob, error)
+}
+
+// checkKey validates the key, the keys are slash separated relative paths without "." and ".." elements
+func checkKey(key string) error {
+	if key == "" || path.Clean(key) != key || !filepath.IsLocal(filepath.FromSlash(key)) {
+		return error2.Validation(fmt.Sprintf(`invalid blob key '%s'`, key))
+	}
+
+	return nil
+}
+
+// "implement" constraint for FileBlobs
+var _ BlobStore = FileBlobs{}
+
+// FileBlobs stores the blobs as files into the Root directory, the key is the relative path of the file
+type FileBlobs struct {
+	Root string
+}
+
+// Put writes the content into a temporary file and renames it, so the readers never obtain incomplete blobs
+func (f FileBlobs) Put(_ context.Context, key string, content io.Reader) (err error) {
+	if err = checkKey(key); err != nil {
+		return err
+	}
+
+	name := filepath.Join(f.Root, filepath.FromSlash(key))
+
+	if err = os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(name), ".blob-*")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	if _, err = io.Copy(file, content); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), name)
+}
+
+// Obtain opens the file of the key
+func (f FileBlobs) Obtain(_ context.Context, key string) (Blob, error) {
+	if err := checkKey(key); err != nil {
+		return Blob{}, err
+	}
+
+	file, err := os.Open(filepath.Join(f.Root, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return Blob{}, error2.NotFound(fmt.Sprintf(`blob identified by key '%s' does not exist`, key))
+	}
+
+	if err != nil {
+		return Blob{}, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return Blob{}, err
+	}
+
+	if info.IsDir() {
+		_ = file.Close()
+		return Blob{}, error2.NotFound(fmt.Sprintf(`blob identified by key '%s' does not exist`, key))
+	}
+
+	return Blob{
+		Content:     file,
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+// "implement" constraint for *MockBlobs
+var _ BlobStore = (*MockBlobs)(nil)
+
+// MockBlobs simulates the BlobStore in memory
+type MockBlobs struct {
+	mutex sync.Mutex
+	blobs map[string][]byte
+}
+
+// Put saves the content in memory
+func (m *MockBlobs) Put(_ context.Context, key string, content io.Reader) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.blobs == nil {
+		m.blobs = make(map[string][]byte)
+	}
+
+	m.blobs[key] = data
+	return nil
+}
+
+// Obtain returns the content saved in memory
+func (m *MockBlobs) Obtain(_ context.Context, key string) (Blob, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	data, ok := m.blobs[key]
+	if !ok {
+		return Blob{}, error2.NotFound(fmt.Sprintf(`blob identified by key '%s' does not exist`, key))
+	}
+
+	return Blob{
+		Content:     nopCloser{bytes.NewReader(data)},
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		Size:        int64(len(data)),
+	}, nil
+}
+
+// Keys returns the keys of the saved blobs
+func (m *MockBlobs) Keys() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	keys := make([]string, 0, len(m.blobs))
+	for key := range m.blobs {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// nopCloser io.ReadSeeker with a Close method that does nothing
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+// Close does nothing
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFileBlobs(t *testing.T) {
+	tdt := []struct {
+		key                 string
+		content             string
+		expectedContentType string
+		expectedErr         error
+	}{
+		{
+			key:                 "abc/original.png",
+			content:             "png",
+			expectedContentType: "image/png",
+		},
+		{
+			key:                 "abc/160w.jpg",
+			content:             "jpeg",
+			expectedContentType: "image/jpeg",
+		},
+		{
+			key:         "../original.png",
+			expectedErr: error2.Validation("invalid blob key '../original.png'"),
+		},
+		{
+			key:         "/etc/passwd",
+			expectedErr: error2.Validation("invalid blob key '/etc/passwd'"),
+		},
+		{
+			key:         "abc/./original.png",
+			expectedErr: error2.Validation("invalid blob key 'abc/./original.png'"),
+		},
+	}
+
+	blobs := FileBlobs{Root: t.TempDir()}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			err := blobs.Put(context.Background(), v.key, strings.NewReader(v.content))
+			if !errors.Is(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			if err != nil {
+				t.Skip(err)
+			}
+
+			blob, err := blobs.Obtain(context.Background(), v.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer blob.Content.Close()
+
+			content, err := io.ReadAll(blob.Content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(content) != v.content || blob.ContentType != v.expectedContentType || blob.Size != int64(len(v.content)) {
+				t.Fatalf("expected blob '%s' (%s) unexpected blob '%s' (%s, %d bytes)", v.content, v.expectedContentType, content, blob.ContentType, blob.Size)
+			}
+		})
+	}
+
+	expectedErr := error2.NotFound("blob identified by key 'abc' does not exist")
+
+	if _, err := blobs.Obtain(context.Background(), "abc"); !errors.Is(err, expectedErr) {
+		t.Fatalf("expected error '%v' unexpected error '%v'", expectedErr, err)
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"gorm.io/gorm"
 )
 
-// NewGormDB returns the instance for *gorm.DB
-func NewGormDB(dsn string) (*gorm.DB, error) {
-	return gorm.Open(postgres.Open(dsn))
+// NewGormDB returns the instance for *gorm.DB, the options are used to configure the *gorm.DB (e.g. to set the logger)
+func NewGormDB(dsn string, opts ...gorm.Option) (*gorm.DB, error) {
+	return gorm.Open(postgres.Open(dsn), opts...)
 }
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"gorm.io/gorm"
+	"sync"
+)
+
+// ImageManager defines the methods to change the images of the products without changing the rest of their fields
+type ImageManager interface {
+	// ReplaceImages changes the images of the product from the model.ImageChange From to To in a single operation.
+	// If the images of the product are no longer From returns error2.Conflict
+	ReplaceImages(ctx context.Context, change model.ImageChange) error
+}
+
+// "implement" constraint for Images
+var _ ImageManager = Images{}
+
+// Images stores the images of the products into the database
+type Images struct {
+	*gorm.DB
+}
+
+// ReplaceImages updates the image columns of the product only if they still hold the From images
+func (i Images) ReplaceImages(ctx context.Context, change model.ImageChange) error {
+	db := i.DB.WithContext(ctx).Model(&model.Product{}).
+		Where(
+			"sku = ? AND principal_image = ? AND other_images = ?",
+			change.SKU,
+			change.From.PrincipalImage,
+			change.From.OtherImages,
+		).
+		Updates(map[string]any{
+			"principal_image": change.To.PrincipalImage,
+			"other_images":    change.To.OtherImages,
+		})
+
+	if db.Error != nil {
+		return db.Error
+	}
+
+	if db.RowsAffected < 1 {
+		return error2.Conflict(fmt.Sprintf(`the images of the product '%s' changed`, change.SKU))
+	}
+
+	return nil
+}
+
+// "implement" constraint for *MockImages
+var _ ImageManager = (*MockImages)(nil)
+
+// MockImages simulates the ImageManager in memory, the images of the products are changed into the Storage
+type MockImages struct {
+	Storage *MockStorage[model.SKU, model.Product]
+
+	mutex sync.Mutex
+}
+
+// ReplaceImages changes the images of the product saved into the Storage if they are still the From images
+func (m *MockImages) ReplaceImages(ctx context.Context, change model.ImageChange) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	product, err := m.Storage.Obtain(ctx, change.SKU)
+	if err != nil {
+		return err
+	}
+
+	if !model.ImagesOf(product).Equal(change.From) {
+		return error2.Conflict(fmt.Sprintf(`the images of the product '%s' changed`, change.SKU))
+	}
+
+	product.PrincipalImage, product.OtherImages = change.To.PrincipalImage, change.To.OtherImages
+	(*m.Storage)[change.SKU] = product
+	return nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	error2 "github.com/yael-castro/products-api/internal/model/error"
+	"gorm.io/gorm"
+	"sync"
+)
+
+// LifecycleManager defines the methods to change the model.Status of the products and to obtain their history
+type LifecycleManager interface {
+	// Transition changes the status of the product from the model.StatusTransition From to To and records the transition
+	// in a single operation. If the status of the product is not From returns error2.Conflict
+	Transition(ctx context.Context, transition model.StatusTransition) error
+	// Transitions returns the model.StatusTransition records of the product ordered by time
+	Transitions(ctx context.Context, sku model.SKU) ([]model.StatusTransition, error)
+}
+
+// "implement" constraint for Lifecycle
+var _ LifecycleManager = Lifecycle{}
+
+// Lifecycle stores the status of the products and their transitions into the database
+type Lifecycle struct {
+	*gorm.DB
+}
+
+// Transition updates the status of the product only if it is still From and inserts the transition in the same database transaction
+func (l Lifecycle) Transition(ctx context.Context, transition model.StatusTransition) error {
+	return l.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		db := tx.Model(&model.Product{}).
+			Where("sku = ? AND status = ?", transition.SKU, transition.From).
+			Update("status", transition.To)
+
+		if db.Error != nil {
+			return db.Error
+		}
+
+		if db.RowsAffected < 1 {
+			return error2.Conflict(fmt.Sprintf(`the status of the product '%s' is no longer '%s'`, transition.SKU, transition.From))
+		}
+
+		return tx.Create(&transition).Error
+	})
+}
+
+// Transitions selects the transitions of the product ordered by time
+func (l Lifecycle) Transitions(ctx context.Context, sku model.SKU) ([]model.StatusTransition, error) {
+	transitions := make([]model.StatusTransition, 0)
+
+	err := l.DB.WithContext(ctx).Where("sku = ?", sku).Order("changed_at, id").Find(&transitions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return transitions, nil
+}
+
+// "implement" constraint for *MockLifecycle
+var _ LifecycleManager = (*MockLifecycle)(nil)
+
+// MockLifecycle simulates the LifecycleManager in memory, the status of the products is changed into the Storage
+type MockLifecycle struct {
+	Storage *MockStorage[model.SKU, model.Product]
+
+	mutex       sync.Mutex
+	transitions []model.StatusTransition
+}
+
+// Transition changes the status of the product saved into the Storage and records the transition in memory
+func (m *MockLifecycle) Transition(ctx context.Context, transition model.StatusTransition) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	product, err := m.Storage.Obtain(ctx, transition.SKU)
+	if err != nil {
+		return err
+	}
+
+	if product.Status != transition.From {
+		return error2.Conflict(fmt.Sprintf(`the status of the product '%s' is no longer '%s'`, transition.SKU, transition.From))
+	}
+
+	product.Status = transition.To
+	(*m.Storage)[transition.SKU] = product
+
+	transition.ID = uint(len(m.transitions) + 1)
+	m.transitions = append(m.transitions, transition)
+	return nil
+}
+
+// Transitions returns the transitions of the product recorded in memory
+func (m *MockLifecycle) Transitions(_ context.Context, sku model.SKU) ([]model.StatusTransition, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	transitions := make([]model.StatusTransition, 0)
+
+	for _, transition := range m.transitions {
+		if transition.SKU == sku {
+			transitions = append(transitions, transition)
+		}
+	}
+
+	return transitions, nil
+}
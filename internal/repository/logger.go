@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"log/slog"
+	"time"
+)
+
+// defaultSlowThreshold default duration from which a SQL statement is logged as slow
+const defaultSlowThreshold = 200 * time.Millisecond
+
+// "implement" constraint for GormLogger
+var _ logger.Interface = GormLogger{}
+
+// GormLogger adapts *slog.Logger to the logger.Interface used by GORM
+//
+// SQL statements are logged with debug level including the duration, slow statements are logged with warn level
+// and failed statements are logged with error level. The request ID carried by the context.Context is included by *slog.Logger
+type GormLogger struct {
+	*slog.Logger
+	// SlowThreshold duration from which a SQL statement is considered slow, if it is zero slow statements are not reported
+	SlowThreshold time.Duration
+}
+
+// NewGormLogger builds a GormLogger based on the *slog.Logger
+func NewGormLogger(l *slog.Logger) GormLogger {
+	return GormLogger{Logger: l, SlowThreshold: defaultSlowThreshold}
+}
+
+// LogMode is a no-op because the log level is managed by *slog.Logger
+func (g GormLogger) LogMode(logger.LogLevel) logger.Interface {
+	return g
+}
+
+// Info logs the message with info level
+func (g GormLogger) Info(ctx context.Context, msg string, args ...any) {
+	g.Logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+// Warn logs the message with warn level
+func (g GormLogger) Warn(ctx context.Context, msg string, args ...any) {
+	g.Logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+// Error logs the message with error level
+func (g GormLogger) Error(ctx context.Context, msg string, args ...any) {
+	g.Logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+// Trace logs the SQL statement executed, its duration and the rows affected
+func (g GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+
+	level := slog.LevelDebug
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level = slog.LevelError
+	case elapsed > g.SlowThreshold && g.SlowThreshold > 0:
+		level = slog.LevelWarn
+	}
+
+	if !g.Logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Duration("duration", elapsed),
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	g.Logger.LogAttrs(ctx, level, "sql statement executed", attrs...)
+}
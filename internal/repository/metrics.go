@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+	"time"
+)
+
+// startKey key used to store the start time of each statement into the *gorm.DB instance
+const startKey = "metrics:start"
+
+// "implement" constraint for *GormMetrics
+var _ gorm.Plugin = (*GormMetrics)(nil)
+
+// GormMetrics GORM plugin that records the duration of the SQL statements by operation and table
+type GormMetrics struct {
+	duration *prometheus.HistogramVec
+}
+
+// NewGormMetrics builds the *GormMetrics and registers its collectors
+func NewGormMetrics(registerer prometheus.Registerer) (*GormMetrics, error) {
+	m := &GormMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gorm_query_duration_seconds",
+			Help:    "Duration of the SQL statements executed by GORM by operation, table and status.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table", "status"}),
+	}
+
+	if err := registerer.Register(m.duration); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Name returns the plugin name
+func (m *GormMetrics) Name() string {
+	return "metrics"
+}
+
+// Initialize registers the callbacks that measure the duration of the statements
+func (m *GormMetrics) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	errs := []error{
+		callbacks.Create().Before("gorm:create").Register("metrics:before_create", m.before),
+		callbacks.Create().After("gorm:create").Register("metrics:after_create", m.after("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:before_query", m.before),
+		callbacks.Query().After("gorm:query").Register("metrics:after_query", m.after("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:before_update", m.before),
+		callbacks.Update().After("gorm:update").Register("metrics:after_update", m.after("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", m.before),
+		callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", m.after("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:before_row", m.before),
+		callbacks.Row().After("gorm:row").Register("metrics:after_row", m.after("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:before_raw", m.before),
+		callbacks.Raw().After("gorm:raw").Register("metrics:after_raw", m.after("raw")),
+	}
+
+	return errors.Join(errs...)
+}
+
+// before stores the start time of the statement
+func (m *GormMetrics) before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+// after records the duration of the statement
+func (m *GormMetrics) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+
+		status := "ok"
+		if db.Error != nil {
+			status = "error"
+		}
+
+		m.duration.WithLabelValues(operation, db.Statement.Table, status).Observe(time.Since(start).Seconds())
+	}
+}
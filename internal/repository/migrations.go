@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+)
+
+// SchemaVersion version of the database schema required by this build, it must be increased with every new migration
+const SchemaVersion uint = 5
+
+// SchemaMigration record of a migration applied to the database
+type SchemaMigration struct {
+	Version   uint      `gorm:"primaryKey"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// Migrate applies the migrations missing in the database and records the SchemaVersion,
+// the sequences of the model.SKUSchemes that do not exist yet are created (the schemes could change between migrations)
+func Migrate(ctx context.Context, db *gorm.DB, schemes model.SKUSchemes) error {
+	db = db.WithContext(ctx)
+	migrator := db.Migrator()
+
+	if !migrator.HasTable(&SchemaMigration{}) {
+		if err := migrator.CreateTable(&SchemaMigration{}); err != nil {
+			return err
+		}
+	}
+
+	if !migrator.HasTable(&model.Product{}) {
+		if err := migrator.CreateTable(&model.Product{}); err != nil {
+			return err
+		}
+	}
+
+	// Version 2: the products created before the lifecycle statuses were live, so they are published
+	if !migrator.HasColumn(&model.Product{}, "Status") {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(`ALTER TABLE products ADD COLUMN status varchar NOT NULL DEFAULT 'published'`).Error; err != nil {
+				return err
+			}
+
+			return tx.Exec(`ALTER TABLE products ALTER COLUMN status SET DEFAULT 'draft'`).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if !migrator.HasTable(&model.StatusTransition{}) {
+		if err := migrator.CreateTable(&model.StatusTransition{}); err != nil {
+			return err
+		}
+	}
+
+	// Version 3: the price changes held until they are approved
+	if !migrator.HasTable(&model.PriceChange{}) {
+		if err := migrator.CreateTable(&model.PriceChange{}); err != nil {
+			return err
+		}
+	}
+
+	// Version 4: the sequences used to allocate the SKUs of each scheme
+	for _, scheme := range schemes.Schemes {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return migrateSequence(tx, scheme)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Version 5: the normalized brands used to find the products of a brand, they are computed by model.Normalize
+	// since the database could not normalize them the same way
+	if !migrator.HasColumn(&model.Product{}, "BrandKey") {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return migrateBrandKeys(tx)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&SchemaMigration{Version: SchemaVersion, AppliedAt: time.Now()}).
+		Error
+}
+
+// migrateBrandKeys adds the column brand_key with its index and fills it with the normalized brand of each product
+func migrateBrandKeys(tx *gorm.DB) error {
+	if err := tx.Exec(`ALTER TABLE products ADD COLUMN brand_key varchar NOT NULL DEFAULT ''`).Error; err != nil {
+		return err
+	}
+
+	brands := make([]string, 0)
+
+	if err := tx.Model(&model.Product{}).Distinct("brand").Pluck("brand", &brands).Error; err != nil {
+		return err
+	}
+
+	for _, brand := range brands {
+		err := tx.Model(&model.Product{}).Where("brand = ?", brand).Update("brand_key", model.Normalize(brand)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Migrator().CreateIndex(&model.Product{}, "BrandKey")
+}
+
+// LatestMigration returns the version of the latest migration applied to the database
+func LatestMigration(ctx context.Context, db *gorm.DB) (version uint, err error) {
+	err = db.WithContext(ctx).Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return
+}
+
+// Health checks the status of the database used by the data access layer
+type Health struct {
+	*gorm.DB
+	// Sequences names of the sequences required by the configured SKU schemes, they are created by Migrate
+	Sequences []string
+}
+
+// Ping verifies that the database is reachable
+func (h Health) Ping(ctx context.Context) error {
+	sqlDB, err := h.DB.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+// Migrations verifies that the latest migration applied to the database is at least SchemaVersion
+// and that the Sequences exist (a new SKU scheme requires applying the migrations again)
+func (h Health) Migrations(ctx context.Context) error {
+	version, err := LatestMigration(ctx, h.DB)
+	if err != nil {
+		return err
+	}
+
+	if version < SchemaVersion {
+		return fmt.Errorf("schema version %d is older than the required version %d", version, SchemaVersion)
+	}
+
+	missing, err := Sequences{DB: h.DB}.Missing(ctx, h.Sequences...)
+	if err != nil {
+		return err
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("the sequences of the sku schemes [%s] do not exist", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -1,10 +1,13 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"github.com/yael-castro/products-api/internal/model"
 	error2 "github.com/yael-castro/products-api/internal/model/error"
 	"gorm.io/gorm"
+	"slices"
+	"strings"
 )
 
 // "implement" constraint for ProductStore
@@ -16,13 +19,16 @@ type ProductStore struct {
 }
 
 // Create inserts into the database a new record using the *model.Product received as parameter
-func (p ProductStore) Create(product *model.Product) error {
-	return p.DB.Create(product).Error
+func (p ProductStore) Create(ctx context.Context, product *model.Product) error {
+	setBrandKey(product)
+	return p.DB.WithContext(ctx).Create(product).Error
 }
 
 // Obtain finds the record for model.Product identified by model.SKU
-func (p ProductStore) Obtain(sku model.SKU) (product model.Product, err error) {
-	db := p.DB.Where("sku = ?", sku).Find(&product)
+//
+// If ctx carries model.Fields only the columns of those fields are selected
+func (p ProductStore) Obtain(ctx context.Context, sku model.SKU) (product model.Product, err error) {
+	db := p.query(ctx).Where("sku = ?", sku).Find(&product)
 
 	if err = db.Error; err != nil {
 		return
@@ -35,14 +41,48 @@ func (p ProductStore) Obtain(sku model.SKU) (product model.Product, err error) {
 	return
 }
 
+// ObtainMany finds the records for model.Product identified by the model.SKUs using a single query,
+// the model.SKUs that do not exist are not included
+//
+// If ctx carries model.Fields only the columns of those fields are selected, the column sku is always selected
+func (p ProductStore) ObtainMany(ctx context.Context, skus []model.SKU) (map[model.SKU]model.Product, error) {
+	records := make(map[model.SKU]model.Product, len(skus))
+	if len(skus) == 0 {
+		return records, nil
+	}
+
+	db := p.DB.WithContext(ctx)
+
+	if fields, ok := FieldsFrom(ctx); ok {
+		if !slices.Contains(fields, "sku") {
+			fields = append(model.Fields{"sku"}, fields...)
+		}
+
+		db = db.Select(fields.Names())
+	}
+
+	products := model.Products{}
+
+	if err := db.Where("sku IN ?", skus).Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	for _, product := range products {
+		records[product.SKU] = product
+	}
+
+	return records, nil
+}
+
 // Update using the instance of model.SKU and model.Product updates the record into database identified by model.SKU
-func (p ProductStore) Update(sku model.SKU, product model.Product) error {
-	return p.DB.Where("sku = ?", sku).Updates(product).Error
+func (p ProductStore) Update(ctx context.Context, sku model.SKU, product model.Product) error {
+	setBrandKey(&product)
+	return p.DB.WithContext(ctx).Where("sku = ?", sku).Updates(product).Error
 }
 
 // Delete removes the record identified by model.SKU from the database
-func (p ProductStore) Delete(sku model.SKU) error {
-	rowsAffected := p.DB.Delete(model.Product{SKU: sku}).RowsAffected
+func (p ProductStore) Delete(ctx context.Context, sku model.SKU) error {
+	rowsAffected := p.DB.WithContext(ctx).Delete(model.Product{SKU: sku}).RowsAffected
 
 	if rowsAffected < 1 {
 		return error2.NotFound(fmt.Sprintf(`product identified by sku '%s' does not exist`, sku))
@@ -51,9 +91,166 @@ func (p ProductStore) Delete(sku model.SKU) error {
 	return nil
 }
 
-// List lists all model.Products from the database
-func (p ProductStore) List() (products model.Products, err error) {
+// List lists all model.Products from the database ordered by SKU
+//
+// If ctx carries model.Fields only the columns of those fields are selected
+// and if ctx carries statuses only the products with those statuses are listed.
+// If ctx carries a brand only the products of that brand are listed, the brands are compared by their model.Product BrandKey
+// (ignoring the case, the punctuation and the extra spaces), so the comparison uses the index of the column brand_key
+func (p ProductStore) List(ctx context.Context) (products model.Products, err error) {
 	products = model.Products{}
-	err = p.DB.Find(&products).Error
+	db := p.query(ctx).Order("sku")
+
+	if statuses, ok := StatusesFrom(ctx); ok {
+		db = db.Where("status IN ?", statuses)
+	}
+
+	if brand, ok := BrandFrom(ctx); ok {
+		db = db.Where("brand_key = ?", model.Normalize(brand))
+	}
+
+	err = db.Find(&products).Error
 	return
 }
+
+// setBrandKey sets the model.Product BrandKey based on its brand, the products without brand are not changed
+// since their brand is not updated
+func setBrandKey(product *model.Product) {
+	if product.Brand != "" {
+		product.BrandKey = model.Normalize(product.Brand)
+	}
+}
+
+// ProductFinder defines the method to list a page of the products that match a model.ProductFilter
+// without reading the whole catalog
+type ProductFinder interface {
+	// Find returns the page of the products that match the model.ProductFilter ordered by SKU
+	// and the number of products that match it
+	Find(ctx context.Context, filter model.ProductFilter) (model.ProductPage, error)
+}
+
+// "implement" constraint for ProductStore
+var _ ProductFinder = ProductStore{}
+
+// Find filters and paginates the products in the database, the total count is computed by a COUNT query.
+// If the filter does not contain limit nor offset, the total count is the number of listed products
+//
+// If ctx carries model.Fields only the columns of those fields are selected
+func (p ProductStore) Find(ctx context.Context, filter model.ProductFilter) (model.ProductPage, error) {
+	page := model.ProductPage{Items: model.Products{}}
+
+	db := p.query(ctx).Scopes(productFilter(filter)).Order("sku").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		db = db.Limit(filter.Limit)
+	}
+
+	if err := db.Find(&page.Items).Error; err != nil {
+		return page, err
+	}
+
+	if filter.Limit <= 0 && filter.Offset <= 0 {
+		page.TotalCount = len(page.Items)
+		return page, nil
+	}
+
+	count := int64(0)
+
+	err := p.DB.WithContext(ctx).Model(&model.Product{}).Scopes(productFilter(filter)).Count(&count).Error
+	page.TotalCount = int(count)
+
+	return page, err
+}
+
+// productFilter returns the scope that adds the conditions of the model.ProductFilter to the query
+func productFilter(filter model.ProductFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(filter.Statuses) > 0 {
+			db = db.Where("status IN ?", filter.Statuses)
+		}
+
+		if filter.Brand != "" {
+			db = db.Where("LOWER(brand) = LOWER(?)", filter.Brand)
+		}
+
+		if filter.NameContains != "" {
+			db = db.Where("name ILIKE ?", "%"+likeEscaper.Replace(filter.NameContains)+"%")
+		}
+
+		if filter.Size != "" {
+			db = db.Where("size = ?", filter.Size)
+		}
+
+		if filter.MinPrice != nil {
+			db = db.Where("price >= ?", *filter.MinPrice)
+		}
+
+		if filter.MaxPrice != nil {
+			db = db.Where("price <= ?", *filter.MaxPrice)
+		}
+
+		return db
+	}
+}
+
+// likeEscaper escapes the wildcards of the LIKE patterns, the backslash is the default escape character of PostgreSQL
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// query returns the *gorm.DB used to read products, the sparse fieldset carried by ctx is pushed down to the SELECT clause.
+// The column status is always selected because the business layer filters the products by status
+func (p ProductStore) query(ctx context.Context) *gorm.DB {
+	db := p.DB.WithContext(ctx)
+
+	if fields, ok := FieldsFrom(ctx); ok {
+		if !slices.Contains(fields, "status") {
+			fields = append(fields[:len(fields):len(fields)], "status")
+		}
+
+		db = db.Select(fields.Names())
+	}
+
+	return db
+}
+
+// fieldsKey context key for model.Fields
+type fieldsKey struct{}
+
+// WithFields returns a copy of ctx that carries the sparse fieldset, the storages are able to read only the requested fields
+func WithFields(ctx context.Context, fields model.Fields) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// FieldsFrom returns the sparse fieldset carried by ctx, the bool value is false if every field was requested
+func FieldsFrom(ctx context.Context) (model.Fields, bool) {
+	fields, ok := ctx.Value(fieldsKey{}).(model.Fields)
+	return fields, ok && len(fields) > 0
+}
+
+// statusesKey context key for the statuses of the listed products
+type statusesKey struct{}
+
+// WithStatuses returns a copy of ctx that carries the statuses of the listed products, the storages are able to read only
+// the products with those statuses
+func WithStatuses(ctx context.Context, statuses []model.Status) context.Context {
+	return context.WithValue(ctx, statusesKey{}, statuses)
+}
+
+// StatusesFrom returns the statuses carried by ctx, the bool value is false if every status was requested
+func StatusesFrom(ctx context.Context) ([]model.Status, bool) {
+	statuses, ok := ctx.Value(statusesKey{}).([]model.Status)
+	return statuses, ok && len(statuses) > 0
+}
+
+// brandKey context key for the brand of the listed products
+type brandKey struct{}
+
+// WithBrand returns a copy of ctx that carries the brand of the listed products, the storages are able to read only
+// the products of that brand
+func WithBrand(ctx context.Context, brand string) context.Context {
+	return context.WithValue(ctx, brandKey{}, brand)
+}
+
+// BrandFrom returns the brand carried by ctx, the bool value is false if every brand was requested
+func BrandFrom(ctx context.Context) (string, bool) {
+	brand, ok := ctx.Value(brandKey{}).(string)
+	return brand, ok && brand != ""
+}
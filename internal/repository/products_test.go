@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"github.com/yael-castro/products-api/internal/model"
@@ -61,10 +62,10 @@ func TestProductStore_Create(t *testing.T) {
 	for i, v := range tdt {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			t.Cleanup(func() {
-				_ = storage.Delete(v.product.SKU)
+				_ = storage.Delete(context.Background(), v.product.SKU)
 			})
 
-			err := storage.Create(&v.product)
+			err := storage.Create(context.Background(), &v.product)
 			if !errors.Is(err, v.expectedErr) {
 				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
 			}
@@ -83,6 +84,8 @@ func TestProductStore_Obtain(t *testing.T) {
 		product      model.Product
 		expectedErr  error
 		skipCreation bool
+		// fields sparse fieldset, if it is not empty only the fields are expected
+		fields model.Fields
 	}{
 		{
 			product: model.Product{
@@ -103,6 +106,20 @@ func TestProductStore_Obtain(t *testing.T) {
 				},
 			},
 		},
+		{
+			product: model.Product{
+				SKU:   "1235",
+				Name:  "...",
+				Brand: "Nike",
+				Price: 1_000,
+				PrincipalImage: func() *model.URL {
+					u, _ := url.Parse("https://example.com")
+					return &model.URL{URL: u}
+				}(),
+				OtherImages: model.URLs{},
+			},
+			fields: model.Fields{"sku", "name", "price"},
+		},
 		{
 			product:      model.Product{SKU: "12345"},
 			skipCreation: true,
@@ -129,13 +146,15 @@ func TestProductStore_Obtain(t *testing.T) {
 	for i, v := range tdt {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			if !v.skipCreation {
-				_ = storage.Create(&v.product)
+				_ = storage.Create(context.Background(), &v.product)
 				t.Cleanup(func() {
-					log.Println(storage.Delete(v.product.SKU))
+					log.Println(storage.Delete(context.Background(), v.product.SKU))
 				})
 			}
 
-			product, err := storage.Obtain(v.product.SKU)
+			ctx := WithFields(context.Background(), v.fields)
+
+			product, err := storage.Obtain(ctx, v.product.SKU)
 			if !errors.Is(err, v.expectedErr) {
 				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
 			}
@@ -144,7 +163,12 @@ func TestProductStore_Obtain(t *testing.T) {
 				t.Skip(err)
 			}
 
-			if !reflect.DeepEqual(v.product, product) {
+			expected := v.product
+			if len(v.fields) > 0 {
+				expected = model.Product{SKU: v.product.SKU, Name: v.product.Name, Price: v.product.Price}
+			}
+
+			if !reflect.DeepEqual(expected, product) {
 				t.Fatalf("expected product '%v' unexpected product '%v'", v.product, product)
 			}
 
@@ -204,10 +228,10 @@ func TestProductStore_Delete(t *testing.T) {
 	for i, v := range tdt {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			if !v.skipCreation {
-				_ = storage.Create(&v.product)
+				_ = storage.Create(context.Background(), &v.product)
 			}
 
-			err := storage.Delete(v.product.SKU)
+			err := storage.Delete(context.Background(), v.product.SKU)
 			if !errors.Is(err, v.expectedErr) {
 				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
 			}
@@ -271,13 +295,13 @@ func TestProductStore_Update(t *testing.T) {
 	for i, v := range tdt {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			if !v.skipCreation {
-				_ = storage.Create(&v.product)
+				_ = storage.Create(context.Background(), &v.product)
 				t.Cleanup(func() {
-					_ = storage.Delete(v.product.SKU)
+					_ = storage.Delete(context.Background(), v.product.SKU)
 				})
 			}
 
-			err := storage.Update(v.product.SKU, v.product)
+			err := storage.Update(context.Background(), v.product.SKU, v.product)
 			if !errors.Is(err, v.expectedErr) {
 				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
 			}
@@ -300,11 +324,12 @@ func TestProductStore_List(t *testing.T) {
 		{
 			products: model.Products{
 				{
-					SKU:   "1234",
-					Name:  "...",
-					Brand: "Nike",
-					Size:  &[]string{"M"}[0],
-					Price: 1_000,
+					SKU:      "1234",
+					Name:     "...",
+					Brand:    "Nike",
+					BrandKey: "nike",
+					Size:     &[]string{"M"}[0],
+					Price:    1_000,
 					PrincipalImage: func() *model.URL {
 						u, _ := url.Parse("https://example.com")
 						return &model.URL{URL: u}
@@ -344,17 +369,17 @@ func TestProductStore_List(t *testing.T) {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			if !v.skipCreation {
 				for _, product := range v.products {
-					_ = storage.Create(&product)
+					_ = storage.Create(context.Background(), &product)
 
 				}
 				t.Cleanup(func() {
 					for _, product := range v.products {
-						_ = storage.Delete(product.SKU)
+						_ = storage.Delete(context.Background(), product.SKU)
 					}
 				})
 			}
 
-			products, err := storage.List()
+			products, err := storage.List(context.Background())
 			if !errors.Is(err, v.expectedErr) {
 				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
 			}
@@ -371,3 +396,177 @@ func TestProductStore_List(t *testing.T) {
 		})
 	}
 }
+
+func TestProductStore_ObtainMany(t *testing.T) {
+	tdt := []struct {
+		products        model.Products
+		skus            []model.SKU
+		expectedRecords map[model.SKU]model.Product
+		expectedErr     error
+	}{
+		{
+			products: model.Products{
+				{
+					SKU:   "1234",
+					Name:  "...",
+					Brand: "Nike",
+					Price: 1_000,
+					PrincipalImage: func() *model.URL {
+						u, _ := url.Parse("https://example.com")
+						return &model.URL{URL: u}
+					}(),
+					OtherImages: model.URLs{},
+				},
+			},
+			skus: []model.SKU{"1234", "12345"},
+			expectedRecords: map[model.SKU]model.Product{
+				"1234": {
+					SKU:      "1234",
+					Name:     "...",
+					Brand:    "Nike",
+					BrandKey: "nike",
+					Price:    1_000,
+					PrincipalImage: func() *model.URL {
+						u, _ := url.Parse("https://example.com")
+						return &model.URL{URL: u}
+					}(),
+					OtherImages: model.URLs{},
+				},
+			},
+		},
+		{
+			skus:            []model.SKU{},
+			expectedRecords: map[model.SKU]model.Product{},
+		},
+	}
+
+	// gormDSN is the Data Source Name for GORM
+	gormDSN := os.Getenv("GORM_DSN")
+	if gormDSN == "" {
+		t.Fatal(`missing environment variable "GORM_DSN"`)
+	}
+
+	db, err := NewGormDB(gormDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := ProductStore{DB: db}
+	if *verbose {
+		storage.DB = db.Debug()
+	}
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			for _, product := range v.products {
+				_ = storage.Create(context.Background(), &product)
+			}
+
+			t.Cleanup(func() {
+				for _, product := range v.products {
+					_ = storage.Delete(context.Background(), product.SKU)
+				}
+			})
+
+			records, err := storage.ObtainMany(context.Background(), v.skus)
+			if !errors.Is(err, v.expectedErr) {
+				t.Fatalf("expected error '%v' unexpected error '%v'", v.expectedErr, err)
+			}
+
+			if err != nil {
+				t.Skip(err)
+			}
+
+			if !reflect.DeepEqual(v.expectedRecords, records) {
+				t.Fatalf("expected records '%v' unexpected records '%v'", v.expectedRecords, records)
+			}
+
+			t.Log(records)
+		})
+	}
+}
+
+func TestProductStore_Find(t *testing.T) {
+	image := func() *model.URL {
+		u, _ := url.Parse("https://example.com")
+		return &model.URL{URL: u}
+	}()
+
+	products := model.Products{
+		{SKU: "FND-1", Name: "Air Max 90", Brand: "Nike", Price: 10, PrincipalImage: image, OtherImages: model.URLs{}},
+		{SKU: "FND-2", Name: "Air_Max 95", Brand: "NIKE", Price: 20, PrincipalImage: image, OtherImages: model.URLs{}},
+		{SKU: "FND-3", Name: "Superstar", Brand: "Adidas", Price: 30, PrincipalImage: image, OtherImages: model.URLs{}},
+	}
+
+	tdt := []struct {
+		filter        model.ProductFilter
+		expectedSKUs  []model.SKU
+		expectedTotal int
+	}{
+		{
+			filter:        model.ProductFilter{Brand: "nike"},
+			expectedSKUs:  []model.SKU{"FND-1", "FND-2"},
+			expectedTotal: 2,
+		},
+		// The wildcards are escaped
+		{
+			filter:        model.ProductFilter{NameContains: "AIR_"},
+			expectedSKUs:  []model.SKU{"FND-2"},
+			expectedTotal: 1,
+		},
+		{
+			filter:        model.ProductFilter{MinPrice: &[]float64{15}[0], MaxPrice: &[]float64{30}[0]},
+			expectedSKUs:  []model.SKU{"FND-2", "FND-3"},
+			expectedTotal: 2,
+		},
+		{
+			filter:        model.ProductFilter{Brand: "nike", Offset: 1, Limit: 1},
+			expectedSKUs:  []model.SKU{"FND-2"},
+			expectedTotal: 2,
+		},
+	}
+
+	// gormDSN is the Data Source Name for GORM
+	gormDSN := os.Getenv("GORM_DSN")
+	if gormDSN == "" {
+		t.Fatal(`missing environment variable "GORM_DSN"`)
+	}
+
+	db, err := NewGormDB(gormDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := ProductStore{DB: db}
+	if *verbose {
+		storage.DB = db.Debug()
+	}
+
+	for _, product := range products {
+		_ = storage.Create(context.Background(), &product)
+	}
+
+	t.Cleanup(func() {
+		for _, product := range products {
+			_ = storage.Delete(context.Background(), product.SKU)
+		}
+	})
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			page, err := storage.Find(context.Background(), v.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			skus := make([]model.SKU, 0, len(page.Items))
+			for _, product := range page.Items {
+				skus = append(skus, product.SKU)
+			}
+
+			if !reflect.DeepEqual(v.expectedSKUs, skus) || page.TotalCount != v.expectedTotal {
+				t.Fatalf("expected skus '%v' of %d unexpected skus '%v' of %d", v.expectedSKUs, v.expectedTotal, skus, page.TotalCount)
+			}
+		})
+	}
+}
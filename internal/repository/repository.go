@@ -2,6 +2,7 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	error2 "github.com/yael-castro/products-api/internal/model/error"
 )
@@ -9,27 +10,29 @@ import (
 // StorageManager defines the common methods for storage management
 type StorageManager[K comparable, V any] interface {
 	// Create saves a new record into the storage
-	Create(*V) error
+	Create(context.Context, *V) error
 	// Obtain returns the record identified by K from the store
-	Obtain(K) (V, error)
+	Obtain(context.Context, K) (V, error)
+	// ObtainMany returns the records identified by the keys indexed by its key, the keys that do not exist are not included
+	ObtainMany(context.Context, []K) (map[K]V, error)
 	// Update updates a record identified by K
-	Update(K, V) error
+	Update(context.Context, K, V) error
 	// Delete removes a record identified by K from the store
-	Delete(K) error
+	Delete(context.Context, K) error
 	// List returns all records into the store
-	List() ([]V, error)
+	List(context.Context) ([]V, error)
 }
 
 // MockStorage simulates data persistence to test some features more easy, also can be used as a memory repository
 type MockStorage[K comparable, V any] map[K]V
 
 // Create missing implementation
-func (m *MockStorage[K, V]) Create(v *V) error {
+func (m *MockStorage[K, V]) Create(_ context.Context, v *V) error {
 	return nil
 }
 
 // Obtain returns the record associate to the key received as parameter
-func (m MockStorage[K, V]) Obtain(k K) (V, error) {
+func (m MockStorage[K, V]) Obtain(_ context.Context, k K) (V, error) {
 	v, ok := m[k]
 	if !ok {
 		return v, error2.NotFound(fmt.Sprintf(`product identified by sku '%v' does not exist`, k))
@@ -38,20 +41,33 @@ func (m MockStorage[K, V]) Obtain(k K) (V, error) {
 	return v, nil
 }
 
+// ObtainMany returns the records associate to the keys received as parameter, the missing keys are ignored
+func (m MockStorage[K, V]) ObtainMany(_ context.Context, keys []K) (map[K]V, error) {
+	records := make(map[K]V, len(keys))
+
+	for _, k := range keys {
+		if v, ok := m[k]; ok {
+			records[k] = v
+		}
+	}
+
+	return records, nil
+}
+
 // Update replaces the record associate to the key received as parameter
-func (m *MockStorage[K, V]) Update(k K, v V) error {
+func (m *MockStorage[K, V]) Update(_ context.Context, k K, v V) error {
 	(*m)[k] = v
 	return nil
 }
 
 // Delete removes the record associate to the key received as parameter
-func (m *MockStorage[K, V]) Delete(k K) error {
+func (m *MockStorage[K, V]) Delete(_ context.Context, k K) error {
 	delete(*m, k)
 	return nil
 }
 
 // List returns all records from the hash map
-func (m MockStorage[K, V]) List() ([]V, error) {
+func (m MockStorage[K, V]) List(context.Context) ([]V, error) {
 	list := make([]V, 0)
 
 	for _, v := range m {
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	"gorm.io/gorm"
+	"sync"
+	"unicode/utf8"
+)
+
+// SequenceManager defines the methods to obtain numbers from named sequences, the numbers of each sequence start at 1
+// and are never returned twice
+type SequenceManager interface {
+	// NextValues returns the next n numbers of the sequence
+	NextValues(ctx context.Context, name string, n int) ([]int64, error)
+}
+
+// "implement" constraint for Sequences
+var _ SequenceManager = Sequences{}
+
+// Sequences PostgreSQL sequences, each sequence is named "sku_<name>_seq"
+type Sequences struct {
+	*gorm.DB
+}
+
+// sequenceName returns the name of the PostgreSQL sequence
+func sequenceName(name string) string {
+	return fmt.Sprintf(`sku_%s_seq`, name)
+}
+
+// migrateSequence creates the sequence of the model.SKUScheme if it does not exist yet. The sequence starts after the
+// highest number of the existing SKUs of the scheme (e.g. the SKUs chosen by the clients), this way the allocation
+// does not have to skip the SKUs that are already taken
+func migrateSequence(tx *gorm.DB, scheme model.SKUScheme) error {
+	exists, err := sequenceExists(tx, scheme.Name)
+	if err != nil || exists {
+		return err
+	}
+
+	if err = tx.Exec(fmt.Sprintf(`CREATE SEQUENCE %q`, sequenceName(scheme.Name))).Error; err != nil {
+		return err
+	}
+
+	skus := make([]model.SKU, 0)
+
+	err = tx.Model(&model.Product{}).
+		Where("LEFT(sku, ?) = ?", utf8.RuneCountInString(scheme.Prefix), scheme.Prefix).
+		Pluck("sku", &skus).
+		Error
+	if err != nil {
+		return err
+	}
+
+	var last int64
+
+	for _, sku := range skus {
+		// The SKUs of other schemes could have the same prefix
+		if n, err := scheme.Number(sku); err == nil && n > last {
+			last = n
+		}
+	}
+
+	if last == 0 {
+		return nil
+	}
+
+	return tx.Exec(`SELECT setval(?::regclass, ?)`, sequenceName(scheme.Name), last).Error
+}
+
+// sequenceExists indicates if the sequence exists
+func sequenceExists(db *gorm.DB, name string) (exists bool, err error) {
+	err = db.Raw(`SELECT to_regclass(?) IS NOT NULL`, sequenceName(name)).Scan(&exists).Error
+	return
+}
+
+// Missing returns the names of the sequences that do not exist, they are created by Migrate
+func (s Sequences) Missing(ctx context.Context, names ...string) ([]string, error) {
+	missing := make([]string, 0)
+
+	for _, name := range names {
+		exists, err := sequenceExists(s.DB.WithContext(ctx), name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing, nil
+}
+
+// NextValues returns the next n numbers of the sequence using a single query, the numbers could be non-consecutive
+// if other clients obtain numbers of the same sequence at the same time
+func (s Sequences) NextValues(ctx context.Context, name string, n int) ([]int64, error) {
+	values := make([]int64, 0, n)
+
+	err := s.DB.WithContext(ctx).
+		Raw(`SELECT nextval(?::regclass) FROM generate_series(1, ?)`, sequenceName(name), n).
+		Scan(&values).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// "implement" constraint for *MockSequences
+var _ SequenceManager = (*MockSequences)(nil)
+
+// MockSequences simulates the sequences in memory
+type MockSequences struct {
+	mutex  sync.Mutex
+	values map[string]int64
+}
+
+// NextValues returns the next n numbers of the sequence
+func (m *MockSequences) NextValues(_ context.Context, name string, n int) ([]int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.values == nil {
+		m.values = make(map[string]int64)
+	}
+
+	values := make([]int64, 0, n)
+
+	for i := 0; i < n; i++ {
+		m.values[name]++
+		values = append(values, m.values[name])
+	}
+
+	return values, nil
+}
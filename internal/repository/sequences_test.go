@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/yael-castro/products-api/internal/model"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSequences_NextValues(t *testing.T) {
+	tdt := []struct {
+		name string
+		n    int
+	}{
+		{name: "test", n: 1},
+		{name: "test", n: 5},
+		{name: "test_other", n: 3},
+	}
+
+	// gormDSN is the Data Source Name for GORM
+	gormDSN := os.Getenv("GORM_DSN")
+	if gormDSN == "" {
+		t.Fatal(`missing environment variable "GORM_DSN"`)
+	}
+
+	db, err := NewGormDB(gormDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sequences := Sequences{DB: db}
+	if *verbose {
+		sequences.DB = db.Debug()
+	}
+
+	schemes := model.SKUSchemes{
+		Schemes: []model.SKUScheme{{Name: "test", Prefix: "TST-"}, {Name: "test_other", Prefix: "OTH-"}},
+	}
+
+	if err = Migrate(context.Background(), sequences.DB, schemes); err != nil {
+		t.Fatal(err)
+	}
+
+	last := make(map[string]int64)
+
+	for i, v := range tdt {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			values, err := sequences.NextValues(context.Background(), v.name, v.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(values) != v.n {
+				t.Fatalf(`expected %d values unexpected %d values`, v.n, len(values))
+			}
+
+			for _, value := range values {
+				if value <= last[v.name] {
+					t.Fatalf(`value %d was already returned`, value)
+				}
+
+				last[v.name] = value
+			}
+		})
+	}
+}
+
+func TestMigrate_sequences(t *testing.T) {
+	// gormDSN is the Data Source Name for GORM
+	gormDSN := os.Getenv("GORM_DSN")
+	if gormDSN == "" {
+		t.Fatal(`missing environment variable "GORM_DSN"`)
+	}
+
+	db, err := NewGormDB(gormDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *verbose {
+		db = db.Debug()
+	}
+
+	// The sequences are created once, so every run uses a new scheme
+	suffix := time.Now().UnixNano()
+	scheme := model.SKUScheme{Name: fmt.Sprintf("test_%d", suffix), Prefix: fmt.Sprintf("T%d-", suffix), Digits: 7}
+
+	storage := ProductStore{DB: db}
+
+	for _, n := range []int64{42, 7} {
+		product := model.Product{Name: "Shoes", Brand: "Nike", Price: 10}
+		if product.SKU, err = scheme.Format(n); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = storage.Create(context.Background(), &product); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() {
+			_ = storage.Delete(context.Background(), product.SKU)
+		})
+	}
+
+	if err = Migrate(context.Background(), db, model.SKUSchemes{Schemes: []model.SKUScheme{scheme}}); err != nil {
+		t.Fatal(err)
+	}
+
+	missing, err := Sequences{DB: db}.Missing(context.Background(), scheme.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(missing) > 0 {
+		t.Fatalf("unexpected missing sequences '%v'", missing)
+	}
+
+	// The allocation starts after the highest existing SKU of the scheme
+	values, err := Sequences{DB: db}.NextValues(context.Background(), scheme.Name, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if values[0] != 43 {
+		t.Fatalf("expected value '43' unexpected value '%d'", values[0])
+	}
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// InstrumentationName name of the instrumentation scope used by the tracers of the data access layer
+const InstrumentationName = "github.com/yael-castro/products-api/internal/repository"
+
+// spanKey key used to store the span of each statement into the *gorm.DB instance
+const spanKey = "tracing:span"
+
+// endSpan records the error (if any) and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// "implement" constraint for StorageTracing
+var _ StorageManager[string, any] = StorageTracing[string, any]{}
+
+// StorageTracing decorates a StorageManager to start a span for each operation
+type StorageTracing[K comparable, V any] struct {
+	StorageManager[K, V]
+	trace.Tracer
+}
+
+// Create traces StorageManager.Create
+func (s StorageTracing[K, V]) Create(ctx context.Context, v *V) (err error) {
+	ctx, span := s.Start(ctx, "StorageManager.Create")
+	defer func() { endSpan(span, err) }()
+
+	return s.StorageManager.Create(ctx, v)
+}
+
+// Obtain traces StorageManager.Obtain
+func (s StorageTracing[K, V]) Obtain(ctx context.Context, k K) (v V, err error) {
+	ctx, span := s.Start(ctx, "StorageManager.Obtain", trace.WithAttributes(attribute.String("key", fmt.Sprint(k))))
+	defer func() { endSpan(span, err) }()
+
+	return s.StorageManager.Obtain(ctx, k)
+}
+
+// ObtainMany traces StorageManager.ObtainMany
+func (s StorageTracing[K, V]) ObtainMany(ctx context.Context, keys []K) (records map[K]V, err error) {
+	ctx, span := s.Start(ctx, "StorageManager.ObtainMany", trace.WithAttributes(attribute.Int("keys", len(keys))))
+	defer func() { endSpan(span, err) }()
+
+	return s.StorageManager.ObtainMany(ctx, keys)
+}
+
+// Update traces StorageManager.Update
+func (s StorageTracing[K, V]) Update(ctx context.Context, k K, v V) (err error) {
+	ctx, span := s.Start(ctx, "StorageManager.Update", trace.WithAttributes(attribute.String("key", fmt.Sprint(k))))
+	defer func() { endSpan(span, err) }()
+
+	return s.StorageManager.Update(ctx, k, v)
+}
+
+// Delete traces StorageManager.Delete
+func (s StorageTracing[K, V]) Delete(ctx context.Context, k K) (err error) {
+	ctx, span := s.Start(ctx, "StorageManager.Delete", trace.WithAttributes(attribute.String("key", fmt.Sprint(k))))
+	defer func() { endSpan(span, err) }()
+
+	return s.StorageManager.Delete(ctx, k)
+}
+
+// List traces StorageManager.List
+func (s StorageTracing[K, V]) List(ctx context.Context) (list []V, err error) {
+	ctx, span := s.Start(ctx, "StorageManager.List")
+	defer func() { endSpan(span, err) }()
+
+	return s.StorageManager.List(ctx)
+}
+
+// "implement" constraint for GormTracing
+var _ gorm.Plugin = GormTracing{}
+
+// GormTracing GORM plugin that starts a client span for each SQL statement, the span includes the SQL statement
+type GormTracing struct {
+	trace.Tracer
+}
+
+// Name returns the plugin name
+func (g GormTracing) Name() string {
+	return "tracing"
+}
+
+// Initialize registers the callbacks that start and end the spans
+func (g GormTracing) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	errs := []error{
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", g.before("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", g.after),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", g.before("query")),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", g.after),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", g.before("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", g.after),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", g.before("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", g.after),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", g.before("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", g.after),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", g.before("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", g.after),
+	}
+
+	return errors.Join(errs...)
+}
+
+// before starts the span of the statement as child of the span carried by the statement context.Context
+func (g GormTracing) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		_, span := g.Start(
+			db.Statement.Context,
+			"gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNamePostgreSQL,
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(db.Statement.Table),
+			),
+		)
+
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+// after adds the SQL statement to the span and ends it
+func (g GormTracing) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+
+	endSpan(span, err)
+}
@@ -0,0 +1,9 @@
+// Package productsapi contains the assets of the REST API that are embedded into the binaries
+package productsapi
+
+import _ "embed"
+
+// OpenAPI OpenAPI document (swagger.yaml) that describes the REST API
+//
+//go:embed swagger.yaml
+var OpenAPI []byte